@@ -0,0 +1,333 @@
+package main
+
+import (
+	"net/http"
+
+	"bookstore/internal/api"
+	"bookstore/internal/handlers"
+	"bookstore/internal/logic"
+	"bookstore/internal/models"
+	"bookstore/internal/search"
+)
+
+type apiHandlers struct {
+	auth      *handlers.AuthHandler
+	account   *handlers.AccountHandler
+	privacy   *handlers.PrivacyHandler
+	books     *handlers.BookHandler
+	transfer  *handlers.BookTransferHandler
+	covers    *handlers.CoverHandler
+	carts     *handlers.CartHandler
+	orders    *handlers.OrderHandler
+	orderCRUD *handlers.OrderCRUDHandler
+	wishlists *handlers.WishlistHandler
+	reviews   *handlers.ReviewHandler
+	recs      *handlers.RecommendationHandler
+	search    *handlers.SearchHandler
+
+	authors    *handlers.EntityHandler
+	publishers *handlers.EntityHandler
+	series     *handlers.EntityHandler
+	genres     *handlers.EntityHandler
+	categories *handlers.CategoryHandler
+}
+
+type CartView struct {
+	Cart  models.Cart       `json:"cart"`
+	Items []models.CartItem `json:"items"`
+}
+
+type OrderView struct {
+	Order models.Order       `json:"order"`
+	Items []models.OrderItem `json:"items"`
+}
+
+type WishlistView struct {
+	Wishlist models.Wishlist       `json:"wishlist"`
+	Items    []models.WishlistItem `json:"items"`
+}
+
+// apiRoutes is the single list of JSON endpoints. It drives both routing and
+// the OpenAPI document served at /api/v1/openapi.json.
+func apiRoutes(h apiHandlers) []api.Route {
+	routes := []api.Route{
+		// ---------------- Auth ----------------
+		{Method: http.MethodPost, Path: "/auth/register", Legacy: "/auth/register", Tag: "auth",
+			Summary: "Register a customer account", Handler: h.auth.Register,
+			Request: logic.Registration{}, Response: api.Message{}, Status: http.StatusCreated},
+		{Method: http.MethodPost, Path: "/auth/login", Legacy: "/auth/login", Tag: "auth",
+			Summary: "Log in and receive a bearer token", Handler: h.auth.Login,
+			Request: logic.Credentials{}, Response: struct {
+				Token string `json:"token"`
+			}{}},
+
+		// ---------------- Account ----------------
+		{Method: http.MethodGet, Path: "/auth/me", Legacy: "/auth/me", Access: api.User, Tag: "account",
+			Summary: "Current user's profile", Handler: h.account.Me, Response: handlers.Profile{}},
+		{Method: http.MethodPut, Path: "/auth/me", Legacy: "/auth/me", Access: api.User, Tag: "account",
+			Summary: "Update name and phone", Handler: h.account.Me,
+			Request: logic.ProfileInput{}, Response: handlers.Profile{}},
+		{Method: http.MethodDelete, Path: "/auth/me", Legacy: "/auth/me", Access: api.User, Tag: "account",
+			Summary: "Delete the account and anonymize personal data", Handler: h.privacy.DeleteMe,
+			Request: logic.AccountDeletion{}, Response: api.Message{}},
+		{Method: http.MethodPut, Path: "/auth/me/password", Legacy: "/auth/me/password", Access: api.User, Tag: "account",
+			Summary: "Change password and sign out other sessions", Handler: h.account.Password,
+			Request: logic.PasswordChange{}, Response: api.Message{}},
+		{Method: http.MethodPut, Path: "/auth/me/email", Legacy: "/auth/me/email", Access: api.User, Tag: "account",
+			Summary: "Change email", Handler: h.account.Email,
+			Request: logic.EmailChange{}, Response: api.Message{}},
+		{Method: http.MethodGet, Path: "/auth/me/sessions", Legacy: "/auth/me/sessions", Access: api.User, Tag: "account",
+			Summary: "List login sessions", Handler: h.account.Sessions,
+			Response: struct {
+				Current  int              `json:"current"`
+				Sessions []models.Session `json:"sessions"`
+			}{}},
+		{Method: http.MethodDelete, Path: "/auth/me/sessions/{id}", Legacy: "/auth/me/sessions/{id}", Access: api.User, Tag: "account",
+			Summary: "Revoke a session", Handler: h.account.SessionByID, Response: api.Message{}},
+		{Method: http.MethodGet, Path: "/auth/me/addresses", Legacy: "/auth/me/addresses", Access: api.User, Tag: "account",
+			Summary: "List saved addresses", Handler: h.account.Addresses, Response: []models.Address{}},
+		{Method: http.MethodPost, Path: "/auth/me/addresses", Legacy: "/auth/me/addresses", Access: api.User, Tag: "account",
+			Summary: "Add an address", Handler: h.account.Addresses,
+			Request: models.Address{}, Response: models.Address{}, Status: http.StatusCreated},
+		{Method: http.MethodPut, Path: "/auth/me/addresses/{id}", Legacy: "/auth/me/addresses/{id}", Access: api.User, Tag: "account",
+			Summary: "Update an address", Handler: h.account.AddressByID,
+			Request: models.Address{}, Response: api.Message{}},
+		{Method: http.MethodDelete, Path: "/auth/me/addresses/{id}", Legacy: "/auth/me/addresses/{id}", Access: api.User, Tag: "account",
+			Summary: "Delete an address", Handler: h.account.AddressByID, Response: api.Message{}},
+		{Method: http.MethodPost, Path: "/auth/me/addresses/{id}/default", Legacy: "/auth/me/addresses/{id}/default", Access: api.User, Tag: "account",
+			Summary: "Make an address the default", Handler: h.account.AddressDefault, Response: api.Message{}},
+		{Method: http.MethodGet, Path: "/auth/me/orders", Legacy: "/auth/me/orders", Access: api.User, Tag: "account",
+			Summary: "Order history", Handler: h.account.Orders, Response: []models.Order{}},
+		{Method: http.MethodGet, Path: "/auth/me/export", Legacy: "/auth/me/export", Access: api.User, Tag: "account",
+			Summary: "Download all personal data", Handler: h.privacy.ExportMe, Response: logic.DataExport{},
+			Query: []api.Param{{Name: "format", Description: "json (default) or zip"}}},
+
+		// ---------------- Admin: users ----------------
+		{Method: http.MethodGet, Path: "/admin/users/{id}/export", Legacy: "/admin/users/{id}/export", Access: api.Admin, Tag: "admin",
+			Summary: "Export a user's personal data", Handler: h.privacy.AdminExport, Response: logic.DataExport{},
+			Query: []api.Param{{Name: "format", Description: "json (default) or zip"}}},
+		{Method: http.MethodDelete, Path: "/admin/users/{id}", Legacy: "/admin/users/{id}", Access: api.Admin, Tag: "admin",
+			Summary: "Delete and anonymize a customer; admins cannot delete themselves or other admins", Handler: h.privacy.AdminDelete, Response: api.Message{}},
+
+		// ---------------- Books ----------------
+		{Method: http.MethodGet, Path: "/books", Legacy: "/books", Tag: "books",
+			Summary: "List books", Handler: h.books.Books, Response: []models.Book{},
+			Query: []api.Param{{Name: "sort", Description: "rating, title or price; stored order when omitted"}}},
+		{Method: http.MethodPost, Path: "/books", Legacy: "/books", Access: api.Admin, Tag: "books",
+			Summary: "Create a book", Handler: h.books.Books,
+			Request: models.Book{}, Response: models.Book{}, Status: http.StatusCreated},
+		// GET /books/{id} is shared with the book pages; RegisterRoutes
+		// mounts the alias.
+		{Method: http.MethodGet, Path: "/books/{id}", Tag: "books",
+			Summary: "Get a book", Handler: h.books.BookByID, Response: models.Book{}},
+		{Method: http.MethodGet, Path: "/books/isbn/{isbn}", Tag: "books",
+			Summary: "Get a book by ISBN-10 or ISBN-13", Handler: h.books.BookByISBN, Response: models.Book{}},
+		{Method: http.MethodPut, Path: "/books/{id}", Legacy: "/books/{id}", Access: api.Admin, Tag: "books",
+			Summary: "Update a book", Handler: h.books.BookByID,
+			Request: models.Book{}, Response: models.Book{}},
+		{Method: http.MethodDelete, Path: "/books/{id}", Legacy: "/books/{id}", Access: api.Admin, Tag: "books",
+			Summary: "Delete a book", Handler: h.books.BookByID, Status: http.StatusNoContent},
+		{Method: http.MethodPut, Path: "/books/{id}/cover", Access: api.Admin, Tag: "books",
+			Summary: "Upload a book's cover (JPEG, PNG or GIF, up to 10 MiB), as the body or a multipart \"cover\" field",
+			Handler: h.covers.Upload, Consumes: []string{"image/jpeg", "image/png", "image/gif", "multipart/form-data"}, Response: models.Book{}},
+		{Method: http.MethodDelete, Path: "/books/{id}/cover", Access: api.Admin, Tag: "books",
+			Summary: "Remove a book's cover", Handler: h.covers.Delete, Response: models.Book{}},
+		{Method: http.MethodPost, Path: "/books/import", Access: api.Admin, Tag: "books",
+			Summary: "Import books from CSV, JSON or ONIX 3.0, upserting by ISBN or external id; large files run in the background",
+			Handler: h.transfer.Import, Consumes: []string{"text/csv", "application/xml"}, Request: []models.Book{}, Response: logic.ImportReport{},
+			Query: []api.Param{
+				{Name: "format", Description: "csv, json or onix; defaults to the Content-Type"},
+				{Name: "dryRun", Description: "true to validate and preview without writing"},
+			}},
+		{Method: http.MethodGet, Path: "/books/imports/{id}", Access: api.Admin, Tag: "books",
+			Summary: "Get a background import and its report", Handler: h.transfer.ImportJob, Response: logic.ImportJob{}},
+		{Method: http.MethodGet, Path: "/books/export", Access: api.Admin, Tag: "books",
+			Summary: "Export the whole catalog", Handler: h.transfer.Export,
+			Produces: []string{"text/csv"}, Response: []models.Book{},
+			Query: []api.Param{{Name: "format", Description: "csv (default) or json"}}},
+		{Method: http.MethodGet, Path: "/books/search", Tag: "books",
+			Summary: "Search books by relevance, with genre, author and price facets of the matches",
+			Handler: h.search.Search, Response: logic.SearchResult{},
+			Query: []api.Param{
+				{Name: "q", Description: "words to find; typos are tolerated and the last word may be a prefix (bleve backend); empty matches every book"},
+				{Name: "genre", Description: "only books of this genre, as in facets.genre"},
+				{Name: "author", Description: "only books by this author, as in facets.author"},
+				{Name: "price", Description: "only books in this price band: 0-10, 10-20, 20-50 or 50-"},
+				{Name: "offset", Description: "matches to skip"},
+				{Name: "limit", Description: "at most 50; 20 when omitted"},
+			}},
+		{Method: http.MethodGet, Path: "/books/suggest", Tag: "books",
+			Summary: "Complete a partly typed search into titles, authors and genres, each with the URL of its page",
+			Handler: h.search.Suggest, Response: search.Suggestions{},
+			Query: []api.Param{
+				{Name: "q", Description: "the text typed so far, matched against the start of any word"},
+				{Name: "limit", Description: "suggestions of each kind, at most 10; 5 when omitted"},
+			}},
+		{Method: http.MethodPost, Path: "/books/search/rebuild", Access: api.Admin, Tag: "books",
+			Summary: "Reindex the whole catalog for search", Handler: h.search.Rebuild, Response: logic.RebuildReport{}},
+
+		// ---------------- Carts ----------------
+		{Method: http.MethodGet, Path: "/carts", Legacy: "/carts", Access: api.User, Tag: "carts",
+			Summary: "List carts (own carts unless admin)", Handler: h.carts.Carts, Response: []models.Cart{}},
+		{Method: http.MethodPost, Path: "/carts", Legacy: "/carts", Access: api.User, Tag: "carts",
+			Summary: "Create a cart", Handler: h.carts.Carts, Response: models.Cart{}, Status: http.StatusCreated},
+		{Method: http.MethodGet, Path: "/carts/{id}", Legacy: "/carts/{id}", Access: api.User, Tag: "carts",
+			Summary: "Get a cart with its items", Handler: h.carts.CartByID, Response: CartView{}},
+		{Method: http.MethodPut, Path: "/carts/{id}", Legacy: "/carts/{id}", Access: api.User, Tag: "carts",
+			Summary: "Update a cart", Handler: h.carts.CartByID, Request: models.Cart{}, Response: api.Message{}},
+		{Method: http.MethodDelete, Path: "/carts/{id}", Legacy: "/carts/{id}", Access: api.User, Tag: "carts",
+			Summary: "Delete a cart", Handler: h.carts.CartByID, Response: api.Message{}},
+		{Method: http.MethodPost, Path: "/carts/{id}/items", Legacy: "/carts/{id}/items", Access: api.User, Tag: "carts",
+			Summary: "Add a book to a cart", Handler: h.carts.CartItems,
+			Request: logic.ItemInput{}, Response: models.CartItem{}, Status: http.StatusCreated},
+		{Method: http.MethodPut, Path: "/carts/{id}/items/{itemId}", Legacy: "/carts/{id}/items/{itemId}", Access: api.User, Tag: "carts",
+			Summary: "Change the quantity of a cart item", Handler: h.carts.CartItemByID,
+			Request: logic.QtyInput{}, Response: api.Message{}},
+		{Method: http.MethodDelete, Path: "/carts/{id}/items/{itemId}", Legacy: "/carts/{id}/items/{itemId}", Access: api.User, Tag: "carts",
+			Summary: "Remove a cart item", Handler: h.carts.CartItemByID, Response: api.Message{}},
+
+		// ---------------- Orders ----------------
+		{Method: http.MethodPost, Path: "/orders", Legacy: "/orders_api", Access: api.User, Tag: "orders",
+			Summary: "Create an order from a cart", Handler: h.orders.Orders,
+			Request: logic.OrderInput{}, Response: OrderView{}, Status: http.StatusCreated},
+		{Method: http.MethodGet, Path: "/orders", Legacy: "/orders_api", Access: api.User, Tag: "orders",
+			Summary: "List orders (own orders unless admin)", Handler: h.orderCRUD.Orders, Response: []models.Order{}},
+		{Method: http.MethodGet, Path: "/orders/{id}", Legacy: "/orders_api/{id}", Access: api.User, Tag: "orders",
+			Summary: "Get an order with its items", Handler: h.orderCRUD.OrderByID, Response: OrderView{}},
+		{Method: http.MethodPut, Path: "/orders/{id}", Legacy: "/orders_api/{id}", Access: api.Admin, Tag: "orders",
+			Summary: "Update an order", Handler: h.orderCRUD.OrderByID, Request: models.Order{}, Response: api.Message{}},
+		{Method: http.MethodDelete, Path: "/orders/{id}", Legacy: "/orders_api/{id}", Access: api.Admin, Tag: "orders",
+			Summary: "Delete an order", Handler: h.orderCRUD.OrderByID, Response: api.Message{}},
+
+		// ---------------- Wishlists ----------------
+		{Method: http.MethodGet, Path: "/wishlists", Legacy: "/wishlists_api", Access: api.User, Tag: "wishlists",
+			Summary: "List wishlists (own wishlists unless admin)", Handler: h.wishlists.Wishlists, Response: []models.Wishlist{}},
+		{Method: http.MethodPost, Path: "/wishlists", Legacy: "/wishlists_api", Access: api.User, Tag: "wishlists",
+			Summary: "Create a wishlist", Handler: h.wishlists.Wishlists,
+			Request: logic.WishlistInput{}, Response: models.Wishlist{}, Status: http.StatusCreated},
+		{Method: http.MethodGet, Path: "/wishlists/{id}", Legacy: "/wishlists_api/{id}", Access: api.User, Tag: "wishlists",
+			Summary: "Get a wishlist with its items", Handler: h.wishlists.WishlistByID, Response: WishlistView{}},
+		{Method: http.MethodDelete, Path: "/wishlists/{id}", Legacy: "/wishlists_api/{id}", Access: api.User, Tag: "wishlists",
+			Summary: "Delete a wishlist", Handler: h.wishlists.WishlistByID, Response: api.Message{}},
+		{Method: http.MethodPost, Path: "/wishlists/{id}/items", Legacy: "/wishlists_api/{id}/items", Access: api.User, Tag: "wishlists",
+			Summary: "Add a book to a wishlist", Handler: h.wishlists.WishlistItems,
+			Request: logic.ItemInput{}, Response: models.WishlistItem{}, Status: http.StatusCreated},
+		{Method: http.MethodDelete, Path: "/wishlists/{id}/items/{itemId}", Legacy: "/wishlists_api/{id}/items/{itemId}", Access: api.User, Tag: "wishlists",
+			Summary: "Remove a wishlist item", Handler: h.wishlists.WishlistItemByID, Response: api.Message{}},
+		{Method: http.MethodPost, Path: "/wishlists/{id}/gift", Legacy: "/wishlists_api/{id}/gift", Access: api.User, Tag: "wishlists",
+			Summary: "Buy a wishlist as a gift", Handler: h.wishlists.Gift,
+			Response: struct {
+				Order             models.Order       `json:"order"`
+				Items             []models.OrderItem `json:"items"`
+				GiftForCustomerID int                `json:"giftForCustomerId"`
+			}{}, Status: http.StatusCreated},
+
+		// ---------------- Reviews ----------------
+		// Reviews live outside /books/{id}, where a subtree would clash
+		// with /books/isbn/{isbn} and /books/imports/{id}.
+		{Method: http.MethodGet, Path: "/reviews", Tag: "reviews",
+			Summary: "List a book's approved reviews", Handler: h.reviews.List, Response: []models.Review{},
+			Query: []api.Param{
+				{Name: "bookId", Description: "the book, required"},
+				{Name: "sort", Description: "helpful (default) or newest"},
+			}},
+		{Method: http.MethodPost, Path: "/reviews", Access: api.User, Tag: "reviews",
+			Summary: "Review a book you ordered; it waits for moderation", Handler: h.reviews.Create,
+			Request: logic.ReviewInput{}, Response: models.Review{}, Status: http.StatusCreated},
+		{Method: http.MethodGet, Path: "/reviews/mine", Access: api.User, Tag: "reviews",
+			Summary: "Your reviews in any status", Handler: h.reviews.Mine, Response: []models.Review{}},
+		{Method: http.MethodGet, Path: "/reviews/pending", Access: api.Admin, Tag: "reviews",
+			Summary: "Moderation queue, oldest first", Handler: h.reviews.Pending, Response: []models.Review{}},
+		{Method: http.MethodGet, Path: "/reviews/{id}", Tag: "reviews",
+			Summary: "Get an approved review", Handler: h.reviews.Get, Response: models.Review{}},
+		{Method: http.MethodPut, Path: "/reviews/{id}", Access: api.User, Tag: "reviews",
+			Summary: "Edit your review; it waits for moderation again", Handler: h.reviews.Update,
+			Request: logic.ReviewInput{}, Response: models.Review{}},
+		{Method: http.MethodDelete, Path: "/reviews/{id}", Access: api.User, Tag: "reviews",
+			Summary: "Delete a review (own reviews unless admin)", Handler: h.reviews.Delete, Status: http.StatusNoContent},
+		{Method: http.MethodPut, Path: "/reviews/{id}/status", Access: api.Admin, Tag: "reviews",
+			Summary: "Approve or reject a review", Handler: h.reviews.Moderate,
+			Request: logic.ReviewModeration{}, Response: models.Review{}},
+		{Method: http.MethodPost, Path: "/reviews/{id}/helpful", Access: api.User, Tag: "reviews",
+			Summary: "Mark a review helpful", Handler: h.reviews.Helpful, Response: models.Review{}},
+		{Method: http.MethodDelete, Path: "/reviews/{id}/helpful", Access: api.User, Tag: "reviews",
+			Summary: "Withdraw your helpful vote", Handler: h.reviews.Helpful, Response: models.Review{}},
+
+		// ---------------- Recommendations ----------------
+		// Like reviews, kept out of /books/{id} to avoid clashing routes.
+		{Method: http.MethodGet, Path: "/recommendations", Tag: "recommendations",
+			Summary: "Books customers also bought, topped up with popular ones", Handler: h.recs.ForBook, Response: []models.Book{},
+			Query: []api.Param{
+				{Name: "bookId", Description: "the book, required"},
+				{Name: "limit", Description: "at most 20; 6 when omitted"},
+			}},
+		{Method: http.MethodGet, Path: "/recommendations/mine", Access: api.User, Tag: "recommendations",
+			Summary: "Books related to your orders and wishlists", Handler: h.recs.Mine, Response: []models.Book{},
+			Query: []api.Param{{Name: "limit", Description: "at most 20; 6 when omitted"}}},
+		{Method: http.MethodPost, Path: "/recommendations/refresh", Access: api.Admin, Tag: "recommendations",
+			Summary: "Recompute recommendations now", Handler: h.recs.Refresh, Response: logic.RefreshReport{}},
+	}
+
+	// ---------------- Authors, publishers, series and genres ----------------
+	routes = append(routes, entityRoutes(models.EntityAuthor, h.authors)...)
+	routes = append(routes, entityRoutes(models.EntityPublisher, h.publishers)...)
+	routes = append(routes, entityRoutes(models.EntitySeries, h.series)...)
+	routes = append(routes, entityRoutes(models.EntityGenre, h.genres)...)
+
+	// ---------------- Categories and tags ----------------
+	routes = append(routes, []api.Route{
+		{Method: http.MethodGet, Path: "/categories", Tag: "categories",
+			Summary: "The category tree", Handler: h.categories.Tree, Response: []models.CategoryNode{}},
+		{Method: http.MethodPost, Path: "/categories", Access: api.Admin, Tag: "categories",
+			Summary: "Create a category, top-level or under parentId", Handler: h.categories.Create,
+			Request: logic.CategoryInput{}, Response: models.Category{}, Status: http.StatusCreated},
+		{Method: http.MethodGet, Path: "/categories/{id}", Tag: "categories",
+			Summary: "Get a category", Handler: h.categories.Get, Response: models.Category{}},
+		{Method: http.MethodPut, Path: "/categories/{id}", Access: api.Admin, Tag: "categories",
+			Summary: "Rename a category or change its description", Handler: h.categories.Update,
+			Request: logic.CategoryInput{}, Response: models.Category{}},
+		{Method: http.MethodDelete, Path: "/categories/{id}", Access: api.Admin, Tag: "categories",
+			Summary: "Delete a category without subcategories or books", Handler: h.categories.Delete, Status: http.StatusNoContent},
+		{Method: http.MethodGet, Path: "/categories/{id}/breadcrumb", Tag: "categories",
+			Summary: "The categories from the top of the tree down to this one", Handler: h.categories.Breadcrumb, Response: []models.Category{}},
+		{Method: http.MethodGet, Path: "/categories/{id}/books", Tag: "categories",
+			Summary: "List the books of a category and its subcategories", Handler: h.categories.Books, Response: []models.Book{}},
+		{Method: http.MethodPost, Path: "/categories/{id}/move", Access: api.Admin, Tag: "categories",
+			Summary: "Move a category and its subcategories under another parent", Handler: h.categories.Move,
+			Request: logic.MoveInput{}, Response: models.Category{}},
+		{Method: http.MethodPost, Path: "/categories/{id}/merge", Access: api.Admin, Tag: "categories",
+			Summary: "Merge another category into this one, moving its subcategories and books", Handler: h.categories.Merge,
+			Request: logic.MergeInput{}, Response: models.Category{}},
+
+		{Method: http.MethodGet, Path: "/tags", Tag: "tags",
+			Summary: "Tags in use with their book counts, most used first", Handler: h.categories.Tags, Response: []models.TagCount{}},
+		{Method: http.MethodGet, Path: "/tags/{tag}/books", Tag: "tags",
+			Summary: "List the books with a tag", Handler: h.categories.TaggedBooks, Response: []models.Book{}},
+	}...)
+	return routes
+}
+
+// entityRoutes are the same for every kind of entity books refer to.
+func entityRoutes(kind string, h *handlers.EntityHandler) []api.Route {
+	noun := models.EntityNoun(kind)
+	path := "/" + kind
+	return []api.Route{
+		{Method: http.MethodGet, Path: path, Tag: kind,
+			Summary: "List " + kind + " by name", Handler: h.List, Response: []models.Entity{}},
+		{Method: http.MethodPost, Path: path, Access: api.Admin, Tag: kind,
+			Summary: "Create a " + noun, Handler: h.Create,
+			Request: logic.EntityInput{}, Response: models.Entity{}, Status: http.StatusCreated},
+		{Method: http.MethodGet, Path: path + "/{id}", Tag: kind,
+			Summary: "Get a " + noun, Handler: h.Get, Response: models.Entity{}},
+		{Method: http.MethodPut, Path: path + "/{id}", Access: api.Admin, Tag: kind,
+			Summary: "Update a " + noun + ", renaming it in its books", Handler: h.Update,
+			Request: logic.EntityInput{}, Response: models.Entity{}},
+		{Method: http.MethodDelete, Path: path + "/{id}", Access: api.Admin, Tag: kind,
+			Summary: "Delete a " + noun + " no book refers to", Handler: h.Delete, Status: http.StatusNoContent},
+		{Method: http.MethodGet, Path: path + "/{id}/books", Tag: kind,
+			Summary: "List the books of a " + noun, Handler: h.Books, Response: []models.Book{}},
+		{Method: http.MethodPost, Path: path + "/{id}/merge", Access: api.Admin, Tag: kind,
+			Summary: "Merge another " + noun + " into this one, moving its books", Handler: h.Merge,
+			Request: logic.MergeInput{}, Response: models.Entity{}},
+	}
+}
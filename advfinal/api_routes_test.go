@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"bookstore/internal/api"
+	"bookstore/internal/config"
+	"bookstore/internal/health"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// testMux holds the real handlers, wired as the server wires them but
+// against a database nobody listens on: requests that reach Mongo fail fast,
+// so routes are exercised up to their access checks and input validation.
+// RegisterRoutes registers process-wide metrics, so it runs once.
+var testMux *http.ServeMux
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "bookstore-routes")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	cfg := config.Default()
+	cfg.Auth.JWTSecret = "test-secret"
+	cfg.Mongo.ReadTimeout = 50 * time.Millisecond
+	cfg.Mongo.WriteTimeout = 50 * time.Millisecond
+	cfg.Catalog.RecommendInterval = 0
+	cfg.Search.Dir = filepath.Join(dir, "search")
+	cfg.Storage.Dir = filepath.Join(dir, "blobs")
+
+	client, err := mongo.Connect(context.Background(), options.Client().
+		ApplyURI("mongodb://127.0.0.1:1").
+		SetServerSelectionTimeout(50*time.Millisecond))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	testMux = http.NewServeMux()
+	closeRoutes := RegisterRoutes(testMux, cfg, client.Database("test"), health.NewChecker(time.Second))
+
+	code := m.Run()
+	_ = closeRoutes()
+	_ = os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+type operation struct {
+	Security  []any          `json:"security"`
+	Responses map[string]any `json:"responses"`
+	Legacy    string         `json:"x-legacy-path"`
+}
+
+// TestSpecMatchesRoutes checks every operation of the served OpenAPI
+// document against the mux: the path and method, and the legacy alias if
+// any, must resolve to the documented route, and an anonymous call with
+// placeholder parameters must answer with a documented status; 401 for
+// routes that need a token.
+func TestSpecMatchesRoutes(t *testing.T) {
+	rec := httptest.NewRecorder()
+	testMux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, api.Prefix+"/openapi.json", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("openapi.json: status %d", rec.Code)
+	}
+	var spec struct {
+		Paths map[string]map[string]operation `json:"paths"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &spec); err != nil {
+		t.Fatal(err)
+	}
+	if len(spec.Paths) == 0 {
+		t.Fatal("openapi.json lists no paths")
+	}
+
+	for path, ops := range spec.Paths {
+		for method, op := range ops {
+			method = strings.ToUpper(method)
+			t.Run(method+" "+path, func(t *testing.T) {
+				req := httptest.NewRequest(method, fillPath(path), strings.NewReader("{"))
+				req.Header.Set("Content-Type", "application/json")
+				if _, pattern := testMux.Handler(req); pattern != method+" "+path {
+					t.Fatalf("resolves to %q", pattern)
+				}
+
+				rec := httptest.NewRecorder()
+				testMux.ServeHTTP(rec, req)
+				if _, ok := op.Responses[strconv.Itoa(rec.Code)]; !ok {
+					t.Errorf("status %d is not documented; body: %s", rec.Code, rec.Body)
+				}
+				if len(op.Security) > 0 && rec.Code != http.StatusUnauthorized {
+					t.Errorf("status %d without a token, want 401", rec.Code)
+				}
+
+				if op.Legacy == "" {
+					return
+				}
+				legacy := httptest.NewRequest(method, fillPath(op.Legacy), nil)
+				if _, pattern := testMux.Handler(legacy); pattern != method+" "+op.Legacy {
+					t.Errorf("legacy path resolves to %q", pattern)
+				}
+			})
+		}
+	}
+}
+
+func TestDeprecatedLinkNamesResource(t *testing.T) {
+	rec := httptest.NewRecorder()
+	testMux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/carts/7", nil))
+
+	if got := rec.Header().Get("Deprecation"); got != "true" {
+		t.Errorf("Deprecation = %q", got)
+	}
+	want := "<" + api.Prefix + `/carts/7>; rel="successor-version"`
+	if got := rec.Header().Get("Link"); got != want {
+		t.Errorf("Link = %q, want %q", got, want)
+	}
+}
+
+func TestUnknownAPIPathGetsErrorBody(t *testing.T) {
+	for _, tc := range []struct {
+		method, path string
+		status       int
+		code         string
+	}{
+		{http.MethodGet, api.Prefix + "/no-such-thing", http.StatusNotFound, "not_found"},
+		{http.MethodPost, api.Prefix + "/books/0/nope", http.StatusNotFound, "not_found"},
+		{http.MethodPatch, api.Prefix + "/openapi.json", http.StatusMethodNotAllowed, "method_not_allowed"},
+	} {
+		t.Run(tc.method+" "+tc.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			testMux.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.path, nil))
+			if rec.Code != tc.status {
+				t.Fatalf("status = %d, want %d; body: %s", rec.Code, tc.status, rec.Body)
+			}
+			var body struct {
+				Error struct {
+					Code string `json:"code"`
+				} `json:"error"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("body is not JSON: %s", rec.Body)
+			}
+			if body.Error.Code != tc.code {
+				t.Errorf("code = %q, want %q", body.Error.Code, tc.code)
+			}
+		})
+	}
+}
+
+// fillPath puts placeholder values into the wildcards of a route path: 0 for
+// ids, which every handler rejects before touching storage, and a word for
+// anything else.
+func fillPath(path string) string {
+	for {
+		open := strings.Index(path, "{")
+		if open < 0 {
+			return path
+		}
+		end := strings.Index(path[open:], "}") + open
+		name := path[open+1 : end]
+		value := "x"
+		if name == "id" || strings.HasSuffix(name, "Id") {
+			value = "0"
+		}
+		path = path[:open] + value + path[end+1:]
+	}
+}
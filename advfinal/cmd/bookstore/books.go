@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"bookstore/internal/logic"
+)
+
+const booksUsage = `  bookstore books export [-file <path>] [-format csv|json]
+      Writes the whole catalog (stdout by default). The format defaults to
+      the file extension, else json.
+  bookstore books import -file <path> [-format csv|json|onix] [-dry-run]
+      Upserts books by ISBN or external id; other rows create new books.
+      ONIX 3.0 messages (.xml) may also update or delete books. Invalid
+      rows are reported and skipped. -dry-run only previews. Imported books
+      are indexed for search; with the bleve backend that needs the server
+      stopped, else use POST /api/v1/books/import. A running server only
+      suggests the new titles once restarted.
+`
+
+func runBooks(ctx context.Context, a *app, args []string) error {
+	sub, args := subcommand(args)
+	fs := flag.NewFlagSet("books "+sub, flag.ContinueOnError)
+	file := fs.String("file", "", "CSV or JSON file")
+	format := fs.String("format", "", "csv, json or onix")
+	dryRun := fs.Bool("dry-run", false, "validate and preview without writing")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+
+	switch sub {
+	case "export":
+		f, err := fileFormat(*format, *file, logic.FormatJSON, logic.ExportFormat)
+		if err != nil {
+			return err
+		}
+		w := io.Writer(os.Stdout)
+		if *file != "" {
+			out, err := os.Create(*file)
+			if err != nil {
+				return err
+			}
+			defer out.Close()
+			w = out
+		}
+		return a.books.ExportBooks(ctx, w, f)
+
+	case "import":
+		if *file == "" {
+			return usagef("-file is required")
+		}
+		f, err := fileFormat(*format, *file, "", logic.ParseFormat)
+		if err != nil {
+			return err
+		}
+		return importBooks(ctx, a, *file, f, *dryRun)
+
+	default:
+		return usagef("unknown subcommand %q", sub)
+	}
+}
+
+// fileFormat resolves -format with parse, falling back to the file's
+// extension and then to def. An empty def makes the format mandatory.
+func fileFormat(format, path, def string, parse func(string) (string, error)) (string, error) {
+	if format == "" {
+		format = strings.TrimPrefix(filepath.Ext(path), ".")
+	}
+	if format == "" {
+		if def == "" {
+			return "", usagef("-format is required when the file has no .csv, .json or .xml extension")
+		}
+		return def, nil
+	}
+	f, err := parse(format)
+	if err != nil {
+		return "", usagef("%s: %v", format, err)
+	}
+	return f, nil
+}
+
+// importBooks keeps going past invalid rows and fails at the end if any row
+// was rejected.
+func importBooks(ctx context.Context, a *app, path, format string, dryRun bool) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	books := a.books
+	if !dryRun {
+		svc, closeIndex, err := a.catalog()
+		if err != nil {
+			return err
+		}
+		defer closeIndex()
+		books = svc
+	}
+
+	report, err := books.ImportBooks(ctx, f, format, dryRun)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	rows := make([][]string, 0, len(report.Rows))
+	for _, r := range report.Rows {
+		msgs := make([]string, 0, len(r.Errors)+1)
+		if r.Note != "" {
+			msgs = append(msgs, r.Note)
+		}
+		for _, e := range r.Errors {
+			msgs = append(msgs, e.Message)
+		}
+		rows = append(rows, []string{itoa(r.Row), r.Action, itoa(r.BookID), r.Title, strings.Join(msgs, "; ")})
+	}
+	if err := a.out.print(report, []string{"ROW", "ACTION", "ID", "TITLE", "NOTE"}, rows); err != nil {
+		return err
+	}
+
+	if !a.out.json {
+		for _, u := range report.Unmapped {
+			if err := a.out.message("unmapped: %s (%d products)", u.Path, u.Count); err != nil {
+				return err
+			}
+		}
+		verb := "imported"
+		if dryRun {
+			verb = "would import"
+		}
+		if err := a.out.message("%s %d rows: %d created, %d updated, %d deleted, %d skipped, %d rejected", verb,
+			report.Total, report.Created, report.Updated, report.Deleted, report.Skipped, report.Failed); err != nil {
+			return err
+		}
+	}
+	if report.Failed > 0 {
+		return fmt.Errorf("%d of %d rows rejected", report.Failed, report.Total)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"flag"
+)
+
+const countersUsage = `  bookstore counters list
+  bookstore counters reset [-to <n>] <name>
+      Without -to the counter is resynced to the highest id in use. Values
+      below that are refused, since they would hand out duplicate ids.
+`
+
+type counterRow struct {
+	Name  string `json:"name"`
+	Seq   int    `json:"seq"`
+	MaxID int    `json:"maxId"`
+}
+
+func runCounters(ctx context.Context, a *app, args []string) error {
+	sub, args := subcommand(args)
+
+	switch sub {
+	case "list":
+		counters, err := a.counters.List(ctx)
+		if err != nil {
+			return err
+		}
+		out := make([]counterRow, 0, len(counters))
+		rows := make([][]string, 0, len(counters))
+		for _, c := range counters {
+			maxID, err := a.counters.MaxID(ctx, c.Name)
+			if err != nil {
+				return err
+			}
+			out = append(out, counterRow{Name: c.Name, Seq: c.Seq, MaxID: maxID})
+			rows = append(rows, []string{c.Name, itoa(c.Seq), itoa(maxID)})
+		}
+		return a.out.print(out, []string{"NAME", "SEQ", "MAX ID"}, rows)
+
+	case "reset":
+		fs := flag.NewFlagSet("counters reset", flag.ContinueOnError)
+		to := fs.Int("to", -1, "new value; the next id handed out is this plus one")
+		if err := parse(fs, args); err != nil {
+			return err
+		}
+		if fs.NArg() != 1 {
+			return usagef("expected one counter name")
+		}
+		name := fs.Arg(0)
+
+		seq := *to
+		if seq < 0 {
+			maxID, err := a.counters.MaxID(ctx, name)
+			if err != nil {
+				return err
+			}
+			seq = maxID
+		}
+		if err := a.counters.Set(ctx, name, seq); err != nil {
+			return err
+		}
+		return a.out.message("counter %s set to %d", name, seq)
+
+	default:
+		return usagef("unknown subcommand %q", sub)
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+)
+
+const jobsUsage = `  bookstore jobs list
+  bookstore jobs retry <id>... | all
+  bookstore jobs discard <id>...
+      Jobs land here when a server worker fails them. Cart jobs cannot be
+      retried from the CLI: carts are kept in the server's memory.
+`
+
+func runJobs(ctx context.Context, a *app, args []string) error {
+	sub, args := subcommand(args)
+
+	switch sub {
+	case "list":
+		jobs := a.jobs.FailedJobs(ctx)
+		rows := make([][]string, 0, len(jobs))
+		for _, j := range jobs {
+			rows = append(rows, []string{
+				itoa(j.ID), j.Type, itoa(j.OrderID), itoa(j.CartID), itoa(j.WishlistID),
+				itoa(j.Attempts), timestamp(j.FailedAt), j.Error,
+			})
+		}
+		return a.out.print(jobs, []string{"ID", "TYPE", "ORDER", "CART", "WISHLIST", "ATTEMPTS", "FAILED", "ERROR"}, rows)
+
+	case "retry", "discard":
+		ids, err := jobIDs(ctx, a, sub, args)
+		if err != nil {
+			return err
+		}
+		results := make([]jobResult, 0, len(ids))
+		rows := make([][]string, 0, len(ids))
+		failed := 0
+		for _, id := range ids {
+			res := jobResult{ID: id, Status: "done"}
+			if sub == "retry" {
+				err = a.jobs.RetryJob(ctx, id)
+			} else {
+				err = a.jobs.DiscardJob(ctx, id)
+			}
+			if err != nil {
+				res.Status, res.Error = "failed", err.Error()
+				failed++
+			}
+			results = append(results, res)
+			rows = append(rows, []string{itoa(id), res.Status, res.Error})
+		}
+		if err := a.out.print(results, []string{"ID", "STATUS", "ERROR"}, rows); err != nil {
+			return err
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d of %d jobs failed", failed, len(ids))
+		}
+		return nil
+
+	default:
+		return usagef("unknown subcommand %q", sub)
+	}
+}
+
+type jobResult struct {
+	ID     int    `json:"id"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+func jobIDs(ctx context.Context, a *app, sub string, args []string) ([]int, error) {
+	if len(args) == 0 {
+		return nil, usagef("%s needs job ids", sub)
+	}
+	if sub == "retry" && len(args) == 1 && args[0] == "all" {
+		var ids []int
+		for _, j := range a.jobs.FailedJobs(ctx) {
+			ids = append(ids, j.ID)
+		}
+		return ids, nil
+	}
+
+	ids := make([]int, 0, len(args))
+	for _, s := range args {
+		id, err := strconv.Atoi(s)
+		if err != nil || id <= 0 {
+			return nil, usagef("invalid job id %q", s)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
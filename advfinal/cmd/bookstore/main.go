@@ -0,0 +1,211 @@
+// Command bookstore is the operator CLI. It loads configuration exactly like
+// the server (environment, .env and CONFIG_FILE) and works directly on the
+// same database through the repository and logic packages.
+//
+//	bookstore [-o table|json] <command> <subcommand> [flags] [args]
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"bookstore/internal/config"
+	"bookstore/internal/db"
+	"bookstore/internal/logging"
+	"bookstore/internal/logic"
+	"bookstore/internal/repository"
+	"bookstore/internal/search"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type app struct {
+	db       *mongo.Database
+	out      *printer
+	books    *logic.BookService
+	auth     *logic.AuthService
+	account  *logic.AccountService
+	orders   *logic.OrderCRUDService
+	jobs     *logic.JobService
+	recs     *logic.RecommendationService
+	counters *repository.CounterRepo
+	// search opens the search index on demand, as only one process can
+	// have the bleve index open.
+	search func() (*logic.SearchService, func() error, error)
+	// catalog is books for writes: it opens the search index the same way
+	// and keeps it in step with every book written.
+	catalog func() (*logic.BookService, func() error, error)
+}
+
+func newApp(cfg config.Config, mongoDB *mongo.Database, out *printer) *app {
+	t := repository.Timeouts{Read: cfg.Mongo.ReadTimeout, Write: cfg.Mongo.WriteTimeout}
+	users := repository.NewUserRepo(mongoDB, t)
+	sessions := repository.NewSessionRepo(mongoDB, t)
+	orders := repository.NewOrderRepo(mongoDB, t)
+	books := repository.NewBookRepo(mongoDB, t)
+	wishlists := repository.NewWishlistRepo(mongoDB, t)
+	entities := logic.NewEntityService(repository.NewEntities(mongoDB, t), books)
+	categories := logic.NewCategoryService(repository.NewCategoryRepo(mongoDB, t), books)
+
+	openIndex := func() (search.SearchIndex, error) {
+		if cfg.Search.Backend != "bleve" {
+			return search.NewMongoIndex(mongoDB, t), nil
+		}
+		idx, _, err := search.OpenBleve(cfg.Search.Dir)
+		if err != nil {
+			return nil, fmt.Errorf("%w (is the server running?)", err)
+		}
+		return idx, nil
+	}
+
+	return &app{
+		db:      mongoDB,
+		out:     out,
+		books:   logic.NewBookService(books, entities, categories, cfg.Catalog.Currency),
+		auth:    logic.NewAuthService(users, sessions, cfg.Auth.JWTSecret, cfg.Auth.TokenTTL),
+		account: logic.NewAccountService(users, sessions, orders),
+		orders:  logic.NewOrderCRUDService(orders),
+		// Carts live in the server's memory, so cart jobs are not retried here.
+		jobs:     logic.NewJobService(repository.NewFailedJobRepo(mongoDB, t), nil, wishlists),
+		recs:     logic.NewRecommendationService(repository.NewRecommendationRepo(mongoDB, t), books, orders, wishlists),
+		counters: repository.NewCounterRepo(mongoDB, t),
+		search: func() (*logic.SearchService, func() error, error) {
+			idx, err := openIndex()
+			if err != nil {
+				return nil, nil, err
+			}
+			return logic.NewSearchService(idx, nil, books), idx.Close, nil
+		},
+		catalog: func() (*logic.BookService, func() error, error) {
+			idx, err := openIndex()
+			if err != nil {
+				return nil, nil, err
+			}
+			synced := search.Synced(books, idx)
+			svc := logic.NewBookService(synced, logic.NewEntityService(repository.NewEntities(mongoDB, t), synced),
+				logic.NewCategoryService(repository.NewCategoryRepo(mongoDB, t), synced), cfg.Catalog.Currency)
+			return svc, idx.Close, nil
+		},
+	}
+}
+
+type command struct {
+	name    string
+	usage   string
+	summary string
+	run     func(ctx context.Context, a *app, args []string) error
+}
+
+var commands = []command{
+	{"users", usersUsage, "create admins and change roles", runUsers},
+	{"books", booksUsage, "import and export the catalog (CSV, JSON, ONIX)", runBooks},
+	{"jobs", jobsUsage, "list, retry and discard failed background jobs", runJobs},
+	{"orders", ordersUsage, "list, inspect and cancel orders", runOrders},
+	{"recommendations", recommendationsUsage, "recompute book recommendations", runRecommendations},
+	{"search", searchUsage, "rebuild the catalog search index", runSearch},
+	{"counters", countersUsage, "inspect and reset id counters", runCounters},
+	{"migrate", migrateUsage, "apply, revert and list schema migrations", runMigrate},
+}
+
+// usageError makes main print the command's usage and exit with status 2.
+type usageError struct{ msg string }
+
+func (e usageError) Error() string { return e.msg }
+
+func usagef(format string, args ...any) error {
+	return usageError{msg: fmt.Sprintf(format, args...)}
+}
+
+func main() {
+	output := flag.String("o", "table", "output format: table or json")
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
+	if *output != "table" && *output != "json" {
+		fmt.Fprintf(os.Stderr, "bookstore: -o must be table or json\n")
+		os.Exit(2)
+	}
+
+	var cmd *command
+	for i := range commands {
+		if commands[i].name == flag.Arg(0) {
+			cmd = &commands[i]
+		}
+	}
+	if cmd == nil {
+		fmt.Fprintf(os.Stderr, "bookstore: unknown command %q\n\n", flag.Arg(0))
+		usage()
+		os.Exit(2)
+	}
+
+	if flag.NArg() == 1 {
+		fmt.Fprintf(os.Stderr, "usage:\n%s", cmd.usage)
+		os.Exit(2)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "bookstore: config: %v\n", err)
+		os.Exit(1)
+	}
+	// Logs go to stderr so that stdout carries only the command's output.
+	slog.SetDefault(logging.New(os.Stderr, cfg.Log.Format, cfg.Log.Level))
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	client, mongoDB, err := db.Connect(cfg.Mongo)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "bookstore: mongo: %v\n", err)
+		os.Exit(1)
+	}
+
+	err = cmd.run(ctx, newApp(cfg, mongoDB, &printer{w: os.Stdout, json: *output == "json"}), flag.Args()[1:])
+	_ = client.Disconnect(context.Background())
+
+	var ue usageError
+	switch {
+	case errors.As(err, &ue):
+		fmt.Fprintf(os.Stderr, "bookstore %s: %v\n\nusage:\n%s", cmd.name, err, cmd.usage)
+		os.Exit(2)
+	case err != nil:
+		fmt.Fprintf(os.Stderr, "bookstore %s: %v\n", cmd.name, err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: bookstore [-o table|json] <command> ...\n\ncommands:\n")
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-16s %s\n", c.name, c.summary)
+	}
+	fmt.Fprintf(os.Stderr, "\nRun a command without arguments for its usage.\n")
+}
+
+// subcommand splits args into the subcommand name and its arguments.
+func subcommand(args []string) (string, []string) {
+	if len(args) == 0 {
+		return "", nil
+	}
+	return args[0], args[1:]
+}
+
+// parse parses flags for a subcommand; errors come back as usage errors.
+func parse(fs *flag.FlagSet, args []string) error {
+	fs.SetOutput(io.Discard)
+	if err := fs.Parse(args); err != nil {
+		return usageError{msg: err.Error()}
+	}
+	return nil
+}
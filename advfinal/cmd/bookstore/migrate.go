@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"strconv"
+
+	"bookstore/internal/migrate"
+)
+
+const migrateUsage = `  bookstore migrate up
+  bookstore migrate down [steps]
+  bookstore migrate status
+`
+
+func runMigrate(ctx context.Context, a *app, args []string) error {
+	m := migrate.NewMigrator(a.db, migrate.All)
+	sub, args := subcommand(args)
+
+	switch sub {
+	case "up":
+		ran, err := m.Up(ctx)
+		if perr := printMigrations(a, "applied", ran); perr != nil {
+			return perr
+		}
+		return err
+
+	case "down":
+		steps := 1
+		if len(args) > 0 {
+			n, err := strconv.Atoi(args[0])
+			if err != nil || n < 1 {
+				return usagef("invalid step count %q", args[0])
+			}
+			steps = n
+		}
+		reverted, err := m.Down(ctx, steps)
+		if perr := printMigrations(a, "reverted", reverted); perr != nil {
+			return perr
+		}
+		return err
+
+	case "status":
+		list, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		rows := make([][]string, 0, len(list))
+		for _, st := range list {
+			applied := "pending"
+			if st.Applied() {
+				applied = timestamp(*st.AppliedAt)
+			}
+			rows = append(rows, []string{itoa(st.Version), st.Description, applied})
+		}
+		return a.out.print(list, []string{"VERSION", "DESCRIPTION", "APPLIED"}, rows)
+
+	default:
+		return usagef("unknown subcommand %q", sub)
+	}
+}
+
+func printMigrations(a *app, verb string, list []migrate.Migration) error {
+	type item struct {
+		Version     int    `json:"version"`
+		Description string `json:"description"`
+	}
+	out := make([]item, 0, len(list))
+	rows := make([][]string, 0, len(list))
+	for _, mg := range list {
+		out = append(out, item{Version: mg.Version, Description: mg.Description})
+		rows = append(rows, []string{itoa(mg.Version), mg.Description, verb})
+	}
+	return a.out.print(out, []string{"VERSION", "DESCRIPTION", "RESULT"}, rows)
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strconv"
+
+	"bookstore/internal/models"
+)
+
+const ordersUsage = `  bookstore orders list [-customer <id>]
+  bookstore orders show <id>
+  bookstore orders cancel <id>
+`
+
+func runOrders(ctx context.Context, a *app, args []string) error {
+	sub, args := subcommand(args)
+
+	switch sub {
+	case "list":
+		fs := flag.NewFlagSet("orders list", flag.ContinueOnError)
+		customer := fs.Int("customer", 0, "only orders of this customer id")
+		if err := parse(fs, args); err != nil {
+			return err
+		}
+
+		orders := a.orders.ListOrders(ctx)
+		if *customer > 0 {
+			own := []models.Order{}
+			for _, o := range orders {
+				if o.CustomerID == *customer {
+					own = append(own, o)
+				}
+			}
+			orders = own
+		}
+		return printOrders(a, orders)
+
+	case "show":
+		id, err := orderID(args)
+		if err != nil {
+			return err
+		}
+		o, items, err := a.orders.GetOrder(ctx, id)
+		if err != nil {
+			return err
+		}
+		if a.out.json {
+			return a.out.print(map[string]any{"order": o, "items": items}, nil, nil)
+		}
+		if err := printOrders(a, []models.Order{o}); err != nil {
+			return err
+		}
+		fmt.Fprintln(a.out.w)
+		rows := make([][]string, 0, len(items))
+		for _, it := range items {
+			rows = append(rows, []string{itoa(it.ID), itoa(it.BookID), itoa(it.Qty), money(it.Price)})
+		}
+		return a.out.print(items, []string{"ITEM", "BOOK", "QTY", "PRICE"}, rows)
+
+	case "cancel":
+		id, err := orderID(args)
+		if err != nil {
+			return err
+		}
+		o, err := a.orders.CancelOrder(ctx, id)
+		if err != nil {
+			return err
+		}
+		return printOrders(a, []models.Order{o})
+
+	default:
+		return usagef("unknown subcommand %q", sub)
+	}
+}
+
+func orderID(args []string) (int, error) {
+	if len(args) != 1 {
+		return 0, usagef("expected one order id")
+	}
+	id, err := strconv.Atoi(args[0])
+	if err != nil || id <= 0 {
+		return 0, usagef("invalid order id %q", args[0])
+	}
+	return id, nil
+}
+
+func printOrders(a *app, orders []models.Order) error {
+	rows := make([][]string, 0, len(orders))
+	for _, o := range orders {
+		rows = append(rows, []string{
+			itoa(o.ID), itoa(o.CustomerID), money(o.Total), o.Status, timestamp(o.CreatedAt),
+		})
+	}
+	return a.out.print(orders, []string{"ID", "CUSTOMER", "TOTAL", "STATUS", "CREATED"}, rows)
+}
@@ -0,0 +1,25 @@
+package main
+
+import "context"
+
+const recommendationsUsage = `  bookstore recommendations refresh
+      Recomputes "customers also bought" from all orders and wishlists, as
+      the server does every catalog.recommendInterval.
+`
+
+func runRecommendations(ctx context.Context, a *app, args []string) error {
+	sub, _ := subcommand(args)
+
+	switch sub {
+	case "refresh":
+		rep, err := a.recs.Refresh(ctx)
+		if err != nil {
+			return err
+		}
+		return a.out.print(rep, []string{"ORDERS", "WISHLISTS", "BOOKS", "COMPUTED"},
+			[][]string{{itoa(rep.Orders), itoa(rep.Wishlists), itoa(rep.Books), timestamp(rep.ComputedAt)}})
+
+	default:
+		return usagef("unknown subcommand %q", sub)
+	}
+}
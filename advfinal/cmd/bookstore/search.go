@@ -0,0 +1,31 @@
+package main
+
+import "context"
+
+const searchUsage = `  bookstore search rebuild
+      Reindexes the whole catalog for search. Only one process can have
+      the bleve index open, so stop the server first, or use
+      POST /api/v1/books/search/rebuild while it runs. The server builds a
+      missing index itself when it starts.
+`
+
+func runSearch(ctx context.Context, a *app, args []string) error {
+	sub, _ := subcommand(args)
+
+	switch sub {
+	case "rebuild":
+		svc, closeIndex, err := a.search()
+		if err != nil {
+			return err
+		}
+		defer closeIndex()
+		rep, err := svc.Rebuild(ctx)
+		if err != nil {
+			return err
+		}
+		return a.out.print(rep, []string{"BOOKS"}, [][]string{{itoa(rep.Books)}})
+
+	default:
+		return usagef("unknown subcommand %q", sub)
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"bookstore/internal/logic"
+)
+
+const usersUsage = `  bookstore users create-admin -email <email> [-password <password>]
+      The password is read from stdin when -password is omitted.
+  bookstore users set-role -email <email> -role user|admin
+`
+
+func runUsers(ctx context.Context, a *app, args []string) error {
+	sub, args := subcommand(args)
+	fs := flag.NewFlagSet("users "+sub, flag.ContinueOnError)
+	email := fs.String("email", "", "account email")
+
+	switch sub {
+	case "create-admin":
+		password := fs.String("password", "", "initial password")
+		if err := parse(fs, args); err != nil {
+			return err
+		}
+		if *email == "" {
+			return usagef("-email is required")
+		}
+		if *password == "" {
+			p, err := readLine("password: ")
+			if err != nil {
+				return err
+			}
+			*password = p
+		}
+		if err := a.auth.CreateUser(ctx, *email, *password, logic.RoleAdmin); err != nil {
+			return err
+		}
+		return a.out.message("admin %s created", *email)
+
+	case "set-role":
+		role := fs.String("role", "", "user or admin")
+		if err := parse(fs, args); err != nil {
+			return err
+		}
+		if *email == "" || *role == "" {
+			return usagef("-email and -role are required")
+		}
+		if err := a.account.SetRole(ctx, *email, *role); err != nil {
+			return err
+		}
+		return a.out.message("%s is now %s", *email, *role)
+
+	default:
+		return usagef("unknown subcommand %q", sub)
+	}
+}
+
+// readLine prompts on stderr when stdin is a terminal and reads one line.
+func readLine(prompt string) (string, error) {
+	if fi, err := os.Stdin.Stat(); err == nil && fi.Mode()&os.ModeCharDevice != 0 {
+		fmt.Fprint(os.Stderr, prompt)
+	}
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	line = strings.TrimRight(line, "\r\n")
+	if line == "" {
+		if err != nil {
+			return "", fmt.Errorf("read stdin: %w", err)
+		}
+		return "", errors.New("empty input")
+	}
+	return line, nil
+}
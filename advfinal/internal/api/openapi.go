@@ -0,0 +1,302 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"bookstore/internal/middleware"
+	"bookstore/internal/validate"
+)
+
+var pathParam = regexp.MustCompile(`\{([a-zA-Z0-9_]+)\}`)
+
+// Spec builds an OpenAPI 3 document from the route table. Schemas are derived
+// from the Go request and response types by reflection and honour json tags.
+func Spec(title, version string, routes []Route) map[string]any {
+	g := &schemaGen{components: map[string]any{}}
+
+	paths := map[string]map[string]any{}
+	for _, rt := range routes {
+		path := Prefix + rt.Path
+		if paths[path] == nil {
+			paths[path] = map[string]any{}
+		}
+		paths[path][strings.ToLower(rt.Method)] = g.operation(rt)
+	}
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":   title,
+			"version": version,
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": g.components,
+			"securitySchemes": map[string]any{
+				"bearerAuth": map[string]any{
+					"type":         "http",
+					"scheme":       "bearer",
+					"bearerFormat": "JWT",
+				},
+			},
+		},
+	}
+}
+
+// SpecHandler serves the document built once at startup.
+func SpecHandler(title, version string, routes []Route) http.HandlerFunc {
+	body, err := json.MarshalIndent(Spec(title, version, routes), "", "  ")
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err != nil {
+			middleware.WriteError(w, r, http.StatusInternalServerError, "internal", "could not build OpenAPI document", nil)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(body)
+	}
+}
+
+func (g *schemaGen) operation(rt Route) map[string]any {
+	op := map[string]any{
+		"summary":     rt.Summary,
+		"operationId": operationID(rt),
+	}
+	if rt.Tag != "" {
+		op["tags"] = []string{rt.Tag}
+	}
+
+	params := []any{}
+	for _, m := range pathParam.FindAllStringSubmatch(rt.Path, -1) {
+		// Ids are numbers; other keys, such as ISBNs, are strings.
+		typ := "string"
+		if m[1] == "id" || strings.HasSuffix(m[1], "Id") {
+			typ = "integer"
+		}
+		params = append(params, map[string]any{
+			"name":     m[1],
+			"in":       "path",
+			"required": true,
+			"schema":   map[string]any{"type": typ},
+		})
+	}
+	for _, q := range rt.Query {
+		params = append(params, map[string]any{
+			"name":        q.Name,
+			"in":          "query",
+			"description": q.Description,
+			"schema":      map[string]any{"type": "string"},
+		})
+	}
+	if len(params) > 0 {
+		op["parameters"] = params
+	}
+
+	if content := g.content(rt.Request, rt.Consumes); content != nil {
+		op["requestBody"] = map[string]any{
+			"required": true,
+			"content":  content,
+		}
+	}
+
+	success := map[string]any{"description": http.StatusText(rt.status())}
+	if content := g.content(rt.Response, rt.Produces); content != nil {
+		success["content"] = content
+	}
+
+	errRef := map[string]any{
+		"content": map[string]any{
+			"application/json": map[string]any{"schema": g.schema(reflect.TypeOf(middleware.ErrorBody{}))},
+		},
+	}
+	responses := map[string]any{
+		strconv.Itoa(rt.status()): success,
+		"400":                     withDescription(errRef, "Invalid request"),
+		"404":                     withDescription(errRef, "Not found"),
+		"500":                     withDescription(errRef, "Internal error, such as the database being unavailable"),
+	}
+	if rt.Access != Public {
+		op["security"] = []any{map[string]any{"bearerAuth": []string{}}}
+		responses["401"] = withDescription(errRef, "Missing or invalid token")
+		responses["403"] = withDescription(errRef, "Forbidden")
+	}
+	op["responses"] = responses
+
+	if rt.Legacy != "" {
+		op["x-legacy-path"] = rt.Legacy
+	}
+	return op
+}
+
+// content documents a body: JSON from the Go type, other media types as
+// opaque files.
+func (g *schemaGen) content(body any, mediaTypes []string) map[string]any {
+	content := map[string]any{}
+	for _, mt := range mediaTypes {
+		content[mt] = map[string]any{"schema": map[string]any{"type": "string", "format": "binary"}}
+	}
+	if body != nil {
+		content["application/json"] = map[string]any{"schema": g.schema(reflect.TypeOf(body))}
+	}
+	if len(content) == 0 {
+		return nil
+	}
+	return content
+}
+
+func withDescription(base map[string]any, desc string) map[string]any {
+	out := map[string]any{"description": desc}
+	for k, v := range base {
+		out[k] = v
+	}
+	return out
+}
+
+func operationID(rt Route) string {
+	parts := []string{strings.ToLower(rt.Method)}
+	for _, seg := range strings.Split(strings.Trim(rt.Path, "/"), "/") {
+		if m := pathParam.FindStringSubmatch(seg); m != nil {
+			seg = "by_" + m[1]
+		}
+		parts = append(parts, seg)
+	}
+	return strings.Join(parts, "_")
+}
+
+type schemaGen struct {
+	components map[string]any
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func (g *schemaGen) schema(t reflect.Type) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t == timeType {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "format": "byte"}
+		}
+		return map[string]any{"type": "array", "items": g.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": g.schema(t.Elem())}
+	case reflect.Interface:
+		return map[string]any{}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.object(t)
+		}
+		name := t.Name()
+		if _, ok := g.components[name]; !ok {
+			g.components[name] = map[string]any{} // placeholder for recursive types
+			g.components[name] = g.object(t)
+		}
+		return map[string]any{"$ref": "#/components/schemas/" + name}
+	}
+	return map[string]any{}
+}
+
+func (g *schemaGen) object(t reflect.Type) map[string]any {
+	props := map[string]any{}
+	var required []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		if f.Anonymous && f.Type.Kind() == reflect.Struct && f.Tag.Get("json") == "" {
+			// encoding/json promotes the fields of embedded structs.
+			inner := g.object(f.Type)
+			for name, prop := range inner["properties"].(map[string]any) {
+				props[name] = prop
+			}
+			if r, ok := inner["required"].([]string); ok {
+				required = append(required, r...)
+			}
+			continue
+		}
+
+		name := f.Name
+		if tag := f.Tag.Get("json"); tag != "" {
+			tagName, _, _ := strings.Cut(tag, ",")
+			if tagName == "-" {
+				continue
+			}
+			if tagName != "" {
+				name = tagName
+			}
+		}
+		prop := g.schema(f.Type)
+		if tag := f.Tag.Get("validate"); tag != "" {
+			if constrain(prop, tag) {
+				required = append(required, name)
+			}
+		}
+		props[name] = prop
+	}
+
+	obj := map[string]any{"type": "object", "properties": props}
+	if len(required) > 0 {
+		obj["required"] = required
+	}
+	return obj
+}
+
+// constrain copies the validate rules of a field into its schema and reports
+// whether the field is required.
+func constrain(prop map[string]any, tag string) bool {
+	if _, isRef := prop["$ref"]; isRef {
+		return strings.Contains(","+tag+",", ",required,")
+	}
+
+	isString := prop["type"] == "string"
+	required := false
+	for _, rule := range strings.Split(tag, ",") {
+		name, arg, _ := strings.Cut(rule, "=")
+		n, _ := strconv.ParseFloat(arg, 64)
+
+		switch {
+		case name == "required":
+			required = true
+			if isString {
+				prop["minLength"] = 1
+			}
+		case name == "email":
+			prop["format"] = "email"
+		case name == "password":
+			required = true
+			prop["format"] = "password"
+			prop["minLength"] = validate.PasswordMin
+			prop["maxLength"] = validate.PasswordMax
+		case name == "min" && isString:
+			prop["minLength"] = n
+		case name == "max" && isString:
+			prop["maxLength"] = n
+		case name == "min":
+			prop["minimum"] = n
+		case name == "max":
+			prop["maximum"] = n
+		}
+	}
+	return required
+}
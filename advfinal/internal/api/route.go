@@ -0,0 +1,129 @@
+package api
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"bookstore/internal/middleware"
+)
+
+const Prefix = "/api/v1"
+
+type Access int
+
+const (
+	Public Access = iota
+	User
+	Admin
+)
+
+// Route describes one JSON endpoint. The same table is used to register the
+// handlers and to generate the OpenAPI document, so the two cannot drift.
+type Route struct {
+	Method  string
+	Path    string // relative to Prefix, in net/http pattern syntax
+	Legacy  string // pre-v1 path kept as a deprecated alias, if any
+	Access  Access
+	Tag     string
+	Summary string
+	Handler http.HandlerFunc
+
+	Request  any // zero value of the JSON body type, nil when there is none
+	Response any // zero value of the success body type, nil for no content
+	Status   int // success status, 200 when unset
+	Query    []Param
+
+	// Media types of non-JSON bodies. Request or Response, when also set,
+	// documents the JSON form.
+	Consumes []string
+	Produces []string
+}
+
+// Message documents the {"message": "..."} body returned by mutations.
+type Message struct {
+	Message string `json:"message"`
+}
+
+type Param struct {
+	Name        string
+	Description string
+}
+
+func (rt Route) status() int {
+	if rt.Status == 0 {
+		return http.StatusOK
+	}
+	return rt.Status
+}
+
+func (rt Route) wrap(secret string, active middleware.SessionCheck) http.HandlerFunc {
+	switch rt.Access {
+	case Admin:
+		return middleware.AdminOnly(secret, active, rt.Handler)
+	case User:
+		return middleware.AuthOnly(secret, active, rt.Handler)
+	default:
+		return rt.Handler
+	}
+}
+
+// Mount registers every route under Prefix and its legacy alias, if any.
+// Legacy aliases answer exactly like the v1 route but advertise their
+// successor through the Deprecation and Link headers. active is passed on to
+// the auth middleware of protected routes. Other paths under Prefix get the
+// JSON error body rather than the storefront's pages.
+func Mount(mux *http.ServeMux, secret string, active middleware.SessionCheck, routes []Route) {
+	for _, rt := range routes {
+		h := rt.wrap(secret, active)
+		mux.HandleFunc(rt.Method+" "+Prefix+rt.Path, h)
+
+		if rt.Legacy != "" {
+			mux.HandleFunc(rt.Method+" "+rt.Legacy, Deprecated(Prefix+rt.Path, h))
+		}
+	}
+	for _, m := range methods {
+		mux.HandleFunc(m+" "+fallback, notFound(mux))
+	}
+}
+
+// fallback catches the paths under Prefix that no route matches. It is
+// registered per method, as a pattern for all methods would clash with the
+// storefront's "GET /".
+const fallback = Prefix + "/"
+
+var methods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+
+// notFound answers 405 when the path has routes for other methods, as the
+// mux itself would, and 404 otherwise.
+func notFound(mux *http.ServeMux) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var allow []string
+		for _, m := range methods {
+			probe := &http.Request{Method: m, URL: r.URL, Host: r.Host}
+			if _, pattern := mux.Handler(probe); pattern != m+" "+fallback {
+				allow = append(allow, m)
+			}
+		}
+		if len(allow) > 0 {
+			w.Header().Set("Allow", strings.Join(allow, ", "))
+			middleware.WriteError(w, r, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed", nil)
+			return
+		}
+		middleware.WriteError(w, r, http.StatusNotFound, "not_found", "no such endpoint", nil)
+	}
+}
+
+// Deprecated marks next's responses as coming from a deprecated alias of
+// successor. Wildcards in successor, such as {id}, are filled in from the
+// request's path values so the Link header names the actual resource.
+func Deprecated(successor string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		link := pathParam.ReplaceAllStringFunc(successor, func(m string) string {
+			return url.PathEscape(r.PathValue(m[1 : len(m)-1]))
+		})
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Link", "<"+link+`>; rel="successor-version"`)
+		next(w, r)
+	}
+}
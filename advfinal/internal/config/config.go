@@ -0,0 +1,421 @@
+// Package config loads the server configuration once at startup.
+//
+// Values are layered, later sources winning: built-in defaults, the YAML file
+// named by CONFIG_FILE (if set), then environment variables, which include
+// anything in a .env file in the working directory. Tracing keeps reading the
+// standard OTEL_* variables itself.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/netip"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+type Config struct {
+	Server  Server  `yaml:"server"`
+	Mongo   Mongo   `yaml:"mongo"`
+	Auth    Auth    `yaml:"auth"`
+	Workers Workers `yaml:"workers"`
+	Catalog Catalog `yaml:"catalog"`
+	Storage Storage `yaml:"storage"`
+	Search  Search  `yaml:"search"`
+	Log     Log     `yaml:"log"`
+	Metrics Metrics `yaml:"metrics"`
+}
+
+type Server struct {
+	Port               int           `yaml:"port"`
+	ShutdownTimeout    time.Duration `yaml:"shutdownTimeout"`
+	DrainDelay         time.Duration `yaml:"drainDelay"`
+	HealthCheckTimeout time.Duration `yaml:"healthCheckTimeout"`
+	// PublicURL is where users reach the site, such as
+	// https://books.example.com. Links in shared pages are built from it;
+	// without it they use the request's host.
+	PublicURL string `yaml:"publicURL"`
+	// TrustedProxies lists the addresses or CIDR ranges of reverse proxies
+	// whose X-Forwarded-For header is believed. Without any, the peer
+	// address is the client address.
+	TrustedProxies []string `yaml:"trustedProxies"`
+}
+
+// Proxies parses TrustedProxies; entries that do not parse, which Validate
+// reports, are left out.
+func (s Server) Proxies() []netip.Prefix {
+	var out []netip.Prefix
+	for _, p := range s.TrustedProxies {
+		if prefix, err := parseProxy(p); err == nil {
+			out = append(out, prefix)
+		}
+	}
+	return out
+}
+
+// parseProxy accepts a single address, such as 10.0.0.5, or a range, such
+// as 10.0.0.0/8.
+func parseProxy(s string) (netip.Prefix, error) {
+	if strings.Contains(s, "/") {
+		p, err := netip.ParsePrefix(s)
+		return p.Masked(), err
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+type Mongo struct {
+	URI            string        `yaml:"uri"`
+	Database       string        `yaml:"database"`
+	ConnectTimeout time.Duration `yaml:"connectTimeout"`
+	ReadTimeout    time.Duration `yaml:"readTimeout"`
+	WriteTimeout   time.Duration `yaml:"writeTimeout"`
+	// MigrateOnStart applies pending migrations before the server listens.
+	MigrateOnStart bool `yaml:"migrateOnStart"`
+}
+
+type Auth struct {
+	JWTSecret string        `yaml:"jwtSecret"`
+	TokenTTL  time.Duration `yaml:"tokenTTL"`
+}
+
+type Workers struct {
+	Order     int `yaml:"order"`
+	QueueSize int `yaml:"queueSize"` // order queue
+	// Cart and CartQueueSize size the stock reservation workers.
+	Cart          int `yaml:"cart"`
+	CartQueueSize int `yaml:"cartQueueSize"`
+}
+
+type Catalog struct {
+	// Currency is the ISO 4217 code prices are kept in; imported ONIX
+	// prices in other currencies are ignored.
+	Currency string `yaml:"currency"`
+	// ImportDir, when set, is watched for catalog files to import. See
+	// logic.ImportJobs.Watch for the layout.
+	ImportDir      string        `yaml:"importDir"`
+	ImportInterval time.Duration `yaml:"importInterval"`
+	// RecommendInterval is how often recommendations are recomputed from
+	// orders and wishlists; 0 leaves it to "bookstore recommendations
+	// refresh" and the admin API.
+	RecommendInterval time.Duration `yaml:"recommendInterval"`
+}
+
+// Storage says where uploaded files, such as cover images, are kept.
+type Storage struct {
+	Driver string    `yaml:"driver"` // local or s3
+	Dir    string    `yaml:"dir"`    // root of the local driver
+	S3     StorageS3 `yaml:"s3"`
+}
+
+// StorageS3 configures the s3 driver, which works with AWS S3 and
+// compatible services such as MinIO.
+type StorageS3 struct {
+	Endpoint  string `yaml:"endpoint"`
+	Region    string `yaml:"region"`
+	Bucket    string `yaml:"bucket"`
+	AccessKey string `yaml:"accessKey"`
+	SecretKey string `yaml:"secretKey"`
+}
+
+// Search picks how the catalog is searched: bleve keeps its own index on
+// disk and tolerates typos; mongo uses the books text index (migration 12).
+type Search struct {
+	Backend string `yaml:"backend"` // bleve or mongo
+	// Dir holds the bleve index. Only one process can have it open, so
+	// "bookstore search rebuild" needs the server stopped.
+	Dir string `yaml:"dir"`
+}
+
+type Log struct {
+	Format string `yaml:"format"`
+	Level  string `yaml:"level"`
+}
+
+type Metrics struct {
+	// Token, when set, is required as a bearer token on /metrics.
+	Token string `yaml:"token"`
+}
+
+func Default() Config {
+	return Config{
+		Server: Server{
+			Port:               8080,
+			ShutdownTimeout:    15 * time.Second,
+			DrainDelay:         5 * time.Second,
+			HealthCheckTimeout: 2 * time.Second,
+		},
+		Mongo: Mongo{
+			ConnectTimeout: 30 * time.Second,
+			ReadTimeout:    5 * time.Second,
+			WriteTimeout:   10 * time.Second,
+		},
+		Auth:    Auth{TokenTTL: 24 * time.Hour},
+		Workers: Workers{Order: 2, QueueSize: 100, Cart: 2, CartQueueSize: 100},
+		Catalog: Catalog{Currency: "USD", ImportInterval: time.Minute, RecommendInterval: time.Hour},
+		Storage: Storage{Driver: "local", Dir: "data/blobs", S3: StorageS3{Region: "us-east-1"}},
+		Search:  Search{Backend: "bleve", Dir: "data/search"},
+		Log:     Log{Format: "json", Level: "info"},
+	}
+}
+
+// Load builds the configuration and validates it. The returned error lists
+// every problem found, not just the first.
+func Load() (Config, error) {
+	if err := godotenv.Load(); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return Config{}, fmt.Errorf(".env: %w", err)
+	}
+
+	cfg := Default()
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		if err := loadFile(path, &cfg); err != nil {
+			return Config{}, err
+		}
+	}
+
+	e := &envReader{}
+	e.int("PORT", &cfg.Server.Port)
+	e.duration("SHUTDOWN_TIMEOUT", &cfg.Server.ShutdownTimeout)
+	e.duration("SHUTDOWN_DRAIN_DELAY", &cfg.Server.DrainDelay)
+	e.duration("HEALTH_CHECK_TIMEOUT", &cfg.Server.HealthCheckTimeout)
+	e.str("PUBLIC_URL", &cfg.Server.PublicURL)
+	e.list("TRUSTED_PROXIES", &cfg.Server.TrustedProxies)
+	e.str("MONGO_URI", &cfg.Mongo.URI)
+	e.str("MONGO_DB", &cfg.Mongo.Database)
+	e.duration("MONGO_CONNECT_TIMEOUT", &cfg.Mongo.ConnectTimeout)
+	e.duration("MONGO_READ_TIMEOUT", &cfg.Mongo.ReadTimeout)
+	e.duration("MONGO_WRITE_TIMEOUT", &cfg.Mongo.WriteTimeout)
+	e.bool("MIGRATE_ON_START", &cfg.Mongo.MigrateOnStart)
+	e.str("JWT_SECRET", &cfg.Auth.JWTSecret)
+	e.duration("TOKEN_TTL", &cfg.Auth.TokenTTL)
+	e.int("ORDER_WORKERS", &cfg.Workers.Order)
+	e.int("ORDER_QUEUE_SIZE", &cfg.Workers.QueueSize)
+	e.int("CART_WORKERS", &cfg.Workers.Cart)
+	e.int("CART_QUEUE_SIZE", &cfg.Workers.CartQueueSize)
+	e.str("CATALOG_CURRENCY", &cfg.Catalog.Currency)
+	e.str("CATALOG_IMPORT_DIR", &cfg.Catalog.ImportDir)
+	e.duration("CATALOG_IMPORT_INTERVAL", &cfg.Catalog.ImportInterval)
+	e.duration("CATALOG_RECOMMEND_INTERVAL", &cfg.Catalog.RecommendInterval)
+	e.str("STORAGE_DRIVER", &cfg.Storage.Driver)
+	e.str("STORAGE_DIR", &cfg.Storage.Dir)
+	e.str("S3_ENDPOINT", &cfg.Storage.S3.Endpoint)
+	e.str("S3_REGION", &cfg.Storage.S3.Region)
+	e.str("S3_BUCKET", &cfg.Storage.S3.Bucket)
+	e.str("S3_ACCESS_KEY", &cfg.Storage.S3.AccessKey)
+	e.str("S3_SECRET_KEY", &cfg.Storage.S3.SecretKey)
+	e.str("SEARCH_BACKEND", &cfg.Search.Backend)
+	e.str("SEARCH_DIR", &cfg.Search.Dir)
+	e.str("LOG_FORMAT", &cfg.Log.Format)
+	e.str("LOG_LEVEL", &cfg.Log.Level)
+	e.str("METRICS_TOKEN", &cfg.Metrics.Token)
+
+	if err := errors.Join(append(e.errs, cfg.Validate())...); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+func loadFile(path string, cfg *Config) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("config file: %w", err)
+	}
+	defer f.Close()
+
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+	return nil
+}
+
+func (c Config) Validate() error {
+	var errs []error
+	bad := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if c.Server.Port < 1 || c.Server.Port > 65535 {
+		bad("server.port: %d is not a valid port", c.Server.Port)
+	}
+	if c.Server.ShutdownTimeout <= 0 {
+		bad("server.shutdownTimeout must be positive")
+	}
+	if c.Server.DrainDelay < 0 {
+		bad("server.drainDelay must not be negative")
+	}
+	if c.Server.HealthCheckTimeout <= 0 {
+		bad("server.healthCheckTimeout must be positive")
+	}
+	if u := c.Server.PublicURL; u != "" {
+		if p, err := url.Parse(u); err != nil || (p.Scheme != "http" && p.Scheme != "https") || p.Host == "" {
+			bad("server.publicURL: %q is not an http or https URL", u)
+		}
+	}
+	for _, p := range c.Server.TrustedProxies {
+		if _, err := parseProxy(p); err != nil {
+			bad("server.trustedProxies: %q is not an IP address or CIDR range", p)
+		}
+	}
+
+	switch {
+	case c.Mongo.URI == "":
+		bad("mongo.uri is required (MONGO_URI)")
+	case !strings.HasPrefix(c.Mongo.URI, "mongodb://") && !strings.HasPrefix(c.Mongo.URI, "mongodb+srv://"):
+		bad("mongo.uri must start with mongodb:// or mongodb+srv://")
+	}
+	if c.Mongo.Database == "" {
+		bad("mongo.database is required (MONGO_DB)")
+	}
+	if c.Mongo.ConnectTimeout <= 0 || c.Mongo.ReadTimeout <= 0 || c.Mongo.WriteTimeout <= 0 {
+		bad("mongo timeouts must be positive")
+	}
+
+	if c.Auth.JWTSecret == "" {
+		bad("auth.jwtSecret is required (JWT_SECRET)")
+	}
+	if c.Auth.TokenTTL <= 0 {
+		bad("auth.tokenTTL must be positive")
+	}
+
+	if c.Workers.Order < 1 {
+		bad("workers.order must be at least 1")
+	}
+	if c.Workers.QueueSize < 1 {
+		bad("workers.queueSize must be at least 1")
+	}
+	if c.Workers.Cart < 1 {
+		bad("workers.cart must be at least 1")
+	}
+	if c.Workers.CartQueueSize < 1 {
+		bad("workers.cartQueueSize must be at least 1")
+	}
+
+	if len(c.Catalog.Currency) != 3 {
+		bad("catalog.currency: %q is not a three-letter currency code", c.Catalog.Currency)
+	}
+	if c.Catalog.ImportDir != "" && c.Catalog.ImportInterval < time.Second {
+		bad("catalog.importInterval must be at least 1s")
+	}
+	if c.Catalog.RecommendInterval != 0 && c.Catalog.RecommendInterval < time.Minute {
+		bad("catalog.recommendInterval must be 0 or at least 1m")
+	}
+
+	switch c.Storage.Driver {
+	case "local":
+		if c.Storage.Dir == "" {
+			bad("storage.dir is required for the local driver (STORAGE_DIR)")
+		}
+	case "s3":
+		s3 := c.Storage.S3
+		if p, err := url.Parse(s3.Endpoint); err != nil || (p.Scheme != "http" && p.Scheme != "https") || p.Host == "" {
+			bad("storage.s3.endpoint: %q is not an http or https URL (S3_ENDPOINT)", s3.Endpoint)
+		}
+		if s3.Region == "" || s3.Bucket == "" {
+			bad("storage.s3.region and storage.s3.bucket are required (S3_REGION, S3_BUCKET)")
+		}
+		if s3.AccessKey == "" || s3.SecretKey == "" {
+			bad("storage.s3.accessKey and storage.s3.secretKey are required (S3_ACCESS_KEY, S3_SECRET_KEY)")
+		}
+	default:
+		bad("storage.driver: %q is not local or s3", c.Storage.Driver)
+	}
+
+	switch c.Search.Backend {
+	case "bleve":
+		if c.Search.Dir == "" {
+			bad("search.dir is required for the bleve backend (SEARCH_DIR)")
+		}
+	case "mongo":
+	default:
+		bad("search.backend: %q is not bleve or mongo", c.Search.Backend)
+	}
+
+	if f := strings.ToLower(c.Log.Format); f != "json" && f != "text" {
+		bad("log.format: %q is not json or text", c.Log.Format)
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.Log.Level)); err != nil {
+		bad("log.level: %q is not debug, info, warn or error", c.Log.Level)
+	}
+
+	return errors.Join(errs...)
+}
+
+// envReader applies set environment variables over the defaults and collects
+// parse errors so they are all reported together.
+type envReader struct {
+	errs []error
+}
+
+func (e *envReader) str(key string, dst *string) {
+	if v, ok := os.LookupEnv(key); ok && v != "" {
+		*dst = v
+	}
+}
+
+// list reads a comma-separated value.
+func (e *envReader) list(key string, dst *[]string) {
+	v, ok := os.LookupEnv(key)
+	if !ok || v == "" {
+		return
+	}
+	var out []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	*dst = out
+}
+
+func (e *envReader) int(key string, dst *int) {
+	v, ok := os.LookupEnv(key)
+	if !ok || v == "" {
+		return
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		e.errs = append(e.errs, fmt.Errorf("%s: %q is not an integer", key, v))
+		return
+	}
+	*dst = n
+}
+
+func (e *envReader) bool(key string, dst *bool) {
+	v, ok := os.LookupEnv(key)
+	if !ok || v == "" {
+		return
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		e.errs = append(e.errs, fmt.Errorf("%s: %q is not true or false", key, v))
+		return
+	}
+	*dst = b
+}
+
+func (e *envReader) duration(key string, dst *time.Duration) {
+	v, ok := os.LookupEnv(key)
+	if !ok || v == "" {
+		return
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		e.errs = append(e.errs, fmt.Errorf("%s: %q is not a duration such as 30s or 5m", key, v))
+		return
+	}
+	*dst = d
+}
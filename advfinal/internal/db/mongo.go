@@ -2,28 +2,33 @@ package db
 
 import (
 	"context"
-	"os"
-	"time"
+
+	"bookstore/internal/config"
 
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.opentelemetry.io/contrib/instrumentation/go.mongodb.org/mongo-driver/mongo/otelmongo"
 )
 
-func Bg() context.Context {
-	return context.Background()
-}
-
-func Connect() (*mongo.Client, *mongo.Database, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+// Connect does not wait for the server; call Ping to find out whether it is
+// reachable.
+func Connect(cfg config.Mongo) (*mongo.Client, *mongo.Database, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ConnectTimeout)
 	defer cancel()
 
-	uri := os.Getenv("MONGO_URI")
-	dbName := os.Getenv("MONGO_DB")
+	opts := options.Client().
+		ApplyURI(cfg.URI).
+		SetMonitor(otelmongo.NewMonitor())
 
-	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	client, err := mongo.Connect(ctx, opts)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	return client, client.Database(dbName), nil
+	return client, client.Database(cfg.Database), nil
+}
+
+func Ping(ctx context.Context, client *mongo.Client) error {
+	return client.Ping(ctx, readpref.Primary())
 }
@@ -0,0 +1,254 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"bookstore/internal/logic"
+	"bookstore/internal/middleware"
+	"bookstore/internal/models"
+)
+
+type AccountHandler struct {
+	service *logic.AccountService
+}
+
+func NewAccountHandler(service *logic.AccountService) *AccountHandler {
+	return &AccountHandler{service: service}
+}
+
+// Profile is the public view of a user; it never carries the password hash.
+type Profile struct {
+	ID        int              `json:"id"`
+	Email     string           `json:"email"`
+	Role      string           `json:"role"`
+	Name      string           `json:"name"`
+	Phone     string           `json:"phone"`
+	Addresses []models.Address `json:"addresses"`
+	CreatedAt time.Time        `json:"createdAt"`
+}
+
+func profileView(u models.User) Profile {
+	addresses := u.Addresses
+	if addresses == nil {
+		addresses = []models.Address{}
+	}
+	return Profile{
+		ID:        u.ID,
+		Email:     u.Email,
+		Role:      u.Role,
+		Name:      u.Name,
+		Phone:     u.Phone,
+		Addresses: addresses,
+		CreatedAt: u.CreatedAt,
+	}
+}
+
+func (h *AccountHandler) Me(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserID(r)
+	if !ok {
+		writeErrorMsg(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		u, err := h.service.Profile(r.Context(), userID)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, profileView(u))
+
+	case http.MethodPut:
+		var in logic.ProfileInput
+		if !decodeJSON(w, r, &in) {
+			return
+		}
+
+		u, err := h.service.UpdateProfile(r.Context(), userID, in.Name, in.Phone)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, profileView(u))
+
+	default:
+		writeErrorMsg(w, r, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (h *AccountHandler) Password(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserID(r)
+	if !ok {
+		writeErrorMsg(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var in logic.PasswordChange
+	if !decodeJSON(w, r, &in) {
+		return
+	}
+
+	if err := h.service.ChangePassword(r.Context(), userID, in.CurrentPassword, in.NewPassword, middleware.SessionID(r)); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"message": "password changed"})
+}
+
+func (h *AccountHandler) Email(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserID(r)
+	if !ok {
+		writeErrorMsg(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var in logic.EmailChange
+	if !decodeJSON(w, r, &in) {
+		return
+	}
+
+	if err := h.service.ChangeEmail(r.Context(), userID, in.CurrentPassword, in.Email); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"message": "email changed"})
+}
+
+func (h *AccountHandler) Sessions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserID(r)
+	if !ok {
+		writeErrorMsg(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"current":  middleware.SessionID(r),
+		"sessions": h.service.Sessions(r.Context(), userID),
+	})
+}
+
+func (h *AccountHandler) SessionByID(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserID(r)
+	if !ok {
+		writeErrorMsg(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id <= 0 {
+		writeErrorMsg(w, r, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	if err := h.service.RevokeSession(r.Context(), userID, id); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"message": "revoked"})
+}
+
+func (h *AccountHandler) Addresses(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserID(r)
+	if !ok {
+		writeErrorMsg(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		list, err := h.service.Addresses(r.Context(), userID)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, list)
+
+	case http.MethodPost:
+		var in models.Address
+		if !decodeJSON(w, r, &in) {
+			return
+		}
+
+		a, err := h.service.AddAddress(r.Context(), userID, in)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, a)
+
+	default:
+		writeErrorMsg(w, r, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (h *AccountHandler) AddressByID(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserID(r)
+	if !ok {
+		writeErrorMsg(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id <= 0 {
+		writeErrorMsg(w, r, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		var in models.Address
+		if !decodeJSON(w, r, &in) {
+			return
+		}
+		in.ID = id
+
+		if err := h.service.UpdateAddress(r.Context(), userID, in); err != nil {
+			writeError(w, r, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"message": "updated"})
+
+	case http.MethodDelete:
+		if err := h.service.DeleteAddress(r.Context(), userID, id); err != nil {
+			writeError(w, r, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"message": "deleted"})
+
+	default:
+		writeErrorMsg(w, r, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (h *AccountHandler) AddressDefault(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserID(r)
+	if !ok {
+		writeErrorMsg(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id <= 0 {
+		writeErrorMsg(w, r, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	if err := h.service.SetDefaultAddress(r.Context(), userID, id); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"message": "updated"})
+}
+
+func (h *AccountHandler) Orders(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserID(r)
+	if !ok {
+		writeErrorMsg(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, h.service.OrderHistory(r.Context(), userID))
+}
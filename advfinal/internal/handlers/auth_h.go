@@ -1,10 +1,10 @@
 package handlers
 
 import (
-	"encoding/json"
 	"net/http"
 
 	"bookstore/internal/logic"
+	"bookstore/internal/middleware"
 )
 
 type AuthHandler struct {
@@ -19,34 +19,18 @@ func NewAuthHandler(service *logic.AuthService) *AuthHandler {
 
 func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		writeJSON(
-			w,
-			http.StatusMethodNotAllowed,
-			map[string]string{"error": "method not allowed"},
-		)
+		writeErrorMsg(w, r, http.StatusMethodNotAllowed, "method not allowed")
 		return
 	}
 
-	var in struct {
-		Email    string `json:"email"`
-		Password string `json:"password"`
-	}
+	var in logic.Registration
 
-	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
-		writeJSON(
-			w,
-			http.StatusBadRequest,
-			map[string]string{"error": "invalid JSON"},
-		)
+	if !decodeJSON(w, r, &in) {
 		return
 	}
 
-	if err := h.service.Register(in.Email, in.Password); err != nil {
-		writeJSON(
-			w,
-			http.StatusBadRequest,
-			map[string]string{"error": err.Error()},
-		)
+	if err := h.service.Register(r.Context(), in.Email, in.Password); err != nil {
+		writeError(w, r, err)
 		return
 	}
 
@@ -59,35 +43,19 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 
 func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		writeJSON(
-			w,
-			http.StatusMethodNotAllowed,
-			map[string]string{"error": "method not allowed"},
-		)
+		writeErrorMsg(w, r, http.StatusMethodNotAllowed, "method not allowed")
 		return
 	}
 
-	var in struct {
-		Email    string `json:"email"`
-		Password string `json:"password"`
-	}
+	var in logic.Credentials
 
-	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
-		writeJSON(
-			w,
-			http.StatusBadRequest,
-			map[string]string{"error": "invalid JSON"},
-		)
+	if !decodeJSON(w, r, &in) {
 		return
 	}
 
-	token, err := h.service.Login(in.Email, in.Password)
+	token, err := h.service.Login(r.Context(), in.Email, in.Password, r.UserAgent(), middleware.ClientIP(r))
 	if err != nil {
-		writeJSON(
-			w,
-			http.StatusUnauthorized,
-			map[string]string{"error": err.Error()},
-		)
+		writeError(w, r, err)
 		return
 	}
 
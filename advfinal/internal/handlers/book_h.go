@@ -1,7 +1,6 @@
 package handlers
 
 import (
-	"encoding/json"
 	"net/http"
 	"strconv"
 
@@ -18,85 +17,82 @@ func NewBookHandler(service *logic.BookService) *BookHandler {
 }
 
 func (h *BookHandler) Books(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-
 	switch r.Method {
 	case http.MethodGet:
-		books := h.service.ListBooks()
-		_ = json.NewEncoder(w).Encode(books)
+		books, err := h.service.ListBooksSorted(r.Context(), r.URL.Query().Get("sort"))
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, books)
 
 	case http.MethodPost:
 		var b models.Book
-		if err := json.NewDecoder(r.Body).Decode(&b); err != nil {
-			w.WriteHeader(http.StatusBadRequest)
-			_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid json"})
+		if !decodeJSON(w, r, &b) {
 			return
 		}
 
-		created, err := h.service.CreateBook(b)
+		created, err := h.service.CreateBook(r.Context(), b)
 		if err != nil {
-			w.WriteHeader(http.StatusBadRequest)
-			_ = json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			writeError(w, r, err)
 			return
 		}
 
-		w.WriteHeader(http.StatusCreated)
-		_ = json.NewEncoder(w).Encode(created)
+		writeJSON(w, http.StatusCreated, created)
 
 	default:
-		w.WriteHeader(http.StatusMethodNotAllowed)
-		_ = json.NewEncoder(w).Encode(map[string]string{"error": "method not allowed"})
+		writeErrorMsg(w, r, http.StatusMethodNotAllowed, "method not allowed")
 	}
 }
 
 func (h *BookHandler) BookByID(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-
-	idStr := r.PathValue("id")
-	id, err := strconv.Atoi(idStr)
+	id, err := strconv.Atoi(r.PathValue("id"))
 	if err != nil || id <= 0 {
-		w.WriteHeader(http.StatusBadRequest)
-		_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid id"})
+		writeErrorMsg(w, r, http.StatusBadRequest, "invalid id")
 		return
 	}
 
 	switch r.Method {
 	case http.MethodGet:
-		b, err := h.service.GetBook(id)
+		b, err := h.service.GetBook(r.Context(), id)
 		if err != nil {
-			w.WriteHeader(http.StatusNotFound)
-			_ = json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			writeError(w, r, err)
 			return
 		}
-		_ = json.NewEncoder(w).Encode(b)
+		writeJSON(w, http.StatusOK, b)
 
 	case http.MethodPut:
 		var b models.Book
-		if err := json.NewDecoder(r.Body).Decode(&b); err != nil {
-			w.WriteHeader(http.StatusBadRequest)
-			_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid json"})
+		if !decodeJSON(w, r, &b) {
 			return
 		}
 
 		b.ID = id
-		if err := h.service.UpdateBook(b); err != nil {
-			w.WriteHeader(http.StatusBadRequest)
-			_ = json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		updated, err := h.service.UpdateBook(r.Context(), b)
+		if err != nil {
+			writeError(w, r, err)
 			return
 		}
 
-		_ = json.NewEncoder(w).Encode(b)
+		writeJSON(w, http.StatusOK, updated)
 
 	case http.MethodDelete:
-		if err := h.service.DeleteBook(id); err != nil {
-			w.WriteHeader(http.StatusNotFound)
-			_ = json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		if err := h.service.DeleteBook(r.Context(), id); err != nil {
+			writeError(w, r, err)
 			return
 		}
 		w.WriteHeader(http.StatusNoContent)
 
 	default:
-		w.WriteHeader(http.StatusMethodNotAllowed)
-		_ = json.NewEncoder(w).Encode(map[string]string{"error": "method not allowed"})
+		writeErrorMsg(w, r, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (h *BookHandler) BookByISBN(w http.ResponseWriter, r *http.Request) {
+	b, err := h.service.GetBookByISBN(r.Context(), r.PathValue("isbn"))
+	if err != nil {
+		writeError(w, r, err)
+		return
 	}
+	writeJSON(w, http.StatusOK, b)
 }
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"time"
+
+	"bookstore/internal/logging"
+	"bookstore/internal/logic"
+)
+
+const (
+	// maxImportBytes caps an uploaded catalog file.
+	maxImportBytes = 32 << 20
+
+	// Files up to inlineImportBytes are imported while the request waits;
+	// larger ones become a background job.
+	inlineImportBytes = 1 << 20
+)
+
+type BookTransferHandler struct {
+	service *logic.BookService
+	jobs    *logic.ImportJobs
+}
+
+func NewBookTransferHandler(service *logic.BookService, jobs *logic.ImportJobs) *BookTransferHandler {
+	return &BookTransferHandler{service: service, jobs: jobs}
+}
+
+// Import takes a CSV, JSON or ONIX catalog as the raw request body. The format
+// comes from ?format= or else the Content-Type; ?dryRun=true only reports
+// what would change.
+func (h *BookTransferHandler) Import(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = r.Header.Get("Content-Type")
+	}
+	format, err := logic.ParseFormat(format)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dryRun"))
+
+	// Spool the body first: the import may outlive the request.
+	f, err := os.CreateTemp("", "book-import-*."+format)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	keep := false
+	defer func() {
+		f.Close()
+		if !keep {
+			os.Remove(f.Name())
+		}
+	}()
+
+	n, err := io.Copy(f, http.MaxBytesReader(w, r.Body, maxImportBytes))
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			writeErrorMsg(w, r, http.StatusRequestEntityTooLarge, "file is too large")
+			return
+		}
+		writeErrorMsg(w, r, http.StatusBadRequest, "could not read request body")
+		return
+	}
+	if n == 0 {
+		writeErrorMsg(w, r, http.StatusBadRequest, "request body is required")
+		return
+	}
+
+	if n > inlineImportBytes {
+		if err := f.Close(); err != nil {
+			writeError(w, r, err)
+			return
+		}
+		keep = true
+		job := h.jobs.Start(r.Context(), f.Name(), format, dryRun)
+		w.Header().Set("Location", path.Join(path.Dir(r.URL.Path), "imports", strconv.Itoa(job.ID)))
+		writeJSON(w, http.StatusAccepted, job)
+		return
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	report, err := h.service.ImportBooks(r.Context(), f, format, dryRun)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	logging.From(r.Context()).Info("book import finished",
+		"dry_run", dryRun, "created", report.Created, "updated", report.Updated, "failed", report.Failed)
+	writeJSON(w, http.StatusOK, report)
+}
+
+func (h *BookTransferHandler) ImportJob(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id <= 0 {
+		writeErrorMsg(w, r, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	job, err := h.jobs.Get(id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, job)
+}
+
+// Export streams the whole catalog. Once the body has started an error can
+// only cut it short, so it is logged rather than reported.
+func (h *BookTransferHandler) Export(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = logic.FormatCSV
+	}
+	format, err := logic.ExportFormat(format)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	contentType := "text/csv; charset=utf-8"
+	if format == logic.FormatJSON {
+		contentType = "application/json"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition",
+		`attachment; filename="books-`+time.Now().UTC().Format("20060102")+"."+format+`"`)
+
+	if err := h.service.ExportBooks(r.Context(), w, format); err != nil {
+		logging.From(r.Context()).Error("book export failed", "err", err)
+	}
+}
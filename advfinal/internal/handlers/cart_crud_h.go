@@ -1,10 +1,8 @@
 package handlers
 
 import (
-	"encoding/json"
 	"net/http"
 	"strconv"
-	"strings"
 
 	"bookstore/internal/logic"
 	"bookstore/internal/middleware"
@@ -22,7 +20,7 @@ func NewCartHandler(service *logic.CartCRUDService) *CartHandler {
 func (h *CartHandler) Carts(w http.ResponseWriter, r *http.Request) {
 	userID, ok := middleware.UserID(r)
 	if !ok {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		writeErrorMsg(w, r, http.StatusUnauthorized, "unauthorized")
 		return
 	}
 	role := middleware.Role(r)
@@ -30,11 +28,11 @@ func (h *CartHandler) Carts(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodGet:
 		if role == "admin" {
-			writeJSON(w, http.StatusOK, h.service.ListCarts())
+			writeJSON(w, http.StatusOK, h.service.ListCarts(r.Context()))
 			return
 		}
 
-		all := h.service.ListCarts()
+		all := h.service.ListCarts(r.Context())
 		out := make([]models.Cart, 0)
 		for _, c := range all {
 			if c.CustomerID == userID {
@@ -44,37 +42,36 @@ func (h *CartHandler) Carts(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusOK, out)
 
 	case http.MethodPost:
-		c := h.service.CreateCart(userID)
+		c := h.service.CreateCart(r.Context(), userID)
 		writeJSON(w, http.StatusCreated, c)
 
 	default:
-		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		writeErrorMsg(w, r, http.StatusMethodNotAllowed, "method not allowed")
 	}
 }
 
 func (h *CartHandler) CartByID(w http.ResponseWriter, r *http.Request) {
 	userID, ok := middleware.UserID(r)
 	if !ok {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		writeErrorMsg(w, r, http.StatusUnauthorized, "unauthorized")
 		return
 	}
 	role := middleware.Role(r)
 
-	idStr := strings.TrimPrefix(r.URL.Path, "/carts/")
-	id, err := strconv.Atoi(idStr)
+	id, err := strconv.Atoi(r.PathValue("id"))
 	if err != nil || id <= 0 {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid id"})
+		writeErrorMsg(w, r, http.StatusBadRequest, "invalid id")
 		return
 	}
 
-	c, items, err := h.service.GetCart(id)
+	c, items, err := h.service.GetCart(r.Context(), id)
 	if err != nil {
-		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		writeError(w, r, err)
 		return
 	}
 
 	if role != "admin" && c.CustomerID != userID {
-		writeJSON(w, http.StatusForbidden, map[string]string{"error": "forbidden"})
+		writeErrorMsg(w, r, http.StatusForbidden, "forbidden")
 		return
 	}
 
@@ -84,8 +81,7 @@ func (h *CartHandler) CartByID(w http.ResponseWriter, r *http.Request) {
 
 	case http.MethodPut:
 		var in models.Cart
-		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
-			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON"})
+		if !decodeJSON(w, r, &in) {
 			return
 		}
 		in.ID = id
@@ -94,138 +90,117 @@ func (h *CartHandler) CartByID(w http.ResponseWriter, r *http.Request) {
 			in.CustomerID = userID
 		}
 
-		if err := h.service.UpdateCart(in); err != nil {
-			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		if err := h.service.UpdateCart(r.Context(), in); err != nil {
+			writeError(w, r, err)
 			return
 		}
 		writeJSON(w, http.StatusOK, map[string]string{"message": "updated"})
 
 	case http.MethodDelete:
-		if err := h.service.DeleteCart(id); err != nil {
-			writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		if err := h.service.DeleteCart(r.Context(), id); err != nil {
+			writeError(w, r, err)
 			return
 		}
 		writeJSON(w, http.StatusOK, map[string]string{"message": "deleted"})
 
 	default:
-		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		writeErrorMsg(w, r, http.StatusMethodNotAllowed, "method not allowed")
 	}
 }
 
 func (h *CartHandler) CartItems(w http.ResponseWriter, r *http.Request) {
 	userID, ok := middleware.UserID(r)
 	if !ok {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		writeErrorMsg(w, r, http.StatusUnauthorized, "unauthorized")
 		return
 	}
 	role := middleware.Role(r)
 
-	path := strings.TrimPrefix(r.URL.Path, "/carts/")
-	parts := strings.Split(path, "/")
-	if len(parts) < 2 || parts[1] != "items" {
-		http.NotFound(w, r)
-		return
-	}
-
-	cartID, err := strconv.Atoi(parts[0])
+	cartID, err := strconv.Atoi(r.PathValue("id"))
 	if err != nil || cartID <= 0 {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid cart id"})
+		writeErrorMsg(w, r, http.StatusBadRequest, "invalid cart id")
 		return
 	}
 
-	c, _, err := h.service.GetCart(cartID)
+	c, _, err := h.service.GetCart(r.Context(), cartID)
 	if err != nil {
-		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		writeError(w, r, err)
 		return
 	}
 	if role != "admin" && c.CustomerID != userID {
-		writeJSON(w, http.StatusForbidden, map[string]string{"error": "forbidden"})
+		writeErrorMsg(w, r, http.StatusForbidden, "forbidden")
 		return
 	}
 
 	switch r.Method {
 	case http.MethodPost:
-		var in struct {
-			BookID int `json:"bookId"`
-			Qty    int `json:"qty"`
-		}
-		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
-			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON"})
+		var in logic.ItemInput
+		if !decodeJSON(w, r, &in) {
 			return
 		}
 
-		item, err := h.service.AddItem(cartID, in.BookID, in.Qty)
+		item, err := h.service.AddItem(r.Context(), cartID, in.BookID, in.Qty)
 		if err != nil {
-			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			writeError(w, r, err)
 			return
 		}
 		writeJSON(w, http.StatusCreated, item)
 
 	default:
-		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		writeErrorMsg(w, r, http.StatusMethodNotAllowed, "method not allowed")
 	}
 }
 
 func (h *CartHandler) CartItemByID(w http.ResponseWriter, r *http.Request) {
 	userID, ok := middleware.UserID(r)
 	if !ok {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		writeErrorMsg(w, r, http.StatusUnauthorized, "unauthorized")
 		return
 	}
 	role := middleware.Role(r)
 
-	path := strings.TrimPrefix(r.URL.Path, "/carts/")
-	parts := strings.Split(path, "/")
-	if len(parts) < 3 || parts[1] != "items" {
-		http.NotFound(w, r)
-		return
-	}
-
-	cartID, err := strconv.Atoi(parts[0])
+	cartID, err := strconv.Atoi(r.PathValue("id"))
 	if err != nil || cartID <= 0 {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid cart id"})
+		writeErrorMsg(w, r, http.StatusBadRequest, "invalid cart id")
 		return
 	}
 
-	itemID, err := strconv.Atoi(parts[2])
+	itemID, err := strconv.Atoi(r.PathValue("itemId"))
 	if err != nil || itemID <= 0 {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid item id"})
+		writeErrorMsg(w, r, http.StatusBadRequest, "invalid item id")
 		return
 	}
 
-	c, _, err := h.service.GetCart(cartID)
+	c, _, err := h.service.GetCart(r.Context(), cartID)
 	if err != nil {
-		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		writeError(w, r, err)
 		return
 	}
 	if role != "admin" && c.CustomerID != userID {
-		writeJSON(w, http.StatusForbidden, map[string]string{"error": "forbidden"})
+		writeErrorMsg(w, r, http.StatusForbidden, "forbidden")
 		return
 	}
 
 	switch r.Method {
 	case http.MethodPut:
-		var in struct {
-			Qty int `json:"qty"`
-		}
-		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
-			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON"})
+		var in logic.QtyInput
+		if !decodeJSON(w, r, &in) {
 			return
 		}
-		if err := h.service.UpdateItem(cartID, itemID, in.Qty); err != nil {
-			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		if err := h.service.UpdateItem(r.Context(), cartID, itemID, in.Qty); err != nil {
+			writeError(w, r, err)
 			return
 		}
 		writeJSON(w, http.StatusOK, map[string]string{"message": "updated"})
 
 	case http.MethodDelete:
-		if err := h.service.DeleteItem(cartID, itemID); err != nil {
-			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		if err := h.service.DeleteItem(r.Context(), cartID, itemID); err != nil {
+			writeError(w, r, err)
 			return
 		}
 		writeJSON(w, http.StatusOK, map[string]string{"message": "deleted"})
 
 	default:
-		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		writeErrorMsg(w, r, http.StatusMethodNotAllowed, "method not allowed")
 	}
 }
@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"bookstore/internal/logic"
+)
+
+// CategoryHandler serves the category tree and the tags books carry.
+type CategoryHandler struct {
+	service *logic.CategoryService
+	books   *logic.BookService
+}
+
+func NewCategoryHandler(service *logic.CategoryService, books *logic.BookService) *CategoryHandler {
+	return &CategoryHandler{service: service, books: books}
+}
+
+func (h *CategoryHandler) Tree(w http.ResponseWriter, r *http.Request) {
+	tree, err := h.service.Tree(r.Context())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, tree)
+}
+
+func (h *CategoryHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var in logic.CategoryInput
+	if !decodeJSON(w, r, &in) {
+		return
+	}
+	c, err := h.service.Create(r.Context(), in)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, c)
+}
+
+func (h *CategoryHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.id(w, r)
+	if !ok {
+		return
+	}
+	c, err := h.service.Get(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, c)
+}
+
+func (h *CategoryHandler) Update(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.id(w, r)
+	if !ok {
+		return
+	}
+	var in logic.CategoryInput
+	if !decodeJSON(w, r, &in) {
+		return
+	}
+	c, err := h.service.Update(r.Context(), id, in)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, c)
+}
+
+func (h *CategoryHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.id(w, r)
+	if !ok {
+		return
+	}
+	if err := h.service.Delete(r.Context(), id); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *CategoryHandler) Breadcrumb(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.id(w, r)
+	if !ok {
+		return
+	}
+	c, err := h.service.Get(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	path, err := h.service.Breadcrumb(r.Context(), c)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, path)
+}
+
+// Books lists the books of the category and of every category below it.
+func (h *CategoryHandler) Books(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.id(w, r)
+	if !ok {
+		return
+	}
+	if _, err := h.service.Get(r.Context(), id); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	books, err := h.service.Books(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, books)
+}
+
+// Move answers POST /categories/{id}/move.
+func (h *CategoryHandler) Move(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.id(w, r)
+	if !ok {
+		return
+	}
+	var in logic.MoveInput
+	if !decodeJSON(w, r, &in) {
+		return
+	}
+	c, err := h.service.Move(r.Context(), id, in)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, c)
+}
+
+// Merge answers POST /categories/{id}/merge, folding the category named in
+// the body into the one in the path.
+func (h *CategoryHandler) Merge(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.id(w, r)
+	if !ok {
+		return
+	}
+	var in logic.MergeInput
+	if !decodeJSON(w, r, &in) {
+		return
+	}
+	c, err := h.service.Merge(r.Context(), id, in)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, c)
+}
+
+func (h *CategoryHandler) Tags(w http.ResponseWriter, r *http.Request) {
+	tags, err := h.books.Tags(r.Context())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, tags)
+}
+
+func (h *CategoryHandler) TaggedBooks(w http.ResponseWriter, r *http.Request) {
+	books, err := h.books.TaggedBooks(r.Context(), r.PathValue("tag"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, books)
+}
+
+func (h *CategoryHandler) id(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id <= 0 {
+		writeErrorMsg(w, r, http.StatusBadRequest, "invalid id")
+		return 0, false
+	}
+	return id, true
+}
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"bookstore/internal/logging"
+	"bookstore/internal/logic"
+)
+
+// maxCoverBytes caps an uploaded cover image.
+const maxCoverBytes = 10 << 20
+
+type CoverHandler struct {
+	service *logic.CoverService
+}
+
+func NewCoverHandler(service *logic.CoverService) *CoverHandler {
+	return &CoverHandler{service: service}
+}
+
+// Upload takes the image as the raw request body, or as the "cover" field
+// of a multipart form.
+func (h *CoverHandler) Upload(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id <= 0 {
+		writeErrorMsg(w, r, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxCoverBytes+1<<20) // room for form overhead
+	var body io.Reader = r.Body
+	if mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mt == "multipart/form-data" {
+		f, _, err := r.FormFile("cover")
+		if err != nil {
+			writeCoverReadError(w, r, err)
+			return
+		}
+		defer f.Close()
+		body = f
+	}
+
+	data, err := io.ReadAll(io.LimitReader(body, maxCoverBytes+1))
+	if err != nil {
+		writeCoverReadError(w, r, err)
+		return
+	}
+	if len(data) > maxCoverBytes {
+		writeErrorMsg(w, r, http.StatusRequestEntityTooLarge, "cover must be at most 10 MiB")
+		return
+	}
+	if len(data) == 0 {
+		writeErrorMsg(w, r, http.StatusBadRequest, "request body is required")
+		return
+	}
+
+	b, err := h.service.UploadCover(r.Context(), id, data)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, b)
+}
+
+func writeCoverReadError(w http.ResponseWriter, r *http.Request, err error) {
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		writeErrorMsg(w, r, http.StatusRequestEntityTooLarge, "cover must be at most 10 MiB")
+		return
+	}
+	writeErrorMsg(w, r, http.StatusBadRequest, "could not read the cover image")
+}
+
+func (h *CoverHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id <= 0 {
+		writeErrorMsg(w, r, http.StatusBadRequest, "invalid id")
+		return
+	}
+	b, err := h.service.DeleteCover(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, b)
+}
+
+// Serve answers GET /covers/{id}/{version}/{file}. A version names one
+// upload, so responses may be cached indefinitely.
+func (h *CoverHandler) Serve(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(r.PathValue("id"))
+	version := r.PathValue("version")
+	size, ok := strings.CutSuffix(r.PathValue("file"), ".jpg")
+	if id <= 0 || !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	etag := `"` + version + "-" + size + `"`
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	w.Header().Set("ETag", etag)
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	body, info, err := h.service.OpenCover(r.Context(), id, version, size)
+	if err != nil {
+		w.Header().Del("Cache-Control")
+		w.Header().Del("ETag")
+		if errors.Is(err, logic.ErrNotFound) {
+			http.NotFound(w, r)
+			return
+		}
+		writeError(w, r, err)
+		return
+	}
+	defer body.Close()
+
+	w.Header().Set("Content-Type", info.ContentType)
+	if info.Size > 0 {
+		w.Header().Set("Content-Length", strconv.FormatInt(info.Size, 10))
+	}
+	if !info.ModTime.IsZero() {
+		w.Header().Set("Last-Modified", info.ModTime.UTC().Format(http.TimeFormat))
+	}
+	if _, err := io.Copy(w, body); err != nil {
+		logging.From(r.Context()).Warn("serve cover", "book_id", id, "err", err)
+	}
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"bookstore/internal/logic"
+	"bookstore/internal/middleware"
+)
+
+// maxBodyBytes caps JSON and form bodies. Nothing the API accepts comes close.
+const maxBodyBytes = 1 << 20
+
+// decodeJSON reads exactly one JSON value into dst, rejecting unknown fields,
+// trailing data and oversized bodies. On failure it writes the error response
+// and returns false.
+func decodeJSON(w http.ResponseWriter, r *http.Request, dst any) bool {
+	r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+
+	err := dec.Decode(dst)
+	if err == nil && dec.Decode(&struct{}{}) != io.EOF {
+		err = errors.New("body must contain a single JSON value")
+	}
+	if err == nil {
+		return true
+	}
+
+	var (
+		maxErr    *http.MaxBytesError
+		syntaxErr *json.SyntaxError
+		typeErr   *json.UnmarshalTypeError
+	)
+	switch {
+	case errors.As(err, &maxErr):
+		writeErrorMsg(w, r, http.StatusRequestEntityTooLarge, "request body is too large")
+	case errors.Is(err, io.EOF):
+		writeErrorMsg(w, r, http.StatusBadRequest, "request body is required")
+	case errors.As(err, &syntaxErr), errors.Is(err, io.ErrUnexpectedEOF):
+		writeErrorMsg(w, r, http.StatusBadRequest, "malformed JSON")
+	case errors.As(err, &typeErr):
+		field := typeErr.Field
+		writeFieldError(w, r, field, field+" must be "+jsonType(typeErr.Type))
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		writeFieldError(w, r, field, "unknown field "+field)
+	default:
+		writeErrorMsg(w, r, http.StatusBadRequest, err.Error())
+	}
+	return false
+}
+
+// parseForm parses a submitted HTML form under the same size cap.
+func parseForm(w http.ResponseWriter, r *http.Request) bool {
+	r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+	if err := r.ParseForm(); err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			http.Error(w, "form is too large", http.StatusRequestEntityTooLarge)
+			return false
+		}
+		http.Error(w, "invalid form", http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+func writeFieldError(w http.ResponseWriter, r *http.Request, field, msg string) {
+	middleware.WriteError(w, r, http.StatusBadRequest, "validation_failed", msg,
+		[]logic.FieldError{{Field: field, Message: msg}})
+}
+
+func jsonType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	default:
+		return "an object"
+	}
+}
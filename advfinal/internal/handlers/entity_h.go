@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"bookstore/internal/logic"
+)
+
+// EntityHandler serves one kind of entity: authors, publishers, series or
+// genres.
+type EntityHandler struct {
+	service *logic.EntityService
+	kind    string
+}
+
+func NewEntityHandler(service *logic.EntityService, kind string) *EntityHandler {
+	return &EntityHandler{service: service, kind: kind}
+}
+
+func (h *EntityHandler) List(w http.ResponseWriter, r *http.Request) {
+	list, err := h.service.List(r.Context(), h.kind)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+func (h *EntityHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var in logic.EntityInput
+	if !decodeJSON(w, r, &in) {
+		return
+	}
+	e, err := h.service.Create(r.Context(), h.kind, in)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, e)
+}
+
+func (h *EntityHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.id(w, r)
+	if !ok {
+		return
+	}
+	e, err := h.service.Get(r.Context(), h.kind, id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, e)
+}
+
+func (h *EntityHandler) Update(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.id(w, r)
+	if !ok {
+		return
+	}
+	var in logic.EntityInput
+	if !decodeJSON(w, r, &in) {
+		return
+	}
+	e, err := h.service.Update(r.Context(), h.kind, id, in)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, e)
+}
+
+func (h *EntityHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.id(w, r)
+	if !ok {
+		return
+	}
+	if err := h.service.Delete(r.Context(), h.kind, id); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Merge answers POST /{kind}/{id}/merge, folding the entity named in the
+// body into the one in the path.
+func (h *EntityHandler) Merge(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.id(w, r)
+	if !ok {
+		return
+	}
+	var in logic.MergeInput
+	if !decodeJSON(w, r, &in) {
+		return
+	}
+	e, err := h.service.Merge(r.Context(), h.kind, id, in)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, e)
+}
+
+func (h *EntityHandler) Books(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.id(w, r)
+	if !ok {
+		return
+	}
+	if _, err := h.service.Get(r.Context(), h.kind, id); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	books, err := h.service.Books(r.Context(), h.kind, id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, books)
+}
+
+func (h *EntityHandler) id(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id <= 0 {
+		writeErrorMsg(w, r, http.StatusBadRequest, "invalid id")
+		return 0, false
+	}
+	return id, true
+}
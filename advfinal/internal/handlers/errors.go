@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"bookstore/internal/logging"
+	"bookstore/internal/logic"
+	"bookstore/internal/middleware"
+)
+
+// writeError is the single place where domain errors become HTTP statuses.
+// Anything that is not one of the typed errors is treated as an internal
+// failure and its message is not leaked to the client.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, logic.ErrValidation):
+		middleware.WriteError(w, r, http.StatusBadRequest, "validation_failed", err.Error(), logic.FieldErrors(err))
+	case errors.Is(err, logic.ErrNotFound):
+		middleware.WriteError(w, r, http.StatusNotFound, "not_found", err.Error(), nil)
+	case errors.Is(err, logic.ErrConflict):
+		middleware.WriteError(w, r, http.StatusConflict, "conflict", err.Error(), nil)
+	case errors.Is(err, logic.ErrForbidden):
+		middleware.WriteError(w, r, http.StatusForbidden, "forbidden", err.Error(), nil)
+	case errors.Is(err, logic.ErrUnauthorized):
+		middleware.WriteError(w, r, http.StatusUnauthorized, "unauthorized", err.Error(), nil)
+	default:
+		logging.From(r.Context()).Error("request failed", "err", err)
+		middleware.WriteError(w, r, http.StatusInternalServerError, "internal", "internal server error", nil)
+	}
+}
+
+// writeErrorMsg reports a problem detected by the handler itself, such as a
+// malformed id or body.
+func writeErrorMsg(w http.ResponseWriter, r *http.Request, status int, message string) {
+	middleware.WriteError(w, r, status, errorCode(status), message, nil)
+}
+
+func errorCode(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return "bad_request"
+	case http.StatusUnauthorized:
+		return "unauthorized"
+	case http.StatusForbidden:
+		return "forbidden"
+	case http.StatusNotFound:
+		return "not_found"
+	case http.StatusMethodNotAllowed:
+		return "method_not_allowed"
+	case http.StatusConflict:
+		return "conflict"
+	case http.StatusRequestEntityTooLarge:
+		return "payload_too_large"
+	default:
+		return "internal"
+	}
+}
@@ -0,0 +1,279 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"bookstore/internal/logic"
+	"bookstore/internal/models"
+)
+
+// ---------- ACCOUNT ----------
+func (h *FrontendHandler) accountData(r *http.Request, tab string) map[string]any {
+	data := h.baseData(r, "account")
+	data["Tab"] = tab
+	if msg := r.URL.Query().Get("saved"); msg != "" {
+		data["Success"] = msg
+	}
+	return data
+}
+
+func (h *FrontendHandler) AccountPage(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.requireAuth(w, r)
+	if !ok {
+		return
+	}
+	h.renderAccount(w, r, userID, nil)
+}
+
+func (h *FrontendHandler) renderAccount(w http.ResponseWriter, r *http.Request, userID int, formErr error) {
+	u, err := h.account.Profile(r.Context(), userID)
+	if err != nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	data := h.accountData(r, "profile")
+	data["Title"] = "My Account"
+	data["User"] = u
+	data["Orders"] = h.account.OrderHistory(r.Context(), userID)
+	if formErr != nil {
+		formError(data, formErr)
+	}
+	h.render(w, "account", data)
+}
+
+func (h *FrontendHandler) AccountProfilePost(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.requireAuth(w, r)
+	if !ok {
+		return
+	}
+
+	if !parseForm(w, r) {
+		return
+	}
+	if _, err := h.account.UpdateProfile(r.Context(), userID, r.FormValue("name"), r.FormValue("phone")); err != nil {
+		h.renderAccount(w, r, userID, err)
+		return
+	}
+	http.Redirect(w, r, "/account?saved=Profile+saved", http.StatusSeeOther)
+}
+
+func (h *FrontendHandler) AccountSecurityPage(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.requireAuth(w, r)
+	if !ok {
+		return
+	}
+	h.renderSecurity(w, r, userID, "", nil)
+}
+
+// renderSecurity shows the security tab. form names the submitted form
+// ("password" or "email") so errors land under the right inputs.
+func (h *FrontendHandler) renderSecurity(w http.ResponseWriter, r *http.Request, userID int, form string, formErr error) {
+	u, err := h.account.Profile(r.Context(), userID)
+	if err != nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	data := h.accountData(r, "security")
+	data["Title"] = "Security"
+	data["User"] = u
+	data["Sessions"] = h.account.Sessions(r.Context(), userID)
+	data["CurrentSession"] = h.currentSessionID(r)
+	data["Form"] = form
+	if formErr != nil {
+		formError(data, formErr)
+	}
+	h.render(w, "account_security", data)
+}
+
+func (h *FrontendHandler) AccountPasswordPost(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.requireAuth(w, r)
+	if !ok {
+		return
+	}
+
+	if !parseForm(w, r) {
+		return
+	}
+	current := r.FormValue("current_password")
+	next := r.FormValue("new_password")
+	if next != r.FormValue("confirm_password") {
+		h.renderSecurity(w, r, userID, "password", &logic.ValidationError{Fields: []logic.FieldError{
+			{Field: "confirmPassword", Message: "passwords do not match"},
+		}})
+		return
+	}
+
+	if err := h.account.ChangePassword(r.Context(), userID, current, next, h.currentSessionID(r)); err != nil {
+		h.renderSecurity(w, r, userID, "password", err)
+		return
+	}
+	http.Redirect(w, r, "/account/security?saved=Password+changed", http.StatusSeeOther)
+}
+
+func (h *FrontendHandler) AccountEmailPost(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.requireAuth(w, r)
+	if !ok {
+		return
+	}
+
+	if !parseForm(w, r) {
+		return
+	}
+	if err := h.account.ChangeEmail(r.Context(), userID, r.FormValue("current_password"), r.FormValue("email")); err != nil {
+		h.renderSecurity(w, r, userID, "email", err)
+		return
+	}
+	http.Redirect(w, r, "/account/security?saved=Email+changed", http.StatusSeeOther)
+}
+
+func (h *FrontendHandler) AccountSessionRevoke(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.requireAuth(w, r)
+	if !ok {
+		return
+	}
+
+	id, _ := strconv.Atoi(r.PathValue("id"))
+	if id > 0 {
+		_ = h.account.RevokeSession(r.Context(), userID, id)
+	}
+	http.Redirect(w, r, "/account/security", http.StatusSeeOther)
+}
+
+func (h *FrontendHandler) AccountAddressesPage(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.requireAuth(w, r)
+	if !ok {
+		return
+	}
+	h.renderAddresses(w, r, userID, models.Address{}, nil)
+}
+
+// renderAddresses shows the address book; draft refills the add form after a
+// failed submit.
+func (h *FrontendHandler) renderAddresses(w http.ResponseWriter, r *http.Request, userID int, draft models.Address, formErr error) {
+	list, err := h.account.Addresses(r.Context(), userID)
+	if err != nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	data := h.accountData(r, "addresses")
+	data["Title"] = "Addresses"
+	data["Addresses"] = list
+	data["Draft"] = draft
+	if formErr != nil {
+		formError(data, formErr)
+	}
+	h.render(w, "account_addresses", data)
+}
+
+func (h *FrontendHandler) AccountAddressAdd(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.requireAuth(w, r)
+	if !ok {
+		return
+	}
+
+	if !parseForm(w, r) {
+		return
+	}
+	a := models.Address{
+		Label:      strings.TrimSpace(r.FormValue("label")),
+		Recipient:  strings.TrimSpace(r.FormValue("recipient")),
+		Line1:      strings.TrimSpace(r.FormValue("line1")),
+		Line2:      strings.TrimSpace(r.FormValue("line2")),
+		City:       strings.TrimSpace(r.FormValue("city")),
+		PostalCode: strings.TrimSpace(r.FormValue("postal_code")),
+		Country:    strings.TrimSpace(r.FormValue("country")),
+		Default:    r.FormValue("default") == "on",
+	}
+
+	if _, err := h.account.AddAddress(r.Context(), userID, a); err != nil {
+		h.renderAddresses(w, r, userID, a, err)
+		return
+	}
+	http.Redirect(w, r, "/account/addresses?saved=Address+added", http.StatusSeeOther)
+}
+
+func (h *FrontendHandler) AccountAddressDelete(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.requireAuth(w, r)
+	if !ok {
+		return
+	}
+
+	id, _ := strconv.Atoi(r.PathValue("id"))
+	if id > 0 {
+		_ = h.account.DeleteAddress(r.Context(), userID, id)
+	}
+	http.Redirect(w, r, "/account/addresses", http.StatusSeeOther)
+}
+
+func (h *FrontendHandler) AccountAddressDefault(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.requireAuth(w, r)
+	if !ok {
+		return
+	}
+
+	id, _ := strconv.Atoi(r.PathValue("id"))
+	if id > 0 {
+		_ = h.account.SetDefaultAddress(r.Context(), userID, id)
+	}
+	http.Redirect(w, r, "/account/addresses", http.StatusSeeOther)
+}
+
+func (h *FrontendHandler) AccountPrivacyPage(w http.ResponseWriter, r *http.Request) {
+	if _, ok := h.requireAuth(w, r); !ok {
+		return
+	}
+
+	data := h.accountData(r, "privacy")
+	data["Title"] = "Privacy"
+	h.render(w, "account_privacy", data)
+}
+
+func (h *FrontendHandler) AccountExport(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.requireAuth(w, r)
+	if !ok {
+		return
+	}
+
+	export, err := h.privacy.Export(r.Context(), userID)
+	if err != nil {
+		http.Redirect(w, r, "/account/privacy", http.StatusSeeOther)
+		return
+	}
+
+	sendExport(w, r, h.privacy, userID, export)
+}
+
+func (h *FrontendHandler) AccountDeletePost(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.requireAuth(w, r)
+	if !ok {
+		return
+	}
+
+	if !parseForm(w, r) {
+		return
+	}
+
+	var err error
+	if r.FormValue("confirm") != "DELETE" {
+		err = &logic.ValidationError{Fields: []logic.FieldError{
+			{Field: "confirm", Message: "type DELETE to confirm"},
+		}}
+	} else {
+		err = h.privacy.DeleteAccountWithPassword(r.Context(), userID, r.FormValue("password"))
+	}
+	if err != nil {
+		data := h.accountData(r, "privacy")
+		data["Title"] = "Privacy"
+		formError(data, err)
+		h.render(w, "account_privacy", data)
+		return
+	}
+
+	h.clearTokenCookie(w)
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
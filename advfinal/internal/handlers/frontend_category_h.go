@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"bookstore/internal/logic"
+	"bookstore/internal/models"
+)
+
+// Categories shows the whole category tree.
+func (h *FrontendHandler) Categories(w http.ResponseWriter, r *http.Request) {
+	tree, err := h.categories.Tree(r.Context())
+	if err != nil {
+		http.Error(w, "could not load the categories", http.StatusInternalServerError)
+		return
+	}
+	data := h.baseData(r, "catalog")
+	data["Title"] = "Categories"
+	data["Kinds"] = browseLinks("categories")
+	data["Tree"] = tree
+	h.render(w, "categories", data)
+}
+
+// Category serves the page of a category with its subcategories and the
+// books of its whole branch, under a breadcrumb from the top of the tree.
+// Replaced slugs redirect to the current one.
+func (h *FrontendHandler) Category(w http.ResponseWriter, r *http.Request) {
+	c, err := h.categories.GetBySlug(r.Context(), r.PathValue("slug"))
+	if errors.Is(err, logic.ErrNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, "could not load the category", http.StatusInternalServerError)
+		return
+	}
+	if c.Slug != r.PathValue("slug") {
+		http.Redirect(w, r, c.Path(), http.StatusMovedPermanently)
+		return
+	}
+	trail, err := h.categories.Breadcrumb(r.Context(), c)
+	if err != nil {
+		http.Error(w, "could not load the category", http.StatusInternalServerError)
+		return
+	}
+	children, err := h.categories.Children(r.Context(), c.ID)
+	if err != nil {
+		http.Error(w, "could not load the category", http.StatusInternalServerError)
+		return
+	}
+	books, err := h.categories.Books(r.Context(), c.ID)
+	if err != nil {
+		http.Error(w, "could not load the books", http.StatusInternalServerError)
+		return
+	}
+
+	data := h.baseData(r, "catalog")
+	data["Title"] = c.Name
+	data["Category"] = c
+	data["Trail"] = trail[:len(trail)-1]
+	data["Children"] = children
+	data["Books"] = books
+	h.render(w, "category", data)
+}
+
+// Tags lists the tags in use, most used first.
+func (h *FrontendHandler) Tags(w http.ResponseWriter, r *http.Request) {
+	tags, err := h.books.Tags(r.Context())
+	if err != nil {
+		http.Error(w, "could not load the tags", http.StatusInternalServerError)
+		return
+	}
+	data := h.baseData(r, "catalog")
+	data["Title"] = "Tags"
+	data["Kinds"] = browseLinks("tags")
+	data["Tags"] = tags
+	h.render(w, "tags", data)
+}
+
+// Tag lists the books with a tag.
+func (h *FrontendHandler) Tag(w http.ResponseWriter, r *http.Request) {
+	books, err := h.books.TaggedBooks(r.Context(), r.PathValue("tag"))
+	if err != nil {
+		http.Error(w, "could not load the books", http.StatusInternalServerError)
+		return
+	}
+	if len(books) == 0 {
+		http.NotFound(w, r)
+		return
+	}
+	data := h.baseData(r, "catalog")
+	data["Title"] = r.PathValue("tag")
+	data["Books"] = books
+	h.render(w, "tag", data)
+}
+
+// categoryTrails are the breadcrumbs of the categories a book is in. A
+// category that cannot be loaded is left out rather than failing the page.
+func (h *FrontendHandler) categoryTrails(ctx context.Context, ids []int) [][]models.Category {
+	var trails [][]models.Category
+	for _, id := range ids {
+		c, err := h.categories.Get(ctx, id)
+		if err != nil {
+			continue
+		}
+		trail, err := h.categories.Breadcrumb(ctx, c)
+		if err != nil {
+			continue
+		}
+		trails = append(trails, trail)
+	}
+	return trails
+}
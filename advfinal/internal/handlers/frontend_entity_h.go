@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"bookstore/internal/logic"
+	"bookstore/internal/models"
+)
+
+// entityTitles head the browse pages of each kind.
+var entityTitles = map[string]string{
+	models.EntityAuthor:    "Authors",
+	models.EntityPublisher: "Publishers",
+	models.EntitySeries:    "Series",
+	models.EntityGenre:     "Genres",
+}
+
+// Entities lists every author, publisher, series or genre, A to Z.
+func (h *FrontendHandler) Entities(kind string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		list, err := h.entities.List(r.Context(), kind)
+		if err != nil {
+			http.Error(w, "could not load the "+kind, http.StatusInternalServerError)
+			return
+		}
+		data := h.baseData(r, "catalog")
+		data["Title"] = entityTitles[kind]
+		data["Kind"] = kind
+		data["Kinds"] = browseLinks(kind)
+		data["Entities"] = list
+		h.render(w, "entities", data)
+	}
+}
+
+// Entity serves the page of an author, publisher, series or genre with its
+// books. Replaced slugs redirect to the current one.
+func (h *FrontendHandler) Entity(kind string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		e, err := h.entities.GetBySlug(r.Context(), kind, r.PathValue("slug"))
+		if errors.Is(err, logic.ErrNotFound) {
+			http.NotFound(w, r)
+			return
+		}
+		if err != nil {
+			http.Error(w, "could not load the "+models.EntityNoun(kind), http.StatusInternalServerError)
+			return
+		}
+		if e.Slug != r.PathValue("slug") {
+			http.Redirect(w, r, e.Path(kind), http.StatusMovedPermanently)
+			return
+		}
+		books, err := h.entities.Books(r.Context(), kind, e.ID)
+		if err != nil {
+			http.Error(w, "could not load the books", http.StatusInternalServerError)
+			return
+		}
+
+		data := h.baseData(r, "catalog")
+		data["Title"] = e.Name
+		data["Kind"] = kind
+		data["KindTitle"] = entityTitles[kind]
+		data["Entity"] = e
+		data["Books"] = books
+		h.render(w, "entity", data)
+	}
+}
+
+type browseLink struct {
+	Title  string
+	URL    string
+	Active bool
+}
+
+// browseLinks are the tabs between the browse pages, active naming the
+// path of the current one without its slash.
+func browseLinks(active string) []browseLink {
+	links := make([]browseLink, 0, len(models.EntityKinds)+2)
+	for _, kind := range models.EntityKinds {
+		links = append(links, browseLink{Title: entityTitles[kind], URL: "/" + kind, Active: kind == active})
+	}
+	return append(links,
+		browseLink{Title: "Categories", URL: "/categories", Active: active == "categories"},
+		browseLink{Title: "Tags", URL: "/tags", Active: active == "tags"},
+	)
+}
+
+// entityLink is a name a book refers to, with the page of its entity when
+// the book refers to it by id.
+type entityLink struct {
+	Name string
+	Path string
+}
+
+// bookRefs links the authors, publisher, series, genre and tags of a book
+// page; the ones the book lacks are nil.
+type bookRefs struct {
+	Authors   []entityLink
+	Publisher *entityLink
+	Series    *entityLink
+	Genre     *entityLink
+	Tags      []entityLink
+}
+
+func refsOf(b models.Book) bookRefs {
+	var refs bookRefs
+	linked := len(b.AuthorIDs) == len(b.Authors)
+	for _, a := range b.Authors {
+		l := entityLink{Name: a}
+		if linked {
+			l.Path = models.EntityPath(models.EntityAuthor, a)
+		}
+		refs.Authors = append(refs.Authors, l)
+	}
+	link := func(kind, name string, id int) *entityLink {
+		if name == "" {
+			return nil
+		}
+		l := &entityLink{Name: name}
+		if id != 0 {
+			l.Path = models.EntityPath(kind, name)
+		}
+		return l
+	}
+	refs.Publisher = link(models.EntityPublisher, b.Publisher, b.PublisherID)
+	refs.Series = link(models.EntitySeries, b.Series, b.SeriesID)
+	refs.Genre = link(models.EntityGenre, b.Genre, b.GenreID)
+	for _, t := range b.Tags {
+		refs.Tags = append(refs.Tags, entityLink{Name: t, Path: models.TagPath(t)})
+	}
+	return refs
+}
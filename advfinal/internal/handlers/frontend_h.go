@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"html/template"
@@ -8,8 +9,10 @@ import (
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"bookstore/internal/logic"
+	"bookstore/internal/middleware"
 	"bookstore/internal/models"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -18,14 +21,23 @@ import (
 type FrontendHandler struct {
 	tpls map[string]*template.Template
 
-	books     *logic.BookService
-	auth      *logic.AuthService
-	cart      *logic.CartCRUDService
-	orderSvc  *logic.OrderService
-	orderCRUD *logic.OrderCRUDService
-	wishlist  *logic.WishlistService
-
-	secret []byte
+	books      *logic.BookService
+	auth       *logic.AuthService
+	cart       *logic.CartCRUDService
+	orderSvc   *logic.OrderService
+	orderCRUD  *logic.OrderCRUDService
+	wishlist   *logic.WishlistService
+	account    *logic.AccountService
+	privacy    *logic.PrivacyService
+	reviews    *logic.ReviewService
+	recs       *logic.RecommendationService
+	search     *logic.SearchService
+	entities   *logic.EntityService
+	categories *logic.CategoryService
+
+	secret    []byte
+	tokenTTL  time.Duration // how long the token cookie lasts, as the token
+	publicURL string        // without a trailing slash; "" uses the request's host
 }
 
 func parsePage(base string, page string) (*template.Template, error) {
@@ -43,7 +55,16 @@ func NewFrontendHandler(
 	orderSvc *logic.OrderService,
 	orderCRUD *logic.OrderCRUDService,
 	wishlist *logic.WishlistService,
+	account *logic.AccountService,
+	privacy *logic.PrivacyService,
+	reviews *logic.ReviewService,
+	recs *logic.RecommendationService,
+	search *logic.SearchService,
+	entities *logic.EntityService,
+	categories *logic.CategoryService,
 	secret string,
+	tokenTTL time.Duration,
+	publicURL string,
 ) (*FrontendHandler, error) {
 	if secret == "" {
 		return nil, errors.New("JWT secret empty")
@@ -54,6 +75,7 @@ func NewFrontendHandler(
 	pages := map[string]string{
 		"home":          "home.html",
 		"catalog":       "catalog.html",
+		"book":          "book.html",
 		"about":         "about.html",
 		"login":         "login.html",
 		"register":      "register.html",
@@ -61,6 +83,17 @@ func NewFrontendHandler(
 		"orders":        "orders.html",
 		"order_details": "order_details.html",
 		"wishlists":     "wishlists.html",
+		"entities":      "entities.html",
+		"entity":        "entity.html",
+		"categories":    "categories.html",
+		"category":      "category.html",
+		"tags":          "tags.html",
+		"tag":           "tag.html",
+
+		"account":           "account.html",
+		"account_security":  "account_security.html",
+		"account_addresses": "account_addresses.html",
+		"account_privacy":   "account_privacy.html",
 	}
 
 	tpls := make(map[string]*template.Template, len(pages))
@@ -73,14 +106,23 @@ func NewFrontendHandler(
 	}
 
 	return &FrontendHandler{
-		tpls:      tpls,
-		books:     books,
-		auth:      auth,
-		cart:      cart,
-		orderSvc:  orderSvc,
-		orderCRUD: orderCRUD,
-		wishlist:  wishlist,
-		secret:    []byte(secret),
+		tpls:       tpls,
+		books:      books,
+		auth:       auth,
+		cart:       cart,
+		orderSvc:   orderSvc,
+		orderCRUD:  orderCRUD,
+		wishlist:   wishlist,
+		account:    account,
+		privacy:    privacy,
+		reviews:    reviews,
+		recs:       recs,
+		search:     search,
+		entities:   entities,
+		categories: categories,
+		secret:     []byte(secret),
+		tokenTTL:   tokenTTL,
+		publicURL:  strings.TrimSuffix(publicURL, "/"),
 	}, nil
 }
 
@@ -120,7 +162,7 @@ func (h *FrontendHandler) setTokenCookie(w http.ResponseWriter, token string) {
 		Path:     "/",
 		HttpOnly: true,
 		SameSite: http.SameSiteLaxMode,
-		Expires:  time.Now().Add(24 * time.Hour),
+		Expires:  time.Now().Add(h.tokenTTL),
 	})
 }
 
@@ -134,10 +176,42 @@ func (h *FrontendHandler) clearTokenCookie(w http.ResponseWriter) {
 	})
 }
 
-func (h *FrontendHandler) currentUser(r *http.Request) (userID int, role string, ok bool) {
+type pageSessionKey struct{}
+
+// pageSession caches the login cookie check for the rest of the request, as
+// a page asks for the current user several times and each check reads the
+// session from the database.
+type pageSession struct {
+	resolved bool
+	claims   jwt.MapClaims
+	ok       bool
+}
+
+// Session makes the pages below it resolve the login cookie at most once per
+// request.
+func (h *FrontendHandler) Session(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), pageSessionKey{}, &pageSession{})
+		next(w, r.WithContext(ctx))
+	}
+}
+
+func (h *FrontendHandler) tokenClaims(r *http.Request) (jwt.MapClaims, bool) {
+	ps, cached := r.Context().Value(pageSessionKey{}).(*pageSession)
+	if !cached {
+		return h.parseToken(r)
+	}
+	if !ps.resolved {
+		ps.claims, ps.ok = h.parseToken(r)
+		ps.resolved = true
+	}
+	return ps.claims, ps.ok
+}
+
+func (h *FrontendHandler) parseToken(r *http.Request) (jwt.MapClaims, bool) {
 	c, err := r.Cookie("token")
 	if err != nil || c.Value == "" {
-		return 0, "", false
+		return nil, false
 	}
 
 	tok, err := jwt.Parse(c.Value, func(t *jwt.Token) (any, error) {
@@ -147,10 +221,24 @@ func (h *FrontendHandler) currentUser(r *http.Request) (userID int, role string,
 		return h.secret, nil
 	})
 	if err != nil || tok == nil || !tok.Valid {
-		return 0, "", false
+		return nil, false
 	}
 
 	claims, ok := tok.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, false
+	}
+
+	uid, _ := claims["userId"].(float64)
+	sid, _ := claims["sid"].(float64)
+	if !h.auth.SessionActive(r.Context(), int(uid), int(sid)) {
+		return nil, false
+	}
+	return claims, true
+}
+
+func (h *FrontendHandler) currentUser(r *http.Request) (userID int, role string, ok bool) {
+	claims, ok := h.tokenClaims(r)
 	if !ok {
 		return 0, "", false
 	}
@@ -161,9 +249,19 @@ func (h *FrontendHandler) currentUser(r *http.Request) (userID int, role string,
 	}
 
 	roleStr, _ := claims["role"].(string)
+	middleware.NoteUser(r, int(idf))
 	return int(idf), roleStr, true
 }
 
+func (h *FrontendHandler) currentSessionID(r *http.Request) int {
+	claims, ok := h.tokenClaims(r)
+	if !ok {
+		return 0
+	}
+	sid, _ := claims["sid"].(float64)
+	return int(sid)
+}
+
 func (h *FrontendHandler) baseData(r *http.Request, active string) map[string]any {
 	_, role, ok := h.currentUser(r)
 	return map[string]any{
@@ -171,9 +269,27 @@ func (h *FrontendHandler) baseData(r *http.Request, active string) map[string]an
 		"IsAuth":   ok,
 		"Role":     role,
 		"Active":   active,
+		"Fields":   map[string]string{},
 	}
 }
 
+// formError puts a service error on the page: validation problems go next to
+// their inputs via .Fields, anything else into the .Error banner.
+func formError(data map[string]any, err error) {
+	fields := logic.FieldErrors(err)
+	if len(fields) == 0 {
+		data["Error"] = err.Error()
+		return
+	}
+
+	byName := map[string]string{}
+	for _, f := range fields {
+		byName[f.Field] = f.Message
+	}
+	data["Fields"] = byName
+	data["Error"] = "Please correct the highlighted fields."
+}
+
 func (h *FrontendHandler) requireAuth(w http.ResponseWriter, r *http.Request) (int, bool) {
 	userID, _, ok := h.currentUser(r)
 	if !ok {
@@ -183,8 +299,8 @@ func (h *FrontendHandler) requireAuth(w http.ResponseWriter, r *http.Request) (i
 	return userID, true
 }
 
-func (h *FrontendHandler) ensureUserCart(userID int) (models.Cart, []models.CartItem) {
-	all := h.cart.ListCarts()
+func (h *FrontendHandler) ensureUserCart(ctx context.Context, userID int) (models.Cart, []models.CartItem) {
+	all := h.cart.ListCarts(ctx)
 	var found models.Cart
 	for _, c := range all {
 		if c.CustomerID == userID {
@@ -193,15 +309,24 @@ func (h *FrontendHandler) ensureUserCart(userID int) (models.Cart, []models.Cart
 		}
 	}
 	if found.ID == 0 {
-		found = h.cart.CreateCart(userID)
+		found = h.cart.CreateCart(ctx, userID)
 	}
-	c, items, err := h.cart.GetCart(found.ID)
+	c, items, err := h.cart.GetCart(ctx, found.ID)
 	if err != nil {
 		return found, []models.CartItem{}
 	}
 	return c, items
 }
 
+func (h *FrontendHandler) ensureUserWishlist(ctx context.Context, userID int) models.Wishlist {
+	actor := logic.Actor{UserID: userID}
+	if own := h.wishlist.ListWishlists(ctx, actor); len(own) > 0 {
+		return own[0]
+	}
+	wl, _ := h.wishlist.CreateWishlist(ctx, actor, userID)
+	return wl
+}
+
 // ---------- PAGES ----------
 func (h *FrontendHandler) Home(w http.ResponseWriter, r *http.Request) {
 	data := h.baseData(r, "home")
@@ -212,10 +337,171 @@ func (h *FrontendHandler) Home(w http.ResponseWriter, r *http.Request) {
 func (h *FrontendHandler) Catalog(w http.ResponseWriter, r *http.Request) {
 	data := h.baseData(r, "catalog")
 	data["Title"] = "Catalog"
-	data["Books"] = h.books.ListBooks()
+	if q := r.URL.Query(); q.Has("q") || q.Get("genre") != "" || q.Get("author") != "" || q.Get("price") != "" {
+		h.catalogSearch(w, r, data)
+		return
+	}
+	sort := r.URL.Query().Get("sort")
+	books, err := h.books.ListBooksSorted(r.Context(), sort)
+	if err != nil {
+		sort = ""
+		books = h.books.ListBooks(r.Context())
+	}
+	data["Sort"] = sort
+	data["Books"] = books
+	data["Categories"], _ = h.categories.Children(r.Context(), 0)
 	h.render(w, "catalog", data)
 }
 
+// Book serves the page of a book by slug. Replaced slugs redirect to the
+// current one.
+func (h *FrontendHandler) Book(w http.ResponseWriter, r *http.Request) {
+	b, err := h.books.GetBookBySlug(r.Context(), r.PathValue("slug"))
+	if err != nil {
+		h.bookNotFound(w, r, err)
+		return
+	}
+	if b.Slug != r.PathValue("slug") {
+		http.Redirect(w, r, b.Path(), http.StatusMovedPermanently)
+		return
+	}
+	h.renderBook(w, r, b, nil)
+}
+
+// BookByID redirects the id-based page links to the slug ones.
+func (h *FrontendHandler) BookByID(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(r.PathValue("id"))
+	b, err := h.books.GetBook(r.Context(), id)
+	if err != nil {
+		h.bookNotFound(w, r, err)
+		return
+	}
+	if b.Slug == "" {
+		h.renderBook(w, r, b, nil) // not yet migrated
+		return
+	}
+	http.Redirect(w, r, b.Path(), http.StatusMovedPermanently)
+}
+
+func (h *FrontendHandler) bookNotFound(w http.ResponseWriter, r *http.Request, err error) {
+	if !errors.Is(err, logic.ErrNotFound) {
+		http.Error(w, "could not load the book", http.StatusInternalServerError)
+		return
+	}
+	http.NotFound(w, r)
+}
+
+// renderBook shows a book with its reviews. formErr is a failed review
+// submission, shown next to the review form with what was sent.
+func (h *FrontendHandler) renderBook(w http.ResponseWriter, r *http.Request, b models.Book, formErr error) {
+	url := h.absURL(r, b.Path())
+	data := h.baseData(r, "catalog")
+	data["Title"] = b.Title + " by " + b.Author
+	data["Book"] = b
+	data["Refs"] = refsOf(b)
+	data["Trails"] = h.categoryTrails(r.Context(), b.CategoryIDs)
+	data["URL"] = url
+	data["Summary"] = summary(b.Description, 200)
+	image := ""
+	if b.Cover != "" {
+		image = h.absURL(r, b.CoverURL(models.CoverDetail))
+	}
+	data["Image"] = image
+	data["JSONLD"] = bookJSONLD(b, url, image, h.books.Currency())
+	if msg := r.URL.Query().Get("saved"); msg != "" {
+		data["Success"] = msg
+	}
+	h.reviewData(r, data, b, formErr)
+	data["AlsoBought"], _ = h.recs.ForBook(r.Context(), b.ID, 0)
+	h.render(w, "book", data)
+}
+
+// absURL resolves path against the public URL, falling back to the host the
+// request came in on.
+func (h *FrontendHandler) absURL(r *http.Request, path string) string {
+	base := h.publicURL
+	if base == "" {
+		scheme := "http"
+		if r.TLS != nil {
+			scheme = "https"
+		}
+		base = scheme + "://" + r.Host
+	}
+	return base + path
+}
+
+// summary cuts s to at most n runes at a word boundary, for meta tags.
+func summary(s string, n int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	cut := string([]rune(s)[:n-1])
+	if i := strings.LastIndexByte(cut, ' '); i > 0 {
+		cut = cut[:i]
+	}
+	return cut + "…"
+}
+
+// bookJSONLD describes b as a schema.org Book for search engines.
+func bookJSONLD(b models.Book, url, image, currency string) map[string]any {
+	ld := map[string]any{
+		"@context": "https://schema.org",
+		"@type":    "Book",
+		"name":     b.Title,
+		"url":      url,
+	}
+	authors := make([]map[string]string, 0, len(b.Authors))
+	for _, a := range b.Authors {
+		authors = append(authors, map[string]string{"@type": "Person", "name": a})
+	}
+	if len(authors) > 0 {
+		ld["author"] = authors
+	}
+	set := func(key string, v any, ok bool) {
+		if ok {
+			ld[key] = v
+		}
+	}
+	set("image", image, image != "")
+	set("isbn", b.ISBN, b.ISBN != "")
+	set("description", b.Description, b.Description != "")
+	set("genre", b.Genre, b.Genre != "")
+	set("inLanguage", b.Language, b.Language != "")
+	set("datePublished", b.PublicationDate, b.PublicationDate != "")
+	set("numberOfPages", b.Pages, b.Pages > 0)
+	set("publisher", map[string]string{"@type": "Organization", "name": b.Publisher}, b.Publisher != "")
+	set("bookFormat", schemaFormats[b.Format], schemaFormats[b.Format] != "")
+	set("aggregateRating", map[string]any{
+		"@type":       "AggregateRating",
+		"ratingValue": strconv.FormatFloat(b.Rating, 'f', 1, 64),
+		"reviewCount": b.RatingCount,
+	}, b.RatingCount > 0)
+
+	availability := "https://schema.org/InStock"
+	switch b.Availability {
+	case models.BookPreorder:
+		availability = "https://schema.org/PreOrder"
+	case models.BookUnavailable:
+		availability = "https://schema.org/OutOfStock"
+	}
+	ld["offers"] = map[string]any{
+		"@type":         "Offer",
+		"price":         strconv.FormatFloat(b.Price, 'f', 2, 64),
+		"priceCurrency": currency,
+		"availability":  availability,
+		"url":           url,
+	}
+	return ld
+}
+
+var schemaFormats = map[string]string{
+	models.FormatHardcover: "https://schema.org/Hardcover",
+	models.FormatPaperback: "https://schema.org/Paperback",
+	models.FormatEbook:     "https://schema.org/EBook",
+	models.FormatAudiobook: "https://schema.org/AudiobookFormat",
+}
+
 func (h *FrontendHandler) About(w http.ResponseWriter, r *http.Request) {
 	data := h.baseData(r, "about")
 	data["Title"] = "About"
@@ -230,15 +516,22 @@ func (h *FrontendHandler) Login(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *FrontendHandler) LoginPost(w http.ResponseWriter, r *http.Request) {
-	_ = r.ParseForm()
+	if !parseForm(w, r) {
+		return
+	}
 	email := strings.TrimSpace(r.FormValue("email"))
 	pass := r.FormValue("password")
 
-	token, err := h.auth.Login(email, pass)
+	token, err := h.auth.Login(r.Context(), email, pass, r.UserAgent(), middleware.ClientIP(r))
 	if err != nil {
 		data := h.baseData(r, "login")
 		data["Title"] = "Login"
-		data["Error"] = "Invalid email or password"
+		data["Email"] = email
+		if errors.Is(err, logic.ErrValidation) {
+			formError(data, err)
+		} else {
+			data["Error"] = "Invalid email or password"
+		}
 		h.render(w, "login", data)
 		return
 	}
@@ -254,19 +547,22 @@ func (h *FrontendHandler) Register(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *FrontendHandler) RegisterPost(w http.ResponseWriter, r *http.Request) {
-	_ = r.ParseForm()
+	if !parseForm(w, r) {
+		return
+	}
 	email := strings.TrimSpace(r.FormValue("email"))
 	pass := r.FormValue("password")
 
-	if err := h.auth.Register(email, pass); err != nil {
+	if err := h.auth.Register(r.Context(), email, pass); err != nil {
 		data := h.baseData(r, "register")
 		data["Title"] = "Register"
-		data["Error"] = err.Error()
+		data["Email"] = email
+		formError(data, err)
 		h.render(w, "register", data)
 		return
 	}
 
-	token, err := h.auth.Login(email, pass)
+	token, err := h.auth.Login(r.Context(), email, pass, r.UserAgent(), middleware.ClientIP(r))
 	if err == nil {
 		h.setTokenCookie(w, token)
 	}
@@ -274,6 +570,7 @@ func (h *FrontendHandler) RegisterPost(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *FrontendHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	_ = h.auth.Logout(r.Context(), h.currentSessionID(r))
 	h.clearTokenCookie(w)
 	http.Redirect(w, r, "/", http.StatusSeeOther)
 }
@@ -285,26 +582,38 @@ func (h *FrontendHandler) CartPage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	c, items := h.ensureUserCart(userID)
-	books := h.books.ListBooks()
+	c, items := h.ensureUserCart(r.Context(), userID)
+	books := h.books.ListBooks(r.Context())
 	bookMap := map[int]models.Book{}
 	for _, b := range books {
 		bookMap[b.ID] = b
 	}
 
 	type row struct {
-		Item models.CartItem
-		Book models.Book
-		Line float64
+		Item    models.CartItem
+		Book    models.Book
+		HasBook bool
+		Line    float64
 	}
 
 	rows := make([]row, 0, len(items))
+	inCart := make([]int, 0, len(items))
 	var total float64
 	for _, it := range items {
-		b := bookMap[it.BookID]
+		b, ok := bookMap[it.BookID]
 		ln := b.Price * float64(it.Qty)
 		total += ln
-		rows = append(rows, row{Item: it, Book: b, Line: ln})
+		rows = append(rows, row{Item: it, Book: b, HasBook: ok, Line: ln})
+		inCart = append(inCart, it.BookID)
+	}
+
+	// Suggest what goes with the cart, or with the customer's history
+	// while it is empty.
+	var suggested []models.Book
+	if len(inCart) > 0 {
+		suggested, _ = h.recs.ForBooks(r.Context(), inCart, 0)
+	} else {
+		suggested, _ = h.recs.ForUser(r.Context(), logic.Actor{UserID: userID}, 0)
 	}
 
 	data := h.baseData(r, "cart")
@@ -312,6 +621,7 @@ func (h *FrontendHandler) CartPage(w http.ResponseWriter, r *http.Request) {
 	data["Cart"] = c
 	data["Rows"] = rows
 	data["Total"] = total
+	data["Suggested"] = suggested
 	h.render(w, "cart", data)
 }
 
@@ -327,8 +637,8 @@ func (h *FrontendHandler) CartAdd(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	c, _ := h.ensureUserCart(userID)
-	_, _ = h.cart.AddItem(c.ID, bookID, 1)
+	c, _ := h.ensureUserCart(r.Context(), userID)
+	_, _ = h.cart.AddItem(r.Context(), c.ID, bookID, 1)
 	http.Redirect(w, r, "/cart", http.StatusSeeOther)
 }
 
@@ -344,15 +654,17 @@ func (h *FrontendHandler) CartUpdateQty(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	_ = r.ParseForm()
+	if !parseForm(w, r) {
+		return
+	}
 	qty, _ := strconv.Atoi(r.FormValue("qty"))
 	if qty <= 0 {
 		http.Redirect(w, r, "/cart", http.StatusSeeOther)
 		return
 	}
 
-	c, _ := h.ensureUserCart(userID)
-	_ = h.cart.UpdateItem(c.ID, itemID, qty)
+	c, _ := h.ensureUserCart(r.Context(), userID)
+	_ = h.cart.UpdateItem(r.Context(), c.ID, itemID, qty)
 	http.Redirect(w, r, "/cart", http.StatusSeeOther)
 }
 
@@ -368,8 +680,8 @@ func (h *FrontendHandler) CartDeleteItem(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	c, _ := h.ensureUserCart(userID)
-	_ = h.cart.DeleteItem(c.ID, itemID)
+	c, _ := h.ensureUserCart(r.Context(), userID)
+	_ = h.cart.DeleteItem(r.Context(), c.ID, itemID)
 	http.Redirect(w, r, "/cart", http.StatusSeeOther)
 }
 
@@ -380,7 +692,7 @@ func (h *FrontendHandler) OrdersPage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	all := h.orderCRUD.ListOrders()
+	all := h.orderCRUD.ListOrders(r.Context())
 	out := make([]models.Order, 0)
 	for _, o := range all {
 		if o.CustomerID == userID {
@@ -406,13 +718,13 @@ func (h *FrontendHandler) OrderDetailsPage(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	o, items, err := h.orderCRUD.GetOrder(id)
+	o, items, err := h.orderCRUD.GetOrder(r.Context(), id)
 	if err != nil || o.CustomerID != userID {
 		http.Redirect(w, r, "/orders", http.StatusSeeOther)
 		return
 	}
 
-	books := h.books.ListBooks()
+	books := h.books.ListBooks(r.Context())
 	bookMap := map[int]models.Book{}
 	for _, b := range books {
 		bookMap[b.ID] = b
@@ -441,13 +753,13 @@ func (h *FrontendHandler) CreateOrderFromCart(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	c, items := h.ensureUserCart(userID)
+	c, items := h.ensureUserCart(r.Context(), userID)
 	if len(items) == 0 {
 		http.Redirect(w, r, "/cart", http.StatusSeeOther)
 		return
 	}
 
-	_, _, _ = h.orderSvc.CreateOrderFromCart(userID, c.ID)
+	_, _, _ = h.orderSvc.CreateOrderFromCart(r.Context(), userID, c.ID)
 	http.Redirect(w, r, "/orders", http.StatusSeeOther)
 }
 
@@ -458,21 +770,11 @@ func (h *FrontendHandler) WishlistsPage(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	all := h.wishlist.ListWishlists()
-	var wl models.Wishlist
-	for _, wli := range all {
-		if wli.CustomerID == userID {
-			wl = wli
-			break
-		}
-	}
-	if wl.ID == 0 {
-		wl = h.wishlist.CreateWishlist(userID)
-	}
+	wl := h.ensureUserWishlist(r.Context(), userID)
 
-	wlObj, items, _ := h.wishlist.GetWishlist(wl.ID)
+	wlObj, items, _ := h.wishlist.GetWishlist(r.Context(), logic.Actor{UserID: userID}, wl.ID)
 
-	books := h.books.ListBooks()
+	books := h.books.ListBooks(r.Context())
 	bookMap := map[int]models.Book{}
 	for _, b := range books {
 		bookMap[b.ID] = b
@@ -508,19 +810,9 @@ func (h *FrontendHandler) WishlistAdd(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	all := h.wishlist.ListWishlists()
-	var wl models.Wishlist
-	for _, wli := range all {
-		if wli.CustomerID == userID {
-			wl = wli
-			break
-		}
-	}
-	if wl.ID == 0 {
-		wl = h.wishlist.CreateWishlist(userID)
-	}
+	wl := h.ensureUserWishlist(r.Context(), userID)
 
-	_, _ = h.wishlist.AddItem(wl.ID, bookID, 1)
+	_, _ = h.wishlist.AddItem(r.Context(), logic.Actor{UserID: userID}, wl.ID, bookID, 1)
 	http.Redirect(w, r, "/wishlists", http.StatusSeeOther)
 }
 
@@ -536,6 +828,6 @@ func (h *FrontendHandler) WishlistGift(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, _, _, _ = h.wishlist.GiftFromWishlist(wishlistID, buyerID)
+	_, _, _, _ = h.wishlist.GiftFromWishlist(r.Context(), logic.Actor{UserID: buyerID}, wishlistID)
 	http.Redirect(w, r, "/orders", http.StatusSeeOther)
 }
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"bookstore/internal/logic"
+	"bookstore/internal/models"
+)
+
+// ---------- REVIEWS ----------
+
+// reviewData adds a book's reviews to its page and, for a signed-in
+// customer, their own review and whether they may write one.
+func (h *FrontendHandler) reviewData(r *http.Request, data map[string]any, b models.Book, formErr error) {
+	reviews, _ := h.reviews.ListForBook(r.Context(), b.ID, "")
+	data["Reviews"] = reviews
+
+	userID, role, ok := h.currentUser(r)
+	if !ok {
+		return
+	}
+	actor := logic.Actor{UserID: userID, Role: role}
+	mine, err := h.reviews.Mine(r.Context(), actor, b.ID)
+	if err == nil {
+		data["MyReview"] = mine
+	}
+	bought, _ := h.reviews.Purchased(r.Context(), actor, b.ID)
+	data["CanReview"] = bought || err == nil
+	data["UserID"] = userID
+
+	draft := logic.ReviewInput{Rating: mine.Rating, Title: mine.Title, Body: mine.Body}
+	if formErr != nil {
+		draft.Rating, _ = strconv.Atoi(r.FormValue("rating"))
+		draft.Title, draft.Body = r.FormValue("title"), r.FormValue("body")
+		formError(data, formErr)
+	}
+	data["Draft"] = draft
+}
+
+// bookForReview loads the book of a review form post, answering the
+// request itself when it cannot.
+func (h *FrontendHandler) bookForReview(w http.ResponseWriter, r *http.Request) (models.Book, int, bool) {
+	userID, ok := h.requireAuth(w, r)
+	if !ok {
+		return models.Book{}, 0, false
+	}
+	id, _ := strconv.Atoi(r.PathValue("id"))
+	b, err := h.books.GetBook(r.Context(), id)
+	if err != nil {
+		h.bookNotFound(w, r, err)
+		return models.Book{}, 0, false
+	}
+	return b, userID, true
+}
+
+// ReviewPost writes the customer's review of a book, creating it the first
+// time and replacing it after.
+func (h *FrontendHandler) ReviewPost(w http.ResponseWriter, r *http.Request) {
+	b, userID, ok := h.bookForReview(w, r)
+	if !ok {
+		return
+	}
+	if !parseForm(w, r) {
+		return
+	}
+
+	actor := logic.Actor{UserID: userID}
+	rating, _ := strconv.Atoi(r.FormValue("rating"))
+	in := logic.ReviewInput{BookID: b.ID, Rating: rating, Title: r.FormValue("title"), Body: r.FormValue("body")}
+
+	var err error
+	if mine, mineErr := h.reviews.Mine(r.Context(), actor, b.ID); mineErr == nil {
+		_, err = h.reviews.Update(r.Context(), actor, mine.ID, in)
+	} else {
+		_, err = h.reviews.Create(r.Context(), actor, in)
+	}
+	if errors.Is(err, logic.ErrForbidden) {
+		err = errors.New("only customers who ordered this book can review it")
+	}
+	if err != nil {
+		h.renderBook(w, r, b, err)
+		return
+	}
+	http.Redirect(w, r, b.Path()+"?saved=Thanks!+Your+review+will+appear+once+approved.#reviews", http.StatusSeeOther)
+}
+
+func (h *FrontendHandler) ReviewDelete(w http.ResponseWriter, r *http.Request) {
+	b, userID, ok := h.bookForReview(w, r)
+	if !ok {
+		return
+	}
+	actor := logic.Actor{UserID: userID}
+	if mine, err := h.reviews.Mine(r.Context(), actor, b.ID); err == nil {
+		_ = h.reviews.Delete(r.Context(), actor, mine.ID)
+	}
+	http.Redirect(w, r, b.Path()+"?saved=Review+deleted#reviews", http.StatusSeeOther)
+}
+
+func (h *FrontendHandler) ReviewHelpful(w http.ResponseWriter, r *http.Request) {
+	b, userID, ok := h.bookForReview(w, r)
+	if !ok {
+		return
+	}
+	reviewID, _ := strconv.Atoi(r.PathValue("reviewId"))
+	_, _ = h.reviews.Vote(r.Context(), logic.Actor{UserID: userID}, reviewID, true)
+	http.Redirect(w, r, b.Path()+"#reviews", http.StatusSeeOther)
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"cmp"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"bookstore/internal/logic"
+	"bookstore/internal/search"
+)
+
+const catalogPageSize = 20
+
+type facetLink struct {
+	Label  string
+	Count  int
+	URL    string
+	Active bool
+}
+
+type facetGroup struct {
+	Title string
+	Links []facetLink
+}
+
+// catalogSearch shows the matches of a search beside its facets. Each facet
+// links to the same search narrowed to it, or widened again when it is
+// the active filter.
+func (h *FrontendHandler) catalogSearch(w http.ResponseWriter, r *http.Request, data map[string]any) {
+	q := r.URL.Query()
+	in, _ := searchInput(q) // a malformed offset starts over
+	if in.Offset < 0 {
+		in.Offset = 0
+	}
+	in.Limit = catalogPageSize
+
+	res, err := h.search.Search(r.Context(), in)
+	if err != nil {
+		if len(logic.FieldErrors(err)) > 0 {
+			data["Error"] = err.Error()
+		} else {
+			data["Error"] = "Search is unavailable right now. Please try again later."
+		}
+	}
+
+	link := func(param, value string) string {
+		v := url.Values{}
+		for _, k := range []string{"q", "genre", "author", "price"} {
+			if s := q.Get(k); s != "" {
+				v.Set(k, s)
+			}
+		}
+		if value == "" {
+			v.Del(param)
+		} else {
+			v.Set(param, value)
+		}
+		return "/catalog?" + v.Encode()
+	}
+	group := func(title, param string, counts []search.FacetCount) facetGroup {
+		g := facetGroup{Title: title}
+		for _, c := range counts {
+			fl := facetLink{Label: cmp.Or(c.Label, c.Value), Count: c.Count, URL: link(param, c.Value)}
+			if q.Get(param) == c.Value {
+				fl.Active, fl.URL = true, link(param, "")
+			}
+			g.Links = append(g.Links, fl)
+		}
+		return g
+	}
+	data["Facets"] = []facetGroup{
+		group("Genre", "genre", res.Facets.Genre),
+		group("Author", "author", res.Facets.Author),
+		group("Price", "price", res.Facets.Price),
+	}
+
+	if in.Offset > 0 {
+		data["PrevURL"] = link("offset", strconv.Itoa(max(in.Offset-catalogPageSize, 0)))
+	}
+	if next := in.Offset + catalogPageSize; next < res.Total {
+		data["NextURL"] = link("offset", strconv.Itoa(next))
+	}
+	data["Search"] = in
+	data["Total"] = res.Total
+	data["Books"] = res.Books
+	h.render(w, "catalog", data)
+}
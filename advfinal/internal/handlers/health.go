@@ -3,14 +3,37 @@ package handlers
 import (
 	"encoding/json"
 	"net/http"
+	"time"
+
+	"bookstore/internal/health"
 )
 
-func Health(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
-		return
+type HealthHandler struct {
+	checker *health.Checker
+}
+
+func NewHealthHandler(checker *health.Checker) *HealthHandler {
+	return &HealthHandler{checker: checker}
+}
+
+// Live reports that the process is up. It checks no dependencies, so a Mongo
+// outage makes the instance unready rather than getting it restarted.
+func (h *HealthHandler) Live(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{
+		"status": "ok",
+		"uptime": h.checker.Uptime().Round(time.Second).String(),
+	})
+}
+
+// Ready answers 503 while a dependency is down or the server is draining.
+func (h *HealthHandler) Ready(w http.ResponseWriter, r *http.Request) {
+	report := h.checker.Check(r.Context())
+	status := http.StatusOK
+	if !report.Ready() {
+		status = http.StatusServiceUnavailable
 	}
-	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, status, report)
 }
 
 func writeJSON(w http.ResponseWriter, status int, data any) {
@@ -1,10 +1,8 @@
 package handlers
 
 import (
-	"encoding/json"
 	"net/http"
 	"strconv"
-	"strings"
 
 	"bookstore/internal/logic"
 	"bookstore/internal/middleware"
@@ -22,14 +20,14 @@ func NewOrderCRUDHandler(crud *logic.OrderCRUDService) *OrderCRUDHandler {
 func (h *OrderCRUDHandler) Orders(w http.ResponseWriter, r *http.Request) {
 	userID, ok := middleware.UserID(r)
 	if !ok {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		writeErrorMsg(w, r, http.StatusUnauthorized, "unauthorized")
 		return
 	}
 	role := middleware.Role(r)
 
 	switch r.Method {
 	case http.MethodGet:
-		all := h.crud.ListOrders()
+		all := h.crud.ListOrders(r.Context())
 
 		if role == "admin" {
 			writeJSON(w, http.StatusOK, all)
@@ -45,33 +43,32 @@ func (h *OrderCRUDHandler) Orders(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusOK, out)
 
 	default:
-		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		writeErrorMsg(w, r, http.StatusMethodNotAllowed, "method not allowed")
 	}
 }
 
 func (h *OrderCRUDHandler) OrderByID(w http.ResponseWriter, r *http.Request) {
 	userID, ok := middleware.UserID(r)
 	if !ok {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		writeErrorMsg(w, r, http.StatusUnauthorized, "unauthorized")
 		return
 	}
 	role := middleware.Role(r)
 
-	idStr := strings.TrimPrefix(r.URL.Path, "/orders/")
-	id, err := strconv.Atoi(idStr)
+	id, err := strconv.Atoi(r.PathValue("id"))
 	if err != nil || id <= 0 {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid id"})
+		writeErrorMsg(w, r, http.StatusBadRequest, "invalid id")
 		return
 	}
 
-	o, items, err := h.crud.GetOrder(id)
+	o, items, err := h.crud.GetOrder(r.Context(), id)
 	if err != nil {
-		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		writeError(w, r, err)
 		return
 	}
 
 	if role != "admin" && o.CustomerID != userID {
-		writeJSON(w, http.StatusForbidden, map[string]string{"error": "forbidden"})
+		writeErrorMsg(w, r, http.StatusForbidden, "forbidden")
 		return
 	}
 
@@ -81,36 +78,35 @@ func (h *OrderCRUDHandler) OrderByID(w http.ResponseWriter, r *http.Request) {
 
 	case http.MethodPut:
 		if role != "admin" {
-			writeJSON(w, http.StatusForbidden, map[string]string{"error": "admin only"})
+			writeErrorMsg(w, r, http.StatusForbidden, "admin only")
 			return
 		}
 
 		var in models.Order
-		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
-			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON"})
+		if !decodeJSON(w, r, &in) {
 			return
 		}
 		in.ID = id
 
-		if err := h.crud.UpdateOrder(in); err != nil {
-			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		if err := h.crud.UpdateOrder(r.Context(), in); err != nil {
+			writeError(w, r, err)
 			return
 		}
 		writeJSON(w, http.StatusOK, map[string]string{"message": "updated"})
 
 	case http.MethodDelete:
 		if role != "admin" {
-			writeJSON(w, http.StatusForbidden, map[string]string{"error": "admin only"})
+			writeErrorMsg(w, r, http.StatusForbidden, "admin only")
 			return
 		}
 
-		if err := h.crud.DeleteOrder(id); err != nil {
-			writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		if err := h.crud.DeleteOrder(r.Context(), id); err != nil {
+			writeError(w, r, err)
 			return
 		}
 		writeJSON(w, http.StatusOK, map[string]string{"message": "deleted"})
 
 	default:
-		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		writeErrorMsg(w, r, http.StatusMethodNotAllowed, "method not allowed")
 	}
 }
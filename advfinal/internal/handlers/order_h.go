@@ -1,7 +1,6 @@
 package handlers
 
 import (
-	"encoding/json"
 	"net/http"
 
 	"bookstore/internal/logic"
@@ -19,29 +18,26 @@ func NewOrderHandler(svc *logic.OrderService) *OrderHandler {
 func (h *OrderHandler) Orders(w http.ResponseWriter, r *http.Request) {
 	userID, ok := middleware.UserID(r)
 	if !ok {
-		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		writeErrorMsg(w, r, http.StatusUnauthorized, "unauthorized")
 		return
 	}
 
 	switch r.Method {
 	case http.MethodPost:
-		var in struct {
-			CartID int `json:"cartId"`
-		}
-		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
-			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON"})
+		var in logic.OrderInput
+		if !decodeJSON(w, r, &in) {
 			return
 		}
 
-		o, items, err := h.svc.CreateOrderFromCart(userID, in.CartID)
+		o, items, err := h.svc.CreateOrderFromCart(r.Context(), userID, in.CartID)
 		if err != nil {
-			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			writeError(w, r, err)
 			return
 		}
 
 		writeJSON(w, http.StatusCreated, map[string]any{"order": o, "items": items})
 
 	default:
-		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		writeErrorMsg(w, r, http.StatusMethodNotAllowed, "method not allowed")
 	}
 }
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"bookstore/internal/logic"
+	"bookstore/internal/middleware"
+)
+
+type PrivacyHandler struct {
+	service *logic.PrivacyService
+}
+
+func NewPrivacyHandler(service *logic.PrivacyService) *PrivacyHandler {
+	return &PrivacyHandler{service: service}
+}
+
+func (h *PrivacyHandler) writeExport(w http.ResponseWriter, r *http.Request, userID int) {
+	export, err := h.service.Export(r.Context(), userID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	sendExport(w, r, h.service, userID, export)
+}
+
+// sendExport sends the export as a JSON download, or as a ZIP when
+// ?format=zip.
+func sendExport(w http.ResponseWriter, r *http.Request, service *logic.PrivacyService, userID int, export logic.DataExport) {
+	name := fmt.Sprintf("bookstore-user-%d-%s", userID, export.GeneratedAt.Format("20060102"))
+
+	if r.URL.Query().Get("format") == "zip" {
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+name+`.zip"`)
+		_ = service.WriteZip(w, export)
+		return
+	}
+
+	w.Header().Set("Content-Disposition", `attachment; filename="`+name+`.json"`)
+	writeJSON(w, http.StatusOK, export)
+}
+
+func (h *PrivacyHandler) ExportMe(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserID(r)
+	if !ok {
+		writeErrorMsg(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	h.writeExport(w, r, userID)
+}
+
+func (h *PrivacyHandler) DeleteMe(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserID(r)
+	if !ok {
+		writeErrorMsg(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var in logic.AccountDeletion
+	if !decodeJSON(w, r, &in) {
+		return
+	}
+
+	if err := h.service.DeleteAccountWithPassword(r.Context(), userID, in.Password); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"message": "account deleted"})
+}
+
+// ---------- admin ----------
+
+func (h *PrivacyHandler) AdminExport(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id <= 0 {
+		writeErrorMsg(w, r, http.StatusBadRequest, "invalid id")
+		return
+	}
+	h.writeExport(w, r, id)
+}
+
+func (h *PrivacyHandler) AdminDelete(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id <= 0 {
+		writeErrorMsg(w, r, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	adminID, _ := middleware.UserID(r)
+	if err := h.service.AdminDeleteAccount(r.Context(), adminID, id); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"message": "account deleted"})
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"bookstore/internal/logic"
+)
+
+type RecommendationHandler struct {
+	service *logic.RecommendationService
+}
+
+func NewRecommendationHandler(service *logic.RecommendationService) *RecommendationHandler {
+	return &RecommendationHandler{service: service}
+}
+
+// limitParam reads the optional "limit" query parameter; 0 means the
+// service's default.
+func limitParam(w http.ResponseWriter, r *http.Request) (int, bool) {
+	raw := r.URL.Query().Get("limit")
+	if raw == "" {
+		return 0, true
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n <= 0 {
+		writeFieldError(w, r, "limit", "limit must be a positive integer")
+		return 0, false
+	}
+	return n, true
+}
+
+// ForBook answers GET /recommendations?bookId=N.
+func (h *RecommendationHandler) ForBook(w http.ResponseWriter, r *http.Request) {
+	bookID, err := strconv.Atoi(r.URL.Query().Get("bookId"))
+	if err != nil || bookID <= 0 {
+		writeFieldError(w, r, "bookId", "bookId must be a positive integer")
+		return
+	}
+	limit, ok := limitParam(w, r)
+	if !ok {
+		return
+	}
+	books, err := h.service.ForBook(r.Context(), bookID, limit)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, books)
+}
+
+func (h *RecommendationHandler) Mine(w http.ResponseWriter, r *http.Request) {
+	actor, ok := actorFrom(r)
+	if !ok {
+		writeErrorMsg(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	limit, ok := limitParam(w, r)
+	if !ok {
+		return
+	}
+	books, err := h.service.ForUser(r.Context(), actor, limit)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, books)
+}
+
+func (h *RecommendationHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	rep, err := h.service.Refresh(r.Context())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, rep)
+}
@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"bookstore/internal/logic"
+)
+
+type ReviewHandler struct {
+	service *logic.ReviewService
+}
+
+func NewReviewHandler(service *logic.ReviewService) *ReviewHandler {
+	return &ReviewHandler{service: service}
+}
+
+// List answers GET /reviews?bookId=N with the book's approved reviews.
+func (h *ReviewHandler) List(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	bookID, err := strconv.Atoi(q.Get("bookId"))
+	if err != nil || bookID <= 0 {
+		writeFieldError(w, r, "bookId", "bookId must be a positive integer")
+		return
+	}
+	reviews, err := h.service.ListForBook(r.Context(), bookID, q.Get("sort"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, reviews)
+}
+
+func (h *ReviewHandler) Create(w http.ResponseWriter, r *http.Request) {
+	actor, ok := actorFrom(r)
+	if !ok {
+		writeErrorMsg(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	var in logic.ReviewInput
+	if !decodeJSON(w, r, &in) {
+		return
+	}
+	rv, err := h.service.Create(r.Context(), actor, in)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, rv)
+}
+
+func (h *ReviewHandler) Mine(w http.ResponseWriter, r *http.Request) {
+	actor, ok := actorFrom(r)
+	if !ok {
+		writeErrorMsg(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	writeJSON(w, http.StatusOK, h.service.ListMine(r.Context(), actor))
+}
+
+func (h *ReviewHandler) Pending(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.service.Pending(r.Context()))
+}
+
+// Get is public, so only approved reviews are found; authors see their
+// others through Mine.
+func (h *ReviewHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id <= 0 {
+		writeErrorMsg(w, r, http.StatusBadRequest, "invalid id")
+		return
+	}
+	actor, _ := actorFrom(r)
+	rv, err := h.service.Get(r.Context(), actor, id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, rv)
+}
+
+func (h *ReviewHandler) Update(w http.ResponseWriter, r *http.Request) {
+	actor, ok := actorFrom(r)
+	if !ok {
+		writeErrorMsg(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id <= 0 {
+		writeErrorMsg(w, r, http.StatusBadRequest, "invalid id")
+		return
+	}
+	var in logic.ReviewInput
+	if !decodeJSON(w, r, &in) {
+		return
+	}
+	rv, err := h.service.Update(r.Context(), actor, id, in)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, rv)
+}
+
+func (h *ReviewHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	actor, ok := actorFrom(r)
+	if !ok {
+		writeErrorMsg(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id <= 0 {
+		writeErrorMsg(w, r, http.StatusBadRequest, "invalid id")
+		return
+	}
+	if err := h.service.Delete(r.Context(), actor, id); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *ReviewHandler) Moderate(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id <= 0 {
+		writeErrorMsg(w, r, http.StatusBadRequest, "invalid id")
+		return
+	}
+	var in logic.ReviewModeration
+	if !decodeJSON(w, r, &in) {
+		return
+	}
+	rv, err := h.service.Moderate(r.Context(), id, in)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, rv)
+}
+
+// Helpful adds the caller's helpful vote on POST and withdraws it on
+// DELETE.
+func (h *ReviewHandler) Helpful(w http.ResponseWriter, r *http.Request) {
+	actor, ok := actorFrom(r)
+	if !ok {
+		writeErrorMsg(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id <= 0 {
+		writeErrorMsg(w, r, http.StatusBadRequest, "invalid id")
+		return
+	}
+	rv, err := h.service.Vote(r.Context(), actor, id, r.Method == http.MethodPost)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, rv)
+}
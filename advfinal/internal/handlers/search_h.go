@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"bookstore/internal/logic"
+)
+
+type SearchHandler struct {
+	service *logic.SearchService
+}
+
+func NewSearchHandler(service *logic.SearchService) *SearchHandler {
+	return &SearchHandler{service: service}
+}
+
+// searchInput reads a search from the query string. It returns the name
+// of a malformed number parameter, if any.
+func searchInput(q url.Values) (logic.SearchInput, string) {
+	in := logic.SearchInput{
+		Q:      q.Get("q"),
+		Genre:  q.Get("genre"),
+		Author: q.Get("author"),
+		Price:  q.Get("price"),
+	}
+	for name, dst := range map[string]*int{"offset": &in.Offset, "limit": &in.Limit} {
+		if raw := q.Get(name); raw != "" {
+			n, err := strconv.Atoi(raw)
+			if err != nil {
+				return in, name
+			}
+			*dst = n
+		}
+	}
+	return in, ""
+}
+
+// Search answers GET /books/search?q=...
+func (h *SearchHandler) Search(w http.ResponseWriter, r *http.Request) {
+	in, bad := searchInput(r.URL.Query())
+	if bad != "" {
+		writeFieldError(w, r, bad, bad+" must be an integer")
+		return
+	}
+	res, err := h.service.Search(r.Context(), in)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// Suggest answers GET /books/suggest?q=..., which the search box calls as
+// the shopper types.
+func (h *SearchHandler) Suggest(w http.ResponseWriter, r *http.Request) {
+	limit, ok := limitParam(w, r)
+	if !ok {
+		return
+	}
+	res, err := h.service.Suggest(logic.SuggestInput{Q: r.URL.Query().Get("q"), Limit: limit})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+func (h *SearchHandler) Rebuild(w http.ResponseWriter, r *http.Request) {
+	rep, err := h.service.Rebuild(r.Context())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, rep)
+}
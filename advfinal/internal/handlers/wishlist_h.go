@@ -1,12 +1,11 @@
 package handlers
 
 import (
-	"encoding/json"
 	"net/http"
 	"strconv"
-	"strings"
 
 	"bookstore/internal/logic"
+	"bookstore/internal/middleware"
 )
 
 type WishlistHandler struct {
@@ -17,101 +16,162 @@ func NewWishlistHandler(service *logic.WishlistService) *WishlistHandler {
 	return &WishlistHandler{service: service}
 }
 
+// actorFrom builds the service-level caller from the auth middleware context.
+func actorFrom(r *http.Request) (logic.Actor, bool) {
+	userID, ok := middleware.UserID(r)
+	if !ok {
+		return logic.Actor{}, false
+	}
+	return logic.Actor{UserID: userID, Role: middleware.Role(r)}, true
+}
+
 func (h *WishlistHandler) Wishlists(w http.ResponseWriter, r *http.Request) {
+	actor, ok := actorFrom(r)
+	if !ok {
+		writeErrorMsg(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
 	switch r.Method {
 	case http.MethodGet:
-		writeJSON(w, http.StatusOK, h.service.ListWishlists())
+		writeJSON(w, http.StatusOK, h.service.ListWishlists(r.Context(), actor))
 	case http.MethodPost:
-		var in struct {
-			CustomerID int `json:"customerId"`
+		var in logic.WishlistInput
+		// The body is optional: without it the wishlist belongs to the caller.
+		if r.ContentLength != 0 && !decodeJSON(w, r, &in) {
+			return
+		}
+
+		wl, err := h.service.CreateWishlist(r.Context(), actor, in.CustomerID)
+		if err != nil {
+			writeError(w, r, err)
+			return
 		}
-		_ = json.NewDecoder(r.Body).Decode(&in)
-		wl := h.service.CreateWishlist(in.CustomerID)
 		writeJSON(w, http.StatusCreated, wl)
 	default:
-		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		writeErrorMsg(w, r, http.StatusMethodNotAllowed, "method not allowed")
 	}
 }
 
 func (h *WishlistHandler) WishlistByID(w http.ResponseWriter, r *http.Request) {
-	idStr := strings.TrimPrefix(r.URL.Path, "/wishlists/")
-	id, err := strconv.Atoi(idStr)
-	if err != nil || id <= 0 {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid id"})
+	actor, ok := actorFrom(r)
+	if !ok {
+		writeErrorMsg(w, r, http.StatusUnauthorized, "unauthorized")
 		return
 	}
 
-	wl, items, err := h.service.GetWishlist(id)
-	if err != nil {
-		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id <= 0 {
+		writeErrorMsg(w, r, http.StatusBadRequest, "invalid id")
 		return
 	}
-	writeJSON(w, http.StatusOK, map[string]any{
-		"wishlist": wl,
-		"items":    items,
-	})
+
+	switch r.Method {
+	case http.MethodGet:
+		wl, items, err := h.service.GetWishlist(r.Context(), actor, id)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{
+			"wishlist": wl,
+			"items":    items,
+		})
+
+	case http.MethodDelete:
+		if err := h.service.DeleteWishlist(r.Context(), actor, id); err != nil {
+			writeError(w, r, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"message": "deleted"})
+
+	default:
+		writeErrorMsg(w, r, http.StatusMethodNotAllowed, "method not allowed")
+	}
 }
 
 func (h *WishlistHandler) WishlistItems(w http.ResponseWriter, r *http.Request) {
-	path := strings.TrimPrefix(r.URL.Path, "/wishlists/")
-	parts := strings.Split(path, "/")
-	if len(parts) < 2 || parts[1] != "items" {
-		http.NotFound(w, r)
+	actor, ok := actorFrom(r)
+	if !ok {
+		writeErrorMsg(w, r, http.StatusUnauthorized, "unauthorized")
 		return
 	}
 
-	wishlistID, err := strconv.Atoi(parts[0])
+	wishlistID, err := strconv.Atoi(r.PathValue("id"))
 	if err != nil || wishlistID <= 0 {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid wishlist id"})
+		writeErrorMsg(w, r, http.StatusBadRequest, "invalid wishlist id")
 		return
 	}
 
 	if r.Method != http.MethodPost {
-		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		writeErrorMsg(w, r, http.StatusMethodNotAllowed, "method not allowed")
 		return
 	}
 
-	var in struct {
-		BookID int `json:"bookId"`
-		Qty    int `json:"qty"`
-	}
-	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON"})
+	var in logic.ItemInput
+	if !decodeJSON(w, r, &in) {
 		return
 	}
 
-	item, err := h.service.AddItem(wishlistID, in.BookID, in.Qty)
+	item, err := h.service.AddItem(r.Context(), actor, wishlistID, in.BookID, in.Qty)
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		writeError(w, r, err)
 		return
 	}
 	writeJSON(w, http.StatusCreated, item)
 }
 
+func (h *WishlistHandler) WishlistItemByID(w http.ResponseWriter, r *http.Request) {
+	actor, ok := actorFrom(r)
+	if !ok {
+		writeErrorMsg(w, r, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	wishlistID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || wishlistID <= 0 {
+		writeErrorMsg(w, r, http.StatusBadRequest, "invalid wishlist id")
+		return
+	}
+	itemID, err := strconv.Atoi(r.PathValue("itemId"))
+	if err != nil || itemID <= 0 {
+		writeErrorMsg(w, r, http.StatusBadRequest, "invalid item id")
+		return
+	}
+
+	if r.Method != http.MethodDelete {
+		writeErrorMsg(w, r, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	if err := h.service.DeleteItem(r.Context(), actor, wishlistID, itemID); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"message": "deleted"})
+}
+
 func (h *WishlistHandler) Gift(w http.ResponseWriter, r *http.Request) {
-	path := strings.TrimPrefix(r.URL.Path, "/wishlists/")
-	parts := strings.Split(path, "/")
-	if len(parts) < 2 || parts[1] != "gift" {
-		http.NotFound(w, r)
+	actor, ok := actorFrom(r)
+	if !ok {
+		writeErrorMsg(w, r, http.StatusUnauthorized, "unauthorized")
 		return
 	}
 
 	if r.Method != http.MethodPost {
-		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		writeErrorMsg(w, r, http.StatusMethodNotAllowed, "method not allowed")
 		return
 	}
 
-	wishlistID, err := strconv.Atoi(parts[0])
+	wishlistID, err := strconv.Atoi(r.PathValue("id"))
 	if err != nil || wishlistID <= 0 {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid wishlist id"})
+		writeErrorMsg(w, r, http.StatusBadRequest, "invalid wishlist id")
 		return
 	}
 
-	buyerID, _ := strconv.Atoi(r.URL.Query().Get("buyerCustomerId"))
-
-	order, items, giftForCustomerID, err := h.service.GiftFromWishlist(wishlistID, buyerID)
+	order, items, giftForCustomerID, err := h.service.GiftFromWishlist(r.Context(), actor, wishlistID)
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		writeError(w, r, err)
 		return
 	}
 
@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"bookstore/internal/logic"
+	"bookstore/internal/middleware"
+	"bookstore/internal/models"
+	"bookstore/internal/repository"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testSecret = "test-secret"
+
+const (
+	ownerID = 10
+	otherID = 20
+	adminID = 99
+)
+
+// fakeWishlists keeps wishlists in memory. Wishlist 1 belongs to ownerID and
+// holds item 1.
+type fakeWishlists struct {
+	lists map[int]models.Wishlist
+	items map[int][]models.WishlistItem
+}
+
+func newFakeWishlists() *fakeWishlists {
+	return &fakeWishlists{
+		lists: map[int]models.Wishlist{1: {ID: 1, CustomerID: ownerID}},
+		items: map[int][]models.WishlistItem{1: {{ID: 1, WishlistID: 1, BookID: 5, Qty: 1}}},
+	}
+}
+
+func (f *fakeWishlists) Create(_ context.Context, customerID int) models.Wishlist {
+	wl := models.Wishlist{ID: len(f.lists) + 1, CustomerID: customerID}
+	f.lists[wl.ID] = wl
+	return wl
+}
+
+func (f *fakeWishlists) GetAll(context.Context) []models.Wishlist {
+	out := make([]models.Wishlist, 0, len(f.lists))
+	for _, wl := range f.lists {
+		out = append(out, wl)
+	}
+	return out
+}
+
+func (f *fakeWishlists) GetByID(_ context.Context, id int) (models.Wishlist, []models.WishlistItem, error) {
+	wl, ok := f.lists[id]
+	if !ok {
+		return models.Wishlist{}, nil, repository.NotFound("wishlist not found")
+	}
+	return wl, f.items[id], nil
+}
+
+func (f *fakeWishlists) Delete(_ context.Context, id int) error {
+	delete(f.lists, id)
+	delete(f.items, id)
+	return nil
+}
+
+func (f *fakeWishlists) AddItem(_ context.Context, wishlistID, bookID, qty int) (models.WishlistItem, error) {
+	item := models.WishlistItem{ID: len(f.items[wishlistID]) + 1, WishlistID: wishlistID, BookID: bookID, Qty: qty}
+	f.items[wishlistID] = append(f.items[wishlistID], item)
+	return item, nil
+}
+
+func (f *fakeWishlists) DeleteItem(_ context.Context, wishlistID, itemID int) error {
+	items := f.items[wishlistID]
+	for i, it := range items {
+		if it.ID == itemID {
+			f.items[wishlistID] = append(items[:i], items[i+1:]...)
+			return nil
+		}
+	}
+	return repository.NotFound("item not found")
+}
+
+// fakeBooks only answers GetByID; the wishlist service needs nothing else.
+type fakeBooks struct {
+	repository.BookRepository
+}
+
+func (fakeBooks) GetByID(_ context.Context, id int) (models.Book, error) {
+	return models.Book{ID: id, Title: "Book", Price: 10}, nil
+}
+
+// fakeOrders only answers Create, for gifts.
+type fakeOrders struct {
+	repository.OrderRepository
+}
+
+func (fakeOrders) Create(_ context.Context, o models.Order, items []models.OrderItem) (models.Order, []models.OrderItem, error) {
+	o.ID = 1
+	return o, items, nil
+}
+
+func token(t *testing.T, userID int, role string) string {
+	t.Helper()
+	s, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"userId": userID,
+		"role":   role,
+	}).SignedString([]byte(testSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestWishlistAccess(t *testing.T) {
+	routes := []struct {
+		method  string
+		pattern string
+		path    string
+		body    string
+		handler func(*WishlistHandler) http.HandlerFunc
+		ok      int
+	}{
+		{"GET", "/wishlists/{id}", "/wishlists/1", "",
+			func(h *WishlistHandler) http.HandlerFunc { return h.WishlistByID }, http.StatusOK},
+		{"DELETE", "/wishlists/{id}", "/wishlists/1", "",
+			func(h *WishlistHandler) http.HandlerFunc { return h.WishlistByID }, http.StatusOK},
+		{"POST", "/wishlists/{id}/items", "/wishlists/1/items", `{"bookId":5,"qty":2}`,
+			func(h *WishlistHandler) http.HandlerFunc { return h.WishlistItems }, http.StatusCreated},
+		{"DELETE", "/wishlists/{id}/items/{itemId}", "/wishlists/1/items/1", "",
+			func(h *WishlistHandler) http.HandlerFunc { return h.WishlistItemByID }, http.StatusOK},
+		{"POST", "/wishlists/{id}/gift", "/wishlists/1/gift", "",
+			func(h *WishlistHandler) http.HandlerFunc { return h.Gift }, http.StatusCreated},
+	}
+	callers := []struct {
+		name   string
+		userID int
+		role   string
+		owner  bool
+	}{
+		{"owner", ownerID, logic.RoleUser, true},
+		{"other user", otherID, logic.RoleUser, false},
+		{"admin", adminID, logic.RoleAdmin, true},
+	}
+
+	// Gifts queue a job to clear the wishlist; nothing drains it here.
+	logic.OrderJobQueue = make(chan logic.OrderJob, 100)
+
+	for _, rt := range routes {
+		for _, c := range callers {
+			t.Run(rt.method+" "+rt.pattern+"/"+c.name, func(t *testing.T) {
+				repo := newFakeWishlists()
+				h := NewWishlistHandler(logic.NewWishlistService(repo, fakeBooks{}, fakeOrders{}))
+				mux := http.NewServeMux()
+				mux.HandleFunc(rt.method+" "+rt.pattern, middleware.AuthOnly(testSecret, nil, rt.handler(h)))
+
+				req := httptest.NewRequest(rt.method, rt.path, strings.NewReader(rt.body))
+				req.Header.Set("Authorization", "Bearer "+token(t, c.userID, c.role))
+				if rt.body != "" {
+					req.Header.Set("Content-Type", "application/json")
+				}
+				rec := httptest.NewRecorder()
+				mux.ServeHTTP(rec, req)
+
+				want := http.StatusForbidden
+				if c.owner {
+					want = rt.ok
+				}
+				if rec.Code != want {
+					t.Fatalf("status = %d, want %d; body: %s", rec.Code, want, rec.Body)
+				}
+				if !c.owner && len(repo.items[1]) != 1 {
+					t.Fatalf("forbidden request changed the wishlist: %+v", repo.items[1])
+				}
+			})
+		}
+	}
+}
+
+func TestWishlistAccessWithoutToken(t *testing.T) {
+	h := NewWishlistHandler(logic.NewWishlistService(newFakeWishlists(), fakeBooks{}, fakeOrders{}))
+	handler := middleware.AuthOnly(testSecret, nil, h.WishlistByID)
+
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest("GET", "/wishlists/1", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestWishlistGiftQueueFull(t *testing.T) {
+	queue := logic.OrderJobQueue
+	t.Cleanup(func() { logic.OrderJobQueue = queue })
+
+	for name, q := range map[string]chan logic.OrderJob{
+		"full":        make(chan logic.OrderJob),
+		"not started": nil,
+	} {
+		t.Run(name, func(t *testing.T) {
+			logic.OrderJobQueue = q
+			repo := newFakeWishlists()
+			h := NewWishlistHandler(logic.NewWishlistService(repo, fakeBooks{}, fakeOrders{}))
+			mux := http.NewServeMux()
+			mux.HandleFunc("POST /wishlists/{id}/gift", middleware.AuthOnly(testSecret, nil, h.Gift))
+
+			req := httptest.NewRequest("POST", "/wishlists/1/gift", nil)
+			req.Header.Set("Authorization", "Bearer "+token(t, ownerID, logic.RoleUser))
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+
+			// The order is placed, so the gift succeeds and the wishlist is
+			// cleared in the request rather than by a worker.
+			if rec.Code != http.StatusCreated {
+				t.Fatalf("status = %d, want %d; body: %s", rec.Code, http.StatusCreated, rec.Body)
+			}
+			if _, ok := repo.lists[1]; ok {
+				t.Fatal("wishlist was not cleared")
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+// Package health runs the dependency checks behind the readiness probe and
+// tracks whether the process is draining for shutdown.
+package health
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	StatusReady    = "ready"
+	StatusNotReady = "not_ready"
+	StatusDraining = "draining"
+
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+// CheckFunc probes one dependency. details, if non-nil, is reported as is.
+type CheckFunc func(ctx context.Context) (details any, err error)
+
+type Result struct {
+	Status     string  `json:"status"`
+	Error      string  `json:"error,omitempty"`
+	Details    any     `json:"details,omitempty"`
+	DurationMS float64 `json:"durationMs"`
+}
+
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks"`
+}
+
+func (r Report) Ready() bool { return r.Status == StatusReady }
+
+type check struct {
+	name string
+	fn   CheckFunc
+}
+
+// Checker is safe for concurrent use once all checks have been added.
+type Checker struct {
+	timeout  time.Duration
+	started  time.Time
+	checks   []check
+	draining atomic.Bool
+}
+
+// NewChecker bounds every check by timeout.
+func NewChecker(timeout time.Duration) *Checker {
+	return &Checker{timeout: timeout, started: time.Now()}
+}
+
+func (c *Checker) Add(name string, fn CheckFunc) {
+	c.checks = append(c.checks, check{name: name, fn: fn})
+}
+
+// Drain marks the process as shutting down; readiness fails from then on.
+func (c *Checker) Drain() { c.draining.Store(true) }
+
+func (c *Checker) Uptime() time.Duration { return time.Since(c.started) }
+
+// Check runs every check concurrently and reports ready only if all are up
+// and the process is not draining.
+func (c *Checker) Check(ctx context.Context) Report {
+	results := make([]Result, len(c.checks))
+
+	var wg sync.WaitGroup
+	for i, ch := range c.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = c.run(ctx, ch.fn)
+		}()
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusReady, Checks: make(map[string]Result, len(c.checks))}
+	for i, ch := range c.checks {
+		report.Checks[ch.name] = results[i]
+		if results[i].Status != StatusUp {
+			report.Status = StatusNotReady
+		}
+	}
+	if c.draining.Load() {
+		report.Status = StatusDraining
+	}
+	return report
+}
+
+func (c *Checker) run(ctx context.Context, fn CheckFunc) Result {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	details, err := fn(ctx)
+	res := Result{
+		Status:     StatusUp,
+		Details:    details,
+		DurationMS: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		res.Status = StatusDown
+		res.Error = err.Error()
+	}
+	return res
+}
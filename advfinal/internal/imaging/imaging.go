@@ -0,0 +1,65 @@
+// Package imaging scales images down with the standard library alone.
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// Fit scales img down to fit within maxW x maxH, keeping its aspect ratio,
+// and flattens transparency onto white. Smaller images are only flattened.
+func Fit(img image.Image, maxW, maxH int) *image.RGBA {
+	src := flatten(img)
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	if w <= maxW && h <= maxH {
+		return src
+	}
+	dw, dh := maxW, h*maxW/w
+	if dh > maxH {
+		dw, dh = w*maxH/h, maxH
+	}
+	return shrink(src, max(dw, 1), max(dh, 1))
+}
+
+func flatten(img image.Image) *image.RGBA {
+	b := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(dst, dst.Bounds(), img, b.Min, draw.Over)
+	return dst
+}
+
+// shrink averages the source pixels under each destination pixel, weighting
+// the ones on its edges by how much of them it covers.
+func shrink(src *image.RGBA, dw, dh int) *image.RGBA {
+	sw, sh := src.Bounds().Dx(), src.Bounds().Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	sx := float64(sw) / float64(dw)
+	sy := float64(sh) / float64(dh)
+
+	for y := 0; y < dh; y++ {
+		y0, y1 := float64(y)*sy, float64(y+1)*sy
+		for x := 0; x < dw; x++ {
+			x0, x1 := float64(x)*sx, float64(x+1)*sx
+			var r, g, b, total float64
+			for py := int(y0); float64(py) < y1 && py < sh; py++ {
+				wy := min(y1, float64(py+1)) - max(y0, float64(py))
+				for px := int(x0); float64(px) < x1 && px < sw; px++ {
+					wt := wy * (min(x1, float64(px+1)) - max(x0, float64(px)))
+					i := src.PixOffset(px, py)
+					r += float64(src.Pix[i]) * wt
+					g += float64(src.Pix[i+1]) * wt
+					b += float64(src.Pix[i+2]) * wt
+					total += wt
+				}
+			}
+			i := dst.PixOffset(x, y)
+			dst.Pix[i] = uint8(r/total + 0.5)
+			dst.Pix[i+1] = uint8(g/total + 0.5)
+			dst.Pix[i+2] = uint8(b/total + 0.5)
+			dst.Pix[i+3] = 0xff
+		}
+	}
+	return dst
+}
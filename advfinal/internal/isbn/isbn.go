@@ -0,0 +1,104 @@
+// Package isbn validates ISBN-10 and ISBN-13 numbers and converts between
+// them. The catalog stores every ISBN as 13 bare digits.
+package isbn
+
+import (
+	"errors"
+	"strings"
+)
+
+var ErrInvalid = errors.New("not a valid ISBN-10 or ISBN-13")
+
+// Normalize returns s as a bare ISBN-13. Hyphens and spaces are ignored and
+// an ISBN-10 is converted.
+func Normalize(s string) (string, error) {
+	s = strip(s)
+	switch len(s) {
+	case 10:
+		if valid10(s) {
+			return To13(s), nil
+		}
+	case 13:
+		if valid13(s) {
+			return s, nil
+		}
+	}
+	return "", ErrInvalid
+}
+
+func Valid(s string) bool {
+	_, err := Normalize(s)
+	return err == nil
+}
+
+// To13 converts a valid, bare ISBN-10.
+func To13(isbn10 string) string {
+	s := "978" + isbn10[:9]
+	return s + string(check13(s))
+}
+
+// To10 converts a bare ISBN-13. Only 978 numbers have an ISBN-10.
+func To10(isbn13 string) (string, bool) {
+	if len(isbn13) != 13 || !strings.HasPrefix(isbn13, "978") {
+		return "", false
+	}
+	s := isbn13[3:12]
+	return s + string(check10(s)), true
+}
+
+func strip(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '-' || r == ' ':
+		case r == 'x':
+			b.WriteByte('X')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func digits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func valid10(s string) bool {
+	return digits(s[:9]) && s[9] == check10(s[:9])
+}
+
+func valid13(s string) bool {
+	return digits(s) && (strings.HasPrefix(s, "978") || strings.HasPrefix(s, "979")) && s[12] == check13(s[:12])
+}
+
+// check10 is the ISBN-10 check character of the first nine digits.
+func check10(s string) byte {
+	sum := 0
+	for i := 0; i < 9; i++ {
+		sum += int(s[i]-'0') * (10 - i)
+	}
+	c := (11 - sum%11) % 11
+	if c == 10 {
+		return 'X'
+	}
+	return byte('0' + c)
+}
+
+// check13 is the EAN-13 check digit of the first twelve digits.
+func check13(s string) byte {
+	sum := 0
+	for i := 0; i < 12; i++ {
+		d := int(s[i] - '0')
+		if i%2 == 1 {
+			d *= 3
+		}
+		sum += d
+	}
+	return byte('0' + (10-sum%10)%10)
+}
@@ -0,0 +1,84 @@
+package isbn
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string // "" for invalid input
+	}{
+		{"9780306406157", "9780306406157"},
+		{"978-0-306-40615-7", "9780306406157"},
+		{"978 0 306 40615 7", "9780306406157"},
+		{"0306406152", "9780306406157"},
+		{"0-306-40615-2", "9780306406157"},
+		{"0 306 40615 2", "9780306406157"},
+		{"080442957X", "9780804429573"},
+		{"0-8044-2957-x", "9780804429573"},
+		{"979-10-90636-07-1", "9791090636071"},
+
+		{"9780306406158", ""}, // bad check digit
+		{"0306406153", ""},    // bad check digit
+		{"0804429579", ""},    // check digit should be X
+		{"9770306406155", ""}, // not a book prefix
+		{"03064061X2", ""},    // X before the check digit
+		{"978030640615", ""},  // too short
+		{"97803064061577", ""},
+		{"", ""},
+		{"isbn", ""},
+	}
+	for _, tc := range tests {
+		got, err := Normalize(tc.in)
+		if tc.want == "" {
+			if !errors.Is(err, ErrInvalid) {
+				t.Errorf("Normalize(%q) = %q, %v; want ErrInvalid", tc.in, got, err)
+			}
+			if Valid(tc.in) {
+				t.Errorf("Valid(%q) = true", tc.in)
+			}
+			continue
+		}
+		if err != nil || got != tc.want {
+			t.Errorf("Normalize(%q) = %q, %v; want %q", tc.in, got, err, tc.want)
+		}
+		if !Valid(tc.in) {
+			t.Errorf("Valid(%q) = false", tc.in)
+		}
+	}
+}
+
+func TestTo13(t *testing.T) {
+	for in, want := range map[string]string{
+		"0306406152": "9780306406157",
+		"080442957X": "9780804429573",
+		"097522980X": "9780975229804",
+	} {
+		if got := To13(in); got != want {
+			t.Errorf("To13(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestTo10(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+		ok   bool
+	}{
+		{"9780306406157", "0306406152", true},
+		{"9780804429573", "080442957X", true},
+		{"9781554042951", "155404295X", true},
+		{"9791090636071", "", false}, // 979 numbers have no ISBN-10
+		{"0306406152", "", false},
+		{"", "", false},
+	}
+	for _, tc := range tests {
+		got, ok := To10(tc.in)
+		if got != tc.want || ok != tc.ok {
+			t.Errorf("To10(%q) = %q, %v; want %q, %v", tc.in, got, ok, tc.want, tc.ok)
+		}
+	}
+}
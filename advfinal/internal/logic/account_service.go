@@ -0,0 +1,275 @@
+package logic
+
+import (
+	"context"
+	"strings"
+
+	"bookstore/internal/models"
+	"bookstore/internal/repository"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+type AccountService struct {
+	users     repository.UserRepository
+	sessions  repository.SessionRepository
+	orderRepo repository.OrderRepository
+}
+
+func NewAccountService(
+	users repository.UserRepository,
+	sessions repository.SessionRepository,
+	orderRepo repository.OrderRepository,
+) *AccountService {
+	return &AccountService{
+		users:     users,
+		sessions:  sessions,
+		orderRepo: orderRepo,
+	}
+}
+
+func (s *AccountService) Profile(ctx context.Context, userID int) (models.User, error) {
+	return s.users.GetByID(ctx, userID)
+}
+
+func (s *AccountService) UpdateProfile(ctx context.Context, userID int, name, phone string) (models.User, error) {
+	in := ProfileInput{Name: strings.TrimSpace(name), Phone: strings.TrimSpace(phone)}
+	if err := check(in); err != nil {
+		return models.User{}, err
+	}
+
+	u, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	u.Name = in.Name
+	u.Phone = in.Phone
+
+	if err := s.users.Update(ctx, u); err != nil {
+		return models.User{}, err
+	}
+	return u, nil
+}
+
+// ChangePassword verifies the current password, stores the new hash and
+// revokes every other session of the user. keepSessionID is the session the
+// request came from so the caller stays logged in.
+func (s *AccountService) ChangePassword(ctx context.Context, userID int, current, next string, keepSessionID int) error {
+	if err := check(PasswordChange{CurrentPassword: current, NewPassword: next}); err != nil {
+		return err
+	}
+
+	u, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !passwordMatches(u.Password, current) {
+		return invalid("currentPassword", "current password is incorrect")
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(next), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	u.Password = string(hash)
+
+	if err := s.users.Update(ctx, u); err != nil {
+		return err
+	}
+	return s.sessions.RevokeAllExcept(ctx, userID, keepSessionID)
+}
+
+func (s *AccountService) ChangeEmail(ctx context.Context, userID int, currentPassword, email string) error {
+	email = strings.TrimSpace(email)
+	if err := check(EmailChange{CurrentPassword: currentPassword, Email: email}); err != nil {
+		return err
+	}
+
+	u, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !passwordMatches(u.Password, currentPassword) {
+		return invalid("currentPassword", "current password is incorrect")
+	}
+	if strings.EqualFold(u.Email, email) {
+		return nil
+	}
+	if _, err := s.users.GetByEmail(ctx, email); err == nil {
+		return conflict("email already exists")
+	}
+
+	u.Email = email
+	return s.users.Update(ctx, u)
+}
+
+// ---------- sessions ----------
+
+func (s *AccountService) Sessions(ctx context.Context, userID int) []models.Session {
+	return s.sessions.ListByUser(ctx, userID)
+}
+
+func (s *AccountService) RevokeSession(ctx context.Context, userID, sessionID int) error {
+	sess, err := s.sessions.GetByID(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+	if sess.UserID != userID {
+		return notFound("session not found")
+	}
+	return s.sessions.Revoke(ctx, sessionID)
+}
+
+// ---------- role ----------
+
+// SetRole changes the role of the account registered under email.
+func (s *AccountService) SetRole(ctx context.Context, email, role string) error {
+	if !validRole(role) {
+		return invalid("role", "role must be user or admin")
+	}
+	u, err := s.users.GetByEmail(ctx, strings.TrimSpace(email))
+	if err != nil {
+		return err
+	}
+	u.Role = role
+	return s.users.Update(ctx, u)
+}
+
+// ---------- addresses ----------
+
+func (s *AccountService) Addresses(ctx context.Context, userID int) ([]models.Address, error) {
+	u, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if u.Addresses == nil {
+		return []models.Address{}, nil
+	}
+	return u.Addresses, nil
+}
+
+func (s *AccountService) AddAddress(ctx context.Context, userID int, a models.Address) (models.Address, error) {
+	if err := check(a); err != nil {
+		return models.Address{}, err
+	}
+
+	u, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return models.Address{}, err
+	}
+
+	next := 1
+	for _, existing := range u.Addresses {
+		if existing.ID >= next {
+			next = existing.ID + 1
+		}
+	}
+	a.ID = next
+	if len(u.Addresses) == 0 {
+		a.Default = true
+	}
+
+	u.Addresses = append(u.Addresses, a)
+	if a.Default {
+		u.Addresses = withDefault(u.Addresses, a.ID)
+	}
+
+	if err := s.users.Update(ctx, u); err != nil {
+		return models.Address{}, err
+	}
+	return a, nil
+}
+
+func (s *AccountService) UpdateAddress(ctx context.Context, userID int, a models.Address) error {
+	if err := check(a); err != nil {
+		return err
+	}
+
+	u, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	found := false
+	for i := range u.Addresses {
+		if u.Addresses[i].ID == a.ID {
+			a.Default = a.Default || u.Addresses[i].Default
+			u.Addresses[i] = a
+			found = true
+			break
+		}
+	}
+	if !found {
+		return notFound("address not found")
+	}
+	if a.Default {
+		u.Addresses = withDefault(u.Addresses, a.ID)
+	}
+
+	return s.users.Update(ctx, u)
+}
+
+func (s *AccountService) DeleteAddress(ctx context.Context, userID, addressID int) error {
+	u, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	out := make([]models.Address, 0, len(u.Addresses))
+	wasDefault := false
+	for _, a := range u.Addresses {
+		if a.ID == addressID {
+			wasDefault = a.Default
+			continue
+		}
+		out = append(out, a)
+	}
+	if len(out) == len(u.Addresses) {
+		return notFound("address not found")
+	}
+	if wasDefault && len(out) > 0 {
+		out[0].Default = true
+	}
+
+	u.Addresses = out
+	return s.users.Update(ctx, u)
+}
+
+func (s *AccountService) SetDefaultAddress(ctx context.Context, userID, addressID int) error {
+	u, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	found := false
+	for _, a := range u.Addresses {
+		if a.ID == addressID {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return notFound("address not found")
+	}
+
+	u.Addresses = withDefault(u.Addresses, addressID)
+	return s.users.Update(ctx, u)
+}
+
+// ---------- orders ----------
+
+func (s *AccountService) OrderHistory(ctx context.Context, userID int) []models.Order {
+	return s.orderRepo.GetByCustomer(ctx, userID)
+}
+
+func passwordMatches(hash, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+func withDefault(list []models.Address, id int) []models.Address {
+	for i := range list {
+		list[i].Default = list[i].ID == id
+	}
+	return list
+}
@@ -0,0 +1,25 @@
+package logic
+
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+func validRole(role string) bool {
+	return role == RoleUser || role == RoleAdmin
+}
+
+// Actor is the authenticated caller a service acts on behalf of.
+type Actor struct {
+	UserID int
+	Role   string
+}
+
+func (a Actor) IsAdmin() bool {
+	return a.Role == RoleAdmin
+}
+
+// CanAccess reports whether the actor may touch a resource owned by ownerID.
+func (a Actor) CanAccess(ownerID int) bool {
+	return a.IsAdmin() || (a.UserID > 0 && a.UserID == ownerID)
+}
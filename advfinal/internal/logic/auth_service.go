@@ -1,7 +1,8 @@
 package logic
 
 import (
-	"errors"
+	"context"
+	"strings"
 	"time"
 
 	"bookstore/internal/models"
@@ -13,17 +14,35 @@ import (
 
 type AuthService struct {
 	repo      repository.UserRepository
+	sessions  repository.SessionRepository
 	jwtSecret []byte
+	tokenTTL  time.Duration
 }
 
-func NewAuthService(repo repository.UserRepository, secret string) *AuthService {
+func NewAuthService(repo repository.UserRepository, sessions repository.SessionRepository, secret string, tokenTTL time.Duration) *AuthService {
 	return &AuthService{
 		repo:      repo,
+		sessions:  sessions,
 		jwtSecret: []byte(secret),
+		tokenTTL:  tokenTTL,
 	}
 }
 
-func (s *AuthService) Register(email, password string) error {
+func (s *AuthService) Register(ctx context.Context, email, password string) error {
+	return s.CreateUser(ctx, email, password, RoleUser)
+}
+
+// CreateUser registers an account with the given role; self-service sign-up
+// goes through Register, which always uses RoleUser.
+func (s *AuthService) CreateUser(ctx context.Context, email, password, role string) error {
+	if !validRole(role) {
+		return invalid("role", "role must be user or admin")
+	}
+	email = strings.TrimSpace(email)
+	if err := check(Registration{Email: email, Password: password}); err != nil {
+		return err
+	}
+
 	hash, err := bcrypt.GenerateFromPassword(
 		[]byte(password),
 		bcrypt.DefaultCost,
@@ -35,32 +54,74 @@ func (s *AuthService) Register(email, password string) error {
 	user := models.User{
 		Email:    email,
 		Password: string(hash),
-		Role:     "user",
+		Role:     role,
 	}
 
-	return s.repo.Create(user)
+	return s.repo.Create(ctx, user)
 }
 
-func (s *AuthService) Login(email, password string) (string, error) {
-	u, err := s.repo.GetByEmail(email)
+// Login checks the credentials, records a new session for the device and
+// returns a signed token carrying the session id in the "sid" claim.
+func (s *AuthService) Login(ctx context.Context, email, password, userAgent, ip string) (string, error) {
+	email = strings.TrimSpace(email)
+	if err := check(Credentials{Email: email, Password: password}); err != nil {
+		return "", err
+	}
+
+	u, err := s.repo.GetByEmail(ctx, email)
 	if err != nil {
-		return "", errors.New("invalid credentials")
+		return "", ErrUnauthorized
 	}
 
 	if err := bcrypt.CompareHashAndPassword(
 		[]byte(u.Password),
 		[]byte(password),
 	); err != nil {
-		return "", errors.New("invalid credentials")
+		return "", ErrUnauthorized
+	}
+
+	now := time.Now()
+	sess, err := s.sessions.Create(ctx, models.Session{
+		UserID:    u.ID,
+		UserAgent: userAgent,
+		IP:        ip,
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.tokenTTL),
+	})
+	if err != nil {
+		return "", err
 	}
 
 	claims := jwt.MapClaims{
 		"userId": u.ID,
 		"role":   u.Role,
-		"exp":    time.Now().Add(24 * time.Hour).Unix(),
+		"sid":    sess.ID,
+		"exp":    sess.ExpiresAt.Unix(),
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 
 	return token.SignedString(s.jwtSecret)
 }
+
+// SessionActive reports whether a token's session is still usable.
+// Tokens issued before sessions were tracked carry no sid and stay valid
+// until they expire, unless the account has been deleted since.
+func (s *AuthService) SessionActive(ctx context.Context, userID, sessionID int) bool {
+	if sessionID <= 0 {
+		u, err := s.repo.GetByID(ctx, userID)
+		return err == nil && u.DeletedAt == nil
+	}
+	sess, err := s.sessions.GetByID(ctx, sessionID)
+	if err != nil {
+		return false
+	}
+	return sess.UserID == userID && sess.RevokedAt == nil && time.Now().Before(sess.ExpiresAt)
+}
+
+func (s *AuthService) Logout(ctx context.Context, sessionID int) error {
+	if sessionID <= 0 {
+		return nil
+	}
+	return s.sessions.Revoke(ctx, sessionID)
+}
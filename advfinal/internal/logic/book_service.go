@@ -1,56 +1,342 @@
 package logic
 
 import (
+	"cmp"
+	"context"
 	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
 
+	"bookstore/internal/isbn"
 	"bookstore/internal/models"
 	"bookstore/internal/repository"
+	"bookstore/internal/slug"
 )
 
 type BookService struct {
-	repo repository.BookRepository
+	repo       repository.BookRepository
+	entities   *EntityService
+	categories *CategoryService
+	currency   string // catalog currency, used to pick imported ONIX prices
 }
 
-func NewBookService(repo repository.BookRepository) *BookService {
-	return &BookService{repo: repo}
+func NewBookService(repo repository.BookRepository, entities *EntityService, categories *CategoryService, currency string) *BookService {
+	return &BookService{repo: repo, entities: entities, categories: categories, currency: currency}
 }
 
-func (s *BookService) ListBooks() []models.Book {
-	return s.repo.GetAll()
+// Currency is the ISO 4217 code of catalog prices.
+func (s *BookService) Currency() string {
+	return s.currency
 }
 
-func (s *BookService) GetBook(id int) (models.Book, error) {
-	return s.repo.GetByID(id)
+func (s *BookService) ListBooks(ctx context.Context) []models.Book {
+	return s.repo.GetAll(ctx)
 }
 
-func (s *BookService) CreateBook(b models.Book) (models.Book, error) {
-	if b.Title == "" || b.Author == "" {
-		return models.Book{}, errors.New("title and author are required")
+// Book orders accepted by ListBooksSorted. The empty order keeps the
+// stored one.
+var bookSorts = map[string]func(a, b models.Book) int{
+	"": nil,
+	"rating": func(a, b models.Book) int {
+		if c := cmp.Compare(b.Rating, a.Rating); c != 0 {
+			return c
+		}
+		return cmp.Compare(b.RatingCount, a.RatingCount)
+	},
+	"title": func(a, b models.Book) int {
+		return cmp.Compare(strings.ToLower(a.Title), strings.ToLower(b.Title))
+	},
+	"price": func(a, b models.Book) int { return cmp.Compare(a.Price, b.Price) },
+}
+
+// ListBooksSorted lists the catalog in one of the orders of bookSorts:
+// "rating" puts the best rated first, "title" and "price" ascend.
+func (s *BookService) ListBooksSorted(ctx context.Context, sort string) ([]models.Book, error) {
+	order, ok := bookSorts[sort]
+	if !ok {
+		return nil, invalid("sort", "sort must be one of rating, title or price")
+	}
+	books := s.repo.GetAll(ctx)
+	if order != nil {
+		slices.SortStableFunc(books, order)
+	}
+	return books, nil
+}
+
+func (s *BookService) GetBook(ctx context.Context, id int) (models.Book, error) {
+	return s.repo.GetByID(ctx, id)
+}
+
+// Tags lists the tags in use, most used first.
+func (s *BookService) Tags(ctx context.Context) ([]models.TagCount, error) {
+	return s.repo.TagCounts(ctx)
+}
+
+// TaggedBooks lists the books tagged tag, by title. The tag is matched in
+// its normalized form, so "Epic  Fantasy" finds "epic fantasy".
+func (s *BookService) TaggedBooks(ctx context.Context, tag string) ([]models.Book, error) {
+	books, err := s.repo.ListByTag(ctx, normalizeTag(tag))
+	if err != nil {
+		return nil, err
+	}
+	slices.SortStableFunc(books, bookSorts["title"])
+	return books, nil
+}
+
+// GetBookByISBN accepts either ISBN form, with or without hyphens.
+func (s *BookService) GetBookByISBN(ctx context.Context, raw string) (models.Book, error) {
+	n, err := isbn.Normalize(raw)
+	if err != nil {
+		return models.Book{}, invalid("isbn", "isbn must be a valid ISBN-10 or ISBN-13")
+	}
+	return s.repo.GetByISBN(ctx, n)
+}
+
+// GetBookBySlug also finds a book by a slug it has since replaced; compare
+// the result's Slug to redirect.
+func (s *BookService) GetBookBySlug(ctx context.Context, slug string) (models.Book, error) {
+	return s.repo.GetBySlug(ctx, slug)
+}
+
+func (s *BookService) CreateBook(ctx context.Context, b models.Book) (models.Book, error) {
+	normalizeBook(&b)
+	if err := s.entities.fillNames(ctx, &b); err != nil {
+		return models.Book{}, err
 	}
-	if b.Price < 0 {
-		return models.Book{}, errors.New("price cannot be negative")
+	if err := checkBook(b); err != nil {
+		return models.Book{}, err
+	}
+	if err := s.categories.checkIDs(ctx, b.CategoryIDs); err != nil {
+		return models.Book{}, err
+	}
+	if err := s.entities.fillIDs(ctx, &b); err != nil {
+		return models.Book{}, err
+	}
+	if err := s.setManaged(ctx, &b, models.Book{}); err != nil {
+		return models.Book{}, err
 	}
 
-	return s.repo.Create(b)
+	return s.repo.Create(ctx, b)
 }
 
-func (s *BookService) UpdateBook(b models.Book) error {
+// UpdateBook returns the book as stored, after normalization.
+func (s *BookService) UpdateBook(ctx context.Context, b models.Book) (models.Book, error) {
 	if b.ID <= 0 {
-		return errors.New("invalid id")
+		return models.Book{}, invalid("id", "invalid id")
+	}
+	normalizeBook(&b)
+	if err := s.entities.fillNames(ctx, &b); err != nil {
+		return models.Book{}, err
+	}
+	if err := checkBook(b); err != nil {
+		return models.Book{}, err
 	}
-	if b.Title == "" || b.Author == "" {
-		return errors.New("title and author are required")
+	if err := s.categories.checkIDs(ctx, b.CategoryIDs); err != nil {
+		return models.Book{}, err
 	}
-	if b.Price < 0 {
-		return errors.New("price cannot be negative")
+	existing, err := s.repo.GetByID(ctx, b.ID)
+	if err != nil {
+		return models.Book{}, err
+	}
+	if err := s.entities.fillIDs(ctx, &b); err != nil {
+		return models.Book{}, err
+	}
+	if err := s.setManaged(ctx, &b, existing); err != nil {
+		return models.Book{}, err
 	}
 
-	return s.repo.Update(b)
+	return b, s.repo.Update(ctx, b)
 }
 
-func (s *BookService) DeleteBook(id int) error {
+func (s *BookService) DeleteBook(ctx context.Context, id int) error {
 	if id <= 0 {
-		return errors.New("invalid id")
+		return invalid("id", "invalid id")
+	}
+	return s.repo.Delete(ctx, id)
+}
+
+// setManaged sets the fields clients cannot, ignoring what they sent. The
+// cover and rating stay as in old. The slug is made from the title and first
+// author, keeping the one old had while it still fits so that links stay
+// stable; a replaced slug joins PreviousSlugs and keeps working as a redirect.
+func (s *BookService) setManaged(ctx context.Context, b *models.Book, old models.Book) error {
+	b.Cover = old.Cover
+	b.Rating, b.RatingCount = old.Rating, old.RatingCount
+	b.Slug, b.PreviousSlugs = old.Slug, old.PreviousSlugs
+	author := b.Author
+	if len(b.Authors) > 0 {
+		author = b.Authors[0]
+	}
+	base := slug.Make(b.Title, author)
+	if b.Slug == base || slug.Base(b.Slug) == base {
+		return nil
+	}
+
+	next, err := slug.Unique(base, func(sl string) (bool, error) {
+		other, err := s.repo.GetBySlug(ctx, sl)
+		if errors.Is(err, ErrNotFound) {
+			return false, nil
+		}
+		return other.ID != b.ID, err // a book may take back its own old slug
+	})
+	if err != nil {
+		return err
+	}
+
+	prev := []string{}
+	for _, p := range old.PreviousSlugs {
+		if p != next {
+			prev = append(prev, p)
+		}
+	}
+	if old.Slug != "" {
+		prev = append(prev, old.Slug)
+	}
+	b.Slug, b.PreviousSlugs = next, prev
+	return nil
+}
+
+// Bounds on the slices of a book; the declarative rules cannot reach into
+// them.
+const (
+	maxAuthors    = 20
+	maxCategories = 10
+	maxTags       = 20
+	maxTagLen     = 40
+)
+
+// normalizeBook brings equivalent inputs to one stored form: ISBNs become
+// bare ISBN-13s, Author and Authors are kept in step and tags are
+// normalized and deduplicated. Values it cannot
+// make sense of are left for checkBook to report.
+func normalizeBook(b *models.Book) {
+	b.ISBN = strings.TrimSpace(b.ISBN)
+	if n, err := isbn.Normalize(b.ISBN); err == nil {
+		b.ISBN = n
+	}
+	b.ExternalID = strings.TrimSpace(b.ExternalID)
+
+	authors := b.Authors[:0:0]
+	for _, a := range b.Authors {
+		if a = strings.TrimSpace(a); a != "" {
+			authors = append(authors, a)
+		}
+	}
+	b.Authors = authors
+	switch {
+	case len(b.Authors) > 0:
+		b.Author = authorLine(b.Authors)
+	case strings.TrimSpace(b.Author) != "":
+		b.Author = strings.TrimSpace(b.Author)
+		b.Authors = []string{b.Author}
+	}
+
+	b.Format = strings.ToLower(strings.TrimSpace(b.Format))
+	b.Language = strings.ToLower(strings.TrimSpace(b.Language))
+	b.PublicationDate = strings.TrimSpace(b.PublicationDate)
+	b.Availability = strings.ToLower(strings.TrimSpace(b.Availability))
+	b.Genre = strings.TrimSpace(b.Genre)
+	b.Publisher = strings.TrimSpace(b.Publisher)
+	b.Series = strings.TrimSpace(b.Series)
+
+	categories := b.CategoryIDs[:0:0]
+	for _, id := range b.CategoryIDs {
+		if !slices.Contains(categories, id) {
+			categories = append(categories, id)
+		}
+	}
+	b.CategoryIDs = categories
+	tags := b.Tags[:0:0]
+	for _, t := range b.Tags {
+		if t = normalizeTag(t); t != "" && !slices.Contains(tags, t) {
+			tags = append(tags, t)
+		}
+	}
+	b.Tags = tags
+}
+
+// normalizeTag lowercases a tag and collapses its spaces.
+func normalizeTag(t string) string {
+	return strings.Join(strings.Fields(strings.ToLower(t)), " ")
+}
+
+// authorLine joins authors for display, shortening long lists to fit Author.
+func authorLine(authors []string) string {
+	s := strings.Join(authors, ", ")
+	if utf8.RuneCountInString(s) > 120 && len(authors) > 1 {
+		s = authors[0] + " et al."
+	}
+	return s
+}
+
+// checkBook adds the rules on enumerations, dates, authors, categories and
+// tags to the declarative ones.
+func checkBook(b models.Book) error {
+	fields := FieldErrors(check(b))
+	bad := func(field, msg string) {
+		fields = append(fields, FieldError{Field: field, Message: msg})
+	}
+
+	switch b.Availability {
+	case "", models.BookAvailable, models.BookPreorder, models.BookUnavailable:
+	default:
+		bad("availability", "availability must be available, preorder or unavailable")
+	}
+	switch b.Format {
+	case "", models.FormatHardcover, models.FormatPaperback, models.FormatEbook, models.FormatAudiobook, models.FormatOther:
+	default:
+		bad("format", "format must be hardcover, paperback, ebook, audiobook or other")
+	}
+	if b.Language != "" && !isLanguageCode(b.Language) {
+		bad("language", "language must be a two- or three-letter ISO 639 code")
+	}
+	if b.PublicationDate != "" {
+		if _, err := time.Parse(time.DateOnly, b.PublicationDate); err != nil {
+			bad("publicationDate", "publicationDate must be a date in YYYY-MM-DD form")
+		}
+	}
+	if b.SeriesPosition > 0 && b.Series == "" {
+		bad("seriesPosition", "seriesPosition needs a series")
+	}
+	if len(b.Authors) > maxAuthors {
+		bad("authors", fmt.Sprintf("authors must list at most %d names", maxAuthors))
+	}
+	for _, a := range b.Authors {
+		if utf8.RuneCountInString(a) > 120 {
+			bad("authors", "each author must be at most 120 characters")
+			break
+		}
+	}
+	if len(b.CategoryIDs) > maxCategories {
+		bad("categoryIDs", fmt.Sprintf("categoryIDs must list at most %d categories", maxCategories))
+	}
+	if len(b.Tags) > maxTags {
+		bad("tags", fmt.Sprintf("tags must list at most %d tags", maxTags))
+	}
+	for _, t := range b.Tags {
+		if utf8.RuneCountInString(t) > maxTagLen {
+			bad("tags", fmt.Sprintf("each tag must be at most %d characters", maxTagLen))
+			break
+		}
+	}
+
+	if len(fields) > 0 {
+		return &ValidationError{Fields: fields}
+	}
+	return nil
+}
+
+func isLanguageCode(s string) bool {
+	if len(s) < 2 || len(s) > 3 {
+		return false
+	}
+	for _, r := range s {
+		if r < 'a' || r > 'z' {
+			return false
+		}
 	}
-	return s.repo.Delete(id)
+	return true
 }
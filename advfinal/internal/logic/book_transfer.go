@@ -0,0 +1,621 @@
+package logic
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"bookstore/internal/models"
+	"bookstore/internal/onix"
+	"bookstore/internal/repository"
+)
+
+// Catalog files are CSV with a header row naming csvColumns (in any order),
+// a JSON array of books as served by the API, or an ONIX 3.0 message.
+const (
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+	FormatONIX = "onix"
+)
+
+// In CSV, authors and tags are separated by semicolons.
+var csvColumns = []string{
+	"id", "isbn", "externalId", "title", "author", "authors", "genre", "publisher", "format",
+	"pages", "language", "publicationDate", "price", "availability", "description",
+	"series", "seriesPosition", "tags",
+}
+
+// ParseFormat accepts a format name, file extension or media type.
+func ParseFormat(s string) (string, error) {
+	s = strings.ToLower(strings.TrimPrefix(s, "."))
+	if mt, _, ok := strings.Cut(s, ";"); ok {
+		s = strings.TrimSpace(mt)
+	}
+	switch s {
+	case FormatCSV, "text/csv":
+		return FormatCSV, nil
+	case FormatJSON, "application/json":
+		return FormatJSON, nil
+	case FormatONIX, "xml", "application/xml", "text/xml":
+		return FormatONIX, nil
+	}
+	return "", invalid("format", "format must be csv, json or onix")
+}
+
+// ExportFormat is ParseFormat for the formats the catalog can be written in.
+func ExportFormat(s string) (string, error) {
+	format, err := ParseFormat(s)
+	if err == nil && format == FormatONIX {
+		return "", invalid("format", "export format must be csv or json")
+	}
+	return format, err
+}
+
+const (
+	ImportCreate = "create"
+	ImportUpdate = "update"
+	ImportDelete = "delete"
+	ImportSkip   = "skip"
+	ImportError  = "error"
+)
+
+// previewRows caps how many successful rows a report lists. Failed rows are
+// always listed and the counters are always exact.
+const previewRows = 100
+
+type ImportRow struct {
+	Row    int          `json:"row"` // CSV line, or 1-based position in the JSON array or ONIX message
+	Action string       `json:"action"`
+	BookID int          `json:"bookId,omitempty"`
+	Title  string       `json:"title,omitempty"`
+	Note   string       `json:"note,omitempty"`
+	Errors []FieldError `json:"errors,omitempty"`
+}
+
+type ImportReport struct {
+	DryRun  bool        `json:"dryRun"`
+	Total   int         `json:"total"`
+	Created int         `json:"created"`
+	Updated int         `json:"updated"`
+	Deleted int         `json:"deleted"`
+	Skipped int         `json:"skipped"`
+	Failed  int         `json:"failed"`
+	Rows    []ImportRow `json:"rows"`
+
+	// Unmapped lists the ONIX elements the catalog has no place for, with
+	// the number of products that carried each.
+	Unmapped []UnmappedField `json:"unmapped,omitempty"`
+}
+
+type UnmappedField struct {
+	Path  string `json:"path"`
+	Count int    `json:"count"`
+}
+
+func (r *ImportReport) add(row ImportRow) {
+	r.Total++
+	switch row.Action {
+	case ImportCreate:
+		r.Created++
+	case ImportUpdate:
+		r.Updated++
+	case ImportDelete:
+		r.Deleted++
+	case ImportSkip:
+		r.Skipped++
+	default:
+		r.Failed++
+		r.Rows = append(r.Rows, row)
+		return
+	}
+	if r.Total-r.Failed <= previewRows {
+		r.Rows = append(r.Rows, row)
+	}
+}
+
+// importRecord is one record of a catalog file.
+type importRecord struct {
+	Row    int
+	Book   models.Book
+	Errors []FieldError // problems found while reading the record
+
+	Delete bool
+	Skip   bool
+	// Fields names the columns a partial update replaces. nil replaces the
+	// whole book, as CSV and JSON records always do.
+	Fields []string
+
+	Unmapped []string
+}
+
+// ImportBooks upserts every record read from r. A record replaces the book
+// with the same ISBN or, failing that, the same external id, and otherwise
+// creates a new one; the id column is ignored. ONIX messages may also delete
+// books and update only some fields. Invalid records are reported and
+// skipped. A dry run validates and matches without writing.
+func (s *BookService) ImportBooks(ctx context.Context, r io.Reader, format string, dryRun bool) (ImportReport, error) {
+	report := ImportReport{DryRun: dryRun, Rows: []ImportRow{}}
+	seen := map[string]int{} // import key -> first row using it
+	unmapped := map[string]int{}
+
+	err := s.readBooks(r, format, func(rec importRecord) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		res := ImportRow{Row: rec.Row, Title: rec.Book.Title, Errors: rec.Errors}
+		if len(rec.Errors) == 0 {
+			if err := s.importRecord(ctx, &res, rec, seen, dryRun); err != nil {
+				return err
+			}
+		}
+		if len(res.Errors) > 0 {
+			res.Action = ImportError
+		}
+		report.add(res)
+
+		counted := map[string]bool{}
+		for _, path := range rec.Unmapped {
+			if !counted[path] {
+				counted[path] = true
+				unmapped[path]++
+			}
+		}
+		return nil
+	})
+
+	for path, n := range unmapped {
+		report.Unmapped = append(report.Unmapped, UnmappedField{Path: path, Count: n})
+	}
+	sort.Slice(report.Unmapped, func(i, j int) bool { return report.Unmapped[i].Path < report.Unmapped[j].Path })
+	return report, err
+}
+
+// importRecord fills in res for a well-formed record. Only failures that are
+// not about the record itself, such as a lost database, are returned.
+func (s *BookService) importRecord(ctx context.Context, res *ImportRow, rec importRecord, seen map[string]int, dryRun bool) error {
+	if rec.Skip {
+		res.Action = ImportSkip
+		res.Note = "test record"
+		return nil
+	}
+
+	// Files name authors, publishers, series and genres; ids are this
+	// catalog's own. Nor do they place books in the category tree.
+	b := rec.Book
+	b.ID = 0
+	b.AuthorIDs, b.PublisherID, b.SeriesID, b.GenreID = nil, 0, 0, 0
+	b.CategoryIDs = nil
+	normalizeBook(&b)
+	partial := rec.Delete || rec.Fields != nil
+	if !partial {
+		if err := checkBook(b); err != nil {
+			res.Errors = columnErrors(FieldErrors(err))
+			return nil
+		}
+	}
+
+	key, field := "", ""
+	switch {
+	case b.ISBN != "":
+		key, field = "isbn:"+b.ISBN, "isbn"
+	case b.ExternalID != "":
+		key, field = "ext:"+b.ExternalID, "externalId"
+	case partial:
+		res.Errors = []FieldError{{Field: "isbn", Message: "isbn or externalId is required to find the book"}}
+		return nil
+	}
+	// Two full records for one book are a mistake in the file, but an ONIX
+	// message may follow a record with updates to it, applied in order.
+	if key != "" && !partial {
+		if first, dup := seen[key]; dup {
+			res.Errors = []FieldError{{Field: field, Message: fmt.Sprintf("%s repeats row %d", field, first)}}
+			return nil
+		}
+		seen[key] = res.Row
+	}
+
+	existing, err := s.matchBook(ctx, b)
+	found := err == nil
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return err
+	}
+
+	switch {
+	case rec.Delete:
+		if !found {
+			res.Action = ImportSkip
+			res.Note = "no matching book to delete"
+			return nil
+		}
+		res.Action = ImportDelete
+		res.BookID = existing.ID
+		res.Title = existing.Title
+		if !dryRun {
+			if err := s.repo.Delete(ctx, existing.ID); err != nil {
+				return rowError(res, err)
+			}
+		}
+		return nil
+
+	case rec.Fields != nil:
+		if !found {
+			res.Errors = []FieldError{{Field: field, Message: "no matching book to update"}}
+			return nil
+		}
+		b = mergeFields(existing, b, rec.Fields)
+		normalizeBook(&b)
+		res.Title = b.Title
+		if err := checkBook(b); err != nil {
+			res.Errors = columnErrors(FieldErrors(err))
+			return nil
+		}
+	}
+
+	if !found {
+		res.Action = ImportCreate
+		if !dryRun {
+			if err := s.entities.fillIDs(ctx, &b); err != nil {
+				return err
+			}
+			if err := s.setManaged(ctx, &b, models.Book{}); err != nil {
+				return err
+			}
+			created, err := s.repo.Create(ctx, b)
+			if err != nil {
+				return rowError(res, err)
+			}
+			res.BookID = created.ID
+		}
+		return nil
+	}
+
+	res.Action = ImportUpdate
+	res.BookID = existing.ID
+	b.ID = existing.ID
+	// A record matched by one key keeps the other one.
+	if b.ISBN == "" {
+		b.ISBN = existing.ISBN
+	}
+	if b.ExternalID == "" {
+		b.ExternalID = existing.ExternalID
+	}
+	b.CategoryIDs = existing.CategoryIDs
+	if !dryRun {
+		if err := s.entities.fillIDs(ctx, &b); err != nil {
+			return err
+		}
+		if err := s.setManaged(ctx, &b, existing); err != nil {
+			return err
+		}
+		if err := s.repo.Update(ctx, b); err != nil {
+			return rowError(res, err)
+		}
+	}
+	return nil
+}
+
+// mergeFields returns dst with the named columns taken from src. A name
+// taken loses its id, to be looked up again.
+func mergeFields(dst, src models.Book, fields []string) models.Book {
+	for _, f := range fields {
+		switch f {
+		case "title":
+			dst.Title = src.Title
+		case "author", "authors":
+			dst.Author, dst.Authors, dst.AuthorIDs = src.Author, src.Authors, nil
+		case "genre":
+			dst.Genre, dst.GenreID = src.Genre, 0
+		case "publisher":
+			dst.Publisher, dst.PublisherID = src.Publisher, 0
+		case "series":
+			dst.Series, dst.SeriesID, dst.SeriesPosition = src.Series, 0, src.SeriesPosition
+		case "tags":
+			dst.Tags = src.Tags
+		case "format":
+			dst.Format = src.Format
+		case "pages":
+			dst.Pages = src.Pages
+		case "language":
+			dst.Language = src.Language
+		case "publicationDate":
+			dst.PublicationDate = src.PublicationDate
+		case "price":
+			dst.Price = src.Price
+		case "availability":
+			dst.Availability = src.Availability
+		case "description":
+			dst.Description = src.Description
+		}
+	}
+	return dst
+}
+
+// rowError records a validation or conflict error against the row and
+// passes anything else through.
+func rowError(res *ImportRow, err error) error {
+	if !errors.Is(err, ErrValidation) && !errors.Is(err, ErrConflict) {
+		return err
+	}
+	res.Errors = columnErrors(FieldErrors(err))
+	if len(res.Errors) == 0 {
+		res.Errors = []FieldError{{Field: "row", Message: err.Error()}}
+	}
+	return nil
+}
+
+func (s *BookService) matchBook(ctx context.Context, b models.Book) (models.Book, error) {
+	if b.ISBN != "" {
+		existing, err := s.repo.GetByISBN(ctx, b.ISBN)
+		if !errors.Is(err, ErrNotFound) || b.ExternalID == "" {
+			return existing, err
+		}
+	}
+	if b.ExternalID != "" {
+		return s.repo.GetByExternalID(ctx, b.ExternalID)
+	}
+	return models.Book{}, repository.NotFound("book not found")
+}
+
+// ExportBooks streams the whole catalog to w.
+func (s *BookService) ExportBooks(ctx context.Context, w io.Writer, format string) error {
+	switch format {
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(csvColumns); err != nil {
+			return err
+		}
+		err := s.repo.Each(ctx, func(b models.Book) error {
+			return cw.Write([]string{
+				strconv.Itoa(b.ID), b.ISBN, b.ExternalID, b.Title, b.Author, strings.Join(b.Authors, "; "),
+				b.Genre, b.Publisher, b.Format, pages(b.Pages), b.Language, b.PublicationDate,
+				strconv.FormatFloat(b.Price, 'f', -1, 64), b.Availability, b.Description,
+				b.Series, pages(b.SeriesPosition), strings.Join(b.Tags, "; "),
+			})
+		})
+		cw.Flush()
+		if err != nil {
+			return err
+		}
+		return cw.Error()
+
+	case FormatJSON:
+		bw := bufio.NewWriter(w)
+		enc := json.NewEncoder(bw)
+		sep := "[\n"
+		err := s.repo.Each(ctx, func(b models.Book) error {
+			if _, err := bw.WriteString(sep); err != nil {
+				return err
+			}
+			sep = ","
+			return enc.Encode(b)
+		})
+		if err != nil {
+			return err
+		}
+		if sep == "[\n" {
+			bw.WriteString("[")
+		}
+		bw.WriteString("]\n")
+		return bw.Flush()
+
+	default:
+		return invalid("format", "export format must be csv or json")
+	}
+}
+
+// readBooks calls fn for every record of r. Records that cannot be turned
+// into a book arrive with Errors set; a malformed file stops the read.
+func (s *BookService) readBooks(r io.Reader, format string, fn func(importRecord) error) error {
+	switch format {
+	case FormatCSV:
+		return readCSV(r, fn)
+	case FormatJSON:
+		return readJSON(r, fn)
+	case FormatONIX:
+		return readONIX(r, s.currency, fn)
+	default:
+		return invalid("format", "format must be csv, json or onix")
+	}
+}
+
+func readCSV(r io.Reader, fn func(importRecord) error) error {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.ReuseRecord = true
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return invalid("file", "file is empty")
+	}
+	if err != nil {
+		return invalid("file", err.Error())
+	}
+
+	cols := make([]string, len(header))
+	for i, h := range header {
+		name := column(strings.TrimSpace(strings.TrimPrefix(h, "\ufeff")))
+		if name == "" {
+			return invalid("file", fmt.Sprintf("unknown column %q; expected %s", h, strings.Join(csvColumns, ", ")))
+		}
+		cols[i] = name
+	}
+
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return invalid("file", err.Error())
+		}
+		line, _ := cr.FieldPos(0)
+
+		var b models.Book
+		var errs []FieldError
+		for i, v := range rec {
+			if i >= len(cols) {
+				errs = append(errs, FieldError{Field: "row", Message: "row has more fields than the header"})
+				break
+			}
+			switch cols[i] {
+			case "isbn":
+				b.ISBN = v
+			case "externalId":
+				b.ExternalID = v
+			case "title":
+				b.Title = v
+			case "author":
+				b.Author = v
+			case "authors":
+				if strings.TrimSpace(v) != "" {
+					b.Authors = strings.Split(v, ";")
+				}
+			case "genre":
+				b.Genre = v
+			case "publisher":
+				b.Publisher = v
+			case "format":
+				b.Format = v
+			case "language":
+				b.Language = v
+			case "publicationDate":
+				b.PublicationDate = v
+			case "availability":
+				b.Availability = v
+			case "description":
+				b.Description = v
+			case "series":
+				b.Series = v
+			case "tags":
+				if strings.TrimSpace(v) != "" {
+					b.Tags = strings.Split(v, ";")
+				}
+			case "price":
+				if v = strings.TrimSpace(v); v != "" {
+					p, err := strconv.ParseFloat(v, 64)
+					if err != nil {
+						errs = append(errs, FieldError{Field: "price", Message: "price must be a number"})
+					}
+					b.Price = p
+				}
+			case "pages":
+				if v = strings.TrimSpace(v); v != "" {
+					n, err := strconv.Atoi(v)
+					if err != nil {
+						errs = append(errs, FieldError{Field: "pages", Message: "pages must be a whole number"})
+					}
+					b.Pages = n
+				}
+			case "seriesPosition":
+				if v = strings.TrimSpace(v); v != "" {
+					n, err := strconv.Atoi(v)
+					if err != nil {
+						errs = append(errs, FieldError{Field: "seriesPosition", Message: "seriesPosition must be a whole number"})
+					}
+					b.SeriesPosition = n
+				}
+			}
+		}
+		if err := fn(importRecord{Row: line, Book: b, Errors: errs}); err != nil {
+			return err
+		}
+	}
+}
+
+func readJSON(r io.Reader, fn func(importRecord) error) error {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('[') {
+		return invalid("file", "file must be a JSON array of books")
+	}
+
+	// Decode reads a whole element before unmarshalling it, so a bad field
+	// only spoils its own record.
+	for row := 1; dec.More(); row++ {
+		var b models.Book
+		var errs []FieldError
+		if err := dec.Decode(&b); err != nil {
+			var typeErr *json.UnmarshalTypeError
+			switch {
+			case errors.As(err, &typeErr):
+				field := column(typeErr.Field)
+				errs = []FieldError{{Field: field, Message: field + " has the wrong type"}}
+			case strings.HasPrefix(err.Error(), "json: unknown field "):
+				field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+				errs = []FieldError{{Field: field, Message: "unknown field " + field}}
+			default:
+				return invalid("file", fmt.Sprintf("record %d: %v", row, err))
+			}
+		}
+		if err := fn(importRecord{Row: row, Book: b, Errors: errs}); err != nil {
+			return err
+		}
+	}
+	if _, err := dec.Token(); err != nil {
+		return invalid("file", "unterminated JSON array")
+	}
+	return nil
+}
+
+func readONIX(r io.Reader, currency string, fn func(importRecord) error) error {
+	products := onix.NewReader(r, currency)
+	for {
+		p, err := products.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return invalid("file", err.Error())
+		}
+
+		rec := importRecord{
+			Row:      p.Position,
+			Book:     p.Book,
+			Errors:   columnErrors(p.Errors),
+			Delete:   p.Action == onix.Delete,
+			Skip:     p.Action == onix.Skip,
+			Fields:   p.Fields,
+			Unmapped: p.Unmapped,
+		}
+		if err := fn(rec); err != nil {
+			return err
+		}
+	}
+}
+
+// pages leaves an unknown page count or series position empty rather than
+// 0.
+func pages(n int) string {
+	if n == 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
+
+// column maps a header, JSON key or validation field name to its canonical
+// column name, or "" if there is none.
+func column(name string) string {
+	for _, c := range csvColumns {
+		if strings.EqualFold(c, name) {
+			return c
+		}
+	}
+	return ""
+}
+
+func columnErrors(errs []FieldError) []FieldError {
+	for i := range errs {
+		if c := column(errs[i].Field); c != "" {
+			errs[i].Field = c
+		}
+	}
+	return errs
+}
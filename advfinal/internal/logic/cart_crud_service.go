@@ -1,7 +1,7 @@
 package logic
 
 import (
-	"errors"
+	"context"
 
 	"bookstore/internal/models"
 	"bookstore/internal/repository"
@@ -16,43 +16,56 @@ func NewCartCRUDService(repo repository.CartRepository, bookRepo repository.Book
 	return &CartCRUDService{repo: repo, bookRepo: bookRepo}
 }
 
-func (s *CartCRUDService) CreateCart(customerID int) models.Cart {
+func (s *CartCRUDService) CreateCart(ctx context.Context, customerID int) models.Cart {
 	if customerID <= 0 {
 		customerID = 1
 	}
-	return s.repo.Create(customerID)
+	return s.repo.Create(ctx, customerID)
 }
 
-func (s *CartCRUDService) ListCarts() []models.Cart {
-	return s.repo.GetAll()
+func (s *CartCRUDService) ListCarts(ctx context.Context) []models.Cart {
+	return s.repo.GetAll(ctx)
 }
 
-func (s *CartCRUDService) GetCart(id int) (models.Cart, []models.CartItem, error) {
-	return s.repo.GetByID(id)
+func (s *CartCRUDService) GetCart(ctx context.Context, id int) (models.Cart, []models.CartItem, error) {
+	return s.repo.GetByID(ctx, id)
 }
 
-func (s *CartCRUDService) UpdateCart(c models.Cart) error {
+func (s *CartCRUDService) UpdateCart(ctx context.Context, c models.Cart) error {
 	if c.ID <= 0 {
-		return errors.New("cart id must be positive")
+		return invalid("id", "cart id must be positive")
 	}
-	return s.repo.Update(c)
+	if err := check(c); err != nil {
+		return err
+	}
+	return s.repo.Update(ctx, c)
 }
 
-func (s *CartCRUDService) DeleteCart(id int) error {
-	return s.repo.Delete(id)
+func (s *CartCRUDService) DeleteCart(ctx context.Context, id int) error {
+	return s.repo.Delete(ctx, id)
 }
 
-func (s *CartCRUDService) AddItem(cartID int, bookID int, qty int) (models.CartItem, error) {
-	if _, err := s.bookRepo.GetByID(bookID); err != nil {
-		return models.CartItem{}, errors.New("book not found")
+func (s *CartCRUDService) AddItem(ctx context.Context, cartID int, bookID int, qty int) (models.CartItem, error) {
+	if err := check(ItemInput{BookID: bookID, Qty: qty}); err != nil {
+		return models.CartItem{}, err
+	}
+	b, err := s.bookRepo.GetByID(ctx, bookID)
+	if err != nil {
+		return models.CartItem{}, notFound("book not found")
 	}
-	return s.repo.AddItem(cartID, bookID, qty)
+	if b.Availability == models.BookUnavailable {
+		return models.CartItem{}, conflict("book is not available")
+	}
+	return s.repo.AddItem(ctx, cartID, bookID, qty)
 }
 
-func (s *CartCRUDService) UpdateItem(cartID int, itemID int, qty int) error {
-	return s.repo.UpdateItem(cartID, itemID, qty)
+func (s *CartCRUDService) UpdateItem(ctx context.Context, cartID int, itemID int, qty int) error {
+	if err := check(QtyInput{Qty: qty}); err != nil {
+		return err
+	}
+	return s.repo.UpdateItem(ctx, cartID, itemID, qty)
 }
 
-func (s *CartCRUDService) DeleteItem(cartID int, itemID int) error {
-	return s.repo.DeleteItem(cartID, itemID)
+func (s *CartCRUDService) DeleteItem(ctx context.Context, cartID int, itemID int) error {
+	return s.repo.DeleteItem(ctx, cartID, itemID)
 }
@@ -1,9 +1,11 @@
 package logic
 
 import (
-	"bookstore/internal/models"
-	"fmt"
+	"log/slog"
 	"time"
+
+	"bookstore/internal/metrics"
+	"bookstore/internal/models"
 )
 
 type CartTask struct {
@@ -11,12 +13,13 @@ type CartTask struct {
 	Item models.CartItem
 }
 
-var CartJobQueue = make(chan CartTask, 100)
+// CartJobQueue is created by StartCartWorkerPool.
+var CartJobQueue chan CartTask
 
 type CartService struct{}
 
 func (s *CartService) AddItemToCart(item models.CartItem) {
-	fmt.Printf("[CART] Item %d added to Cart %d\n", item.BookID, item.CartID)
+	slog.Info("cart item added", "book_id", item.BookID, "cart_id", item.CartID)
 
 	CartJobQueue <- CartTask{
 		Type: "RESERVE_STOCK",
@@ -24,10 +27,14 @@ func (s *CartService) AddItemToCart(item models.CartItem) {
 	}
 }
 
-func StartCartWorkerPool(workerCount int) {
+// StartCartWorkerPool creates CartJobQueue and starts its workers.
+func StartCartWorkerPool(workerCount, queueSize int) {
+	CartJobQueue = make(chan CartTask, queueSize)
+	slog.Info("starting cart workers", "count", workerCount, "queue_size", queueSize)
+
 	for i := 1; i <= workerCount; i++ {
 		go func(workerID int) {
-			fmt.Printf("Worker %d ready to process cart tasks\n", workerID)
+			slog.Debug("cart worker started", "worker_id", workerID)
 			for job := range CartJobQueue {
 				processCartJob(workerID, job)
 			}
@@ -36,7 +43,9 @@ func StartCartWorkerPool(workerCount int) {
 }
 
 func processCartJob(workerID int, job CartTask) {
-	fmt.Printf("[WORKER %d] Checking stock for Book ID %d...\n", workerID, job.Item.BookID)
+	log := slog.With("worker", "cart", "worker_id", workerID, "task", job.Type, "book_id", job.Item.BookID)
+	log.Debug("checking stock")
 	time.Sleep(2 * time.Second)
-	fmt.Printf("[WORKER %d] Stock reserved for Book ID %d\n", workerID, job.Item.BookID)
+	log.Info("stock reserved")
+	metrics.JobDone(job.Type, nil)
 }
@@ -0,0 +1,348 @@
+package logic
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"bookstore/internal/models"
+	"bookstore/internal/repository"
+	"bookstore/internal/slug"
+)
+
+// CategoryService manages the category tree. Books are filed under any
+// number of categories, and a category lists the books of its whole branch.
+type CategoryService struct {
+	categories repository.CategoryRepository
+	books      repository.BookRepository
+}
+
+func NewCategoryService(categories repository.CategoryRepository, books repository.BookRepository) *CategoryService {
+	return &CategoryService{categories: categories, books: books}
+}
+
+// Tree returns the top-level categories with their subcategories nested
+// under them, each level by name.
+func (s *CategoryService) Tree(ctx context.Context) ([]models.CategoryNode, error) {
+	all, err := s.categories.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	children := map[int][]models.Category{}
+	for _, c := range all {
+		children[c.ParentID] = append(children[c.ParentID], c)
+	}
+	var nodes func(parent int) []models.CategoryNode
+	nodes = func(parent int) []models.CategoryNode {
+		out := []models.CategoryNode{}
+		for _, c := range children[parent] {
+			out = append(out, models.CategoryNode{Category: c, Children: nodes(c.ID)})
+		}
+		return out
+	}
+	return nodes(0), nil
+}
+
+func (s *CategoryService) Get(ctx context.Context, id int) (models.Category, error) {
+	if id <= 0 {
+		return models.Category{}, invalid("id", "invalid id")
+	}
+	return s.categories.GetByID(ctx, id)
+}
+
+// GetBySlug also finds a category by a slug it has since replaced; compare
+// the result's Slug to redirect.
+func (s *CategoryService) GetBySlug(ctx context.Context, slug string) (models.Category, error) {
+	return s.categories.GetBySlug(ctx, slug)
+}
+
+// Children returns the categories directly under id, or the top-level ones
+// for 0.
+func (s *CategoryService) Children(ctx context.Context, id int) ([]models.Category, error) {
+	return s.categories.Children(ctx, id)
+}
+
+// Breadcrumb returns the categories from the top of the tree down to c,
+// c included.
+func (s *CategoryService) Breadcrumb(ctx context.Context, c models.Category) ([]models.Category, error) {
+	out := make([]models.Category, 0, len(c.Ancestors)+1)
+	for _, id := range c.Ancestors {
+		a, err := s.categories.GetByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, a)
+	}
+	return append(out, c), nil
+}
+
+// Books lists the books in a category or any category below it, by title.
+func (s *CategoryService) Books(ctx context.Context, id int) ([]models.Book, error) {
+	below, err := s.categories.Descendants(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	ids := []int{id}
+	for _, c := range below {
+		ids = append(ids, c.ID)
+	}
+	books, err := s.books.ListByCategories(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	slices.SortStableFunc(books, func(a, b models.Book) int {
+		return cmp.Compare(strings.ToLower(a.Title), strings.ToLower(b.Title))
+	})
+	return books, nil
+}
+
+func (s *CategoryService) Create(ctx context.Context, in CategoryInput) (models.Category, error) {
+	in.Name = strings.TrimSpace(in.Name)
+	if err := check(in); err != nil {
+		return models.Category{}, err
+	}
+	c := models.Category{Name: in.Name, ParentID: in.ParentID, Ancestors: []int{}, Description: in.Description}
+	if in.ParentID != 0 {
+		parent, err := s.parent(ctx, in.ParentID)
+		if err != nil {
+			return models.Category{}, err
+		}
+		c.Ancestors = append(slices.Clone(parent.Ancestors), parent.ID)
+	}
+	if err := s.nameFree(ctx, in.ParentID, in.Name, 0); err != nil {
+		return models.Category{}, err
+	}
+	sl, err := s.freeSlug(ctx, in.Name, 0)
+	if err != nil {
+		return models.Category{}, err
+	}
+	c.Slug = sl
+	return s.categories.Create(ctx, c)
+}
+
+// Update renames a category or changes its description; Move gives it
+// another parent. A replaced slug keeps working as a redirect.
+func (s *CategoryService) Update(ctx context.Context, id int, in CategoryInput) (models.Category, error) {
+	in.Name = strings.TrimSpace(in.Name)
+	if err := check(in); err != nil {
+		return models.Category{}, err
+	}
+	c, err := s.Get(ctx, id)
+	if err != nil {
+		return models.Category{}, err
+	}
+	if err := s.nameFree(ctx, c.ParentID, in.Name, id); err != nil {
+		return models.Category{}, err
+	}
+	// The slug stays if it is the new name's, or that numbered by Unique; a
+	// number that was part of the old name, as in "Apollo 13", does not count.
+	base := slug.Make(in.Name)
+	numbered := c.Slug != slug.Make(c.Name) && slug.Base(c.Slug) == base
+	if c.Slug != base && !numbered {
+		next, err := s.freeSlug(ctx, in.Name, id)
+		if err != nil {
+			return models.Category{}, err
+		}
+		prev := slices.DeleteFunc(c.PreviousSlugs, func(p string) bool { return p == next })
+		c.Slug, c.PreviousSlugs = next, append(prev, c.Slug)
+	}
+	c.Name, c.Description = in.Name, in.Description
+	return c, s.categories.Update(ctx, c)
+}
+
+// Move puts a category, with its whole branch, under another parent, or at
+// the top of the tree for parent 0.
+func (s *CategoryService) Move(ctx context.Context, id int, in MoveInput) (models.Category, error) {
+	if err := check(in); err != nil {
+		return models.Category{}, err
+	}
+	c, err := s.Get(ctx, id)
+	if err != nil {
+		return models.Category{}, err
+	}
+	if in.ParentID == c.ParentID {
+		return c, nil
+	}
+	ancestors := []int{}
+	if in.ParentID != 0 {
+		parent, err := s.parent(ctx, in.ParentID)
+		if err != nil {
+			return models.Category{}, err
+		}
+		if parent.ID == c.ID || slices.Contains(parent.Ancestors, c.ID) {
+			return models.Category{}, invalid("parentId", "cannot move a category under itself or one of its subcategories")
+		}
+		ancestors = append(slices.Clone(parent.Ancestors), parent.ID)
+	}
+	if err := s.nameFree(ctx, in.ParentID, c.Name, c.ID); err != nil {
+		return models.Category{}, err
+	}
+	return s.move(ctx, c, in.ParentID, ancestors)
+}
+
+// move places c under parent, whose own ancestors are given, and rewrites
+// the ancestors of everything below c to match.
+func (s *CategoryService) move(ctx context.Context, c models.Category, parent int, ancestors []int) (models.Category, error) {
+	below, err := s.categories.Descendants(ctx, c.ID)
+	if err != nil {
+		return models.Category{}, err
+	}
+	c.ParentID, c.Ancestors = parent, ancestors
+	if err := s.categories.Update(ctx, c); err != nil {
+		return models.Category{}, err
+	}
+	for _, d := range below {
+		i := slices.Index(d.Ancestors, c.ID)
+		d.Ancestors = append(append(slices.Clone(ancestors), c.ID), d.Ancestors[i+1:]...)
+		if err := s.categories.Update(ctx, d); err != nil {
+			return models.Category{}, err
+		}
+	}
+	return c, nil
+}
+
+// Delete refuses to remove a category with subcategories or books; move
+// or merge them first.
+func (s *CategoryService) Delete(ctx context.Context, id int) error {
+	if id <= 0 {
+		return invalid("id", "invalid id")
+	}
+	children, err := s.categories.Children(ctx, id)
+	if err != nil {
+		return err
+	}
+	if len(children) > 0 {
+		return conflict("the category has subcategories; move or merge them first")
+	}
+	books, err := s.books.ListByCategories(ctx, []int{id})
+	if err != nil {
+		return err
+	}
+	if len(books) > 0 {
+		return conflict("the category has books; merge it into another instead")
+	}
+	return s.categories.Delete(ctx, id)
+}
+
+// Merge moves the subcategories and books of category from into category
+// into and deletes from, whose slugs then redirect to into. Subcategories
+// named alike in both must be merged first.
+func (s *CategoryService) Merge(ctx context.Context, into int, in MergeInput) (models.Category, error) {
+	if err := check(in); err != nil {
+		return models.Category{}, err
+	}
+	if in.From == into {
+		return models.Category{}, invalid("from", "cannot merge a category into itself")
+	}
+	target, err := s.Get(ctx, into)
+	if err != nil {
+		return models.Category{}, err
+	}
+	source, err := s.Get(ctx, in.From)
+	if err != nil {
+		return models.Category{}, err
+	}
+	if slices.Contains(target.Ancestors, source.ID) {
+		return models.Category{}, invalid("from", "cannot merge a category into one of its subcategories")
+	}
+
+	children, err := s.categories.Children(ctx, source.ID)
+	if err != nil {
+		return models.Category{}, err
+	}
+	for _, child := range children {
+		if err := s.nameFree(ctx, target.ID, child.Name, child.ID); err != nil {
+			return models.Category{}, err
+		}
+	}
+	ancestors := append(slices.Clone(target.Ancestors), target.ID)
+	for _, child := range children {
+		if _, err := s.move(ctx, child, target.ID, ancestors); err != nil {
+			return models.Category{}, err
+		}
+	}
+
+	books, err := s.books.ListByCategories(ctx, []int{source.ID})
+	if err != nil {
+		return models.Category{}, err
+	}
+	for _, b := range books {
+		ids := b.CategoryIDs[:0]
+		for _, id := range b.CategoryIDs {
+			if id == source.ID {
+				id = target.ID
+			}
+			if !slices.Contains(ids, id) {
+				ids = append(ids, id)
+			}
+		}
+		b.CategoryIDs = ids
+		if err := s.books.Update(ctx, b); err != nil {
+			return models.Category{}, err
+		}
+	}
+
+	target.PreviousSlugs = append(append(target.PreviousSlugs, source.Slug), source.PreviousSlugs...)
+	if err := s.categories.Update(ctx, target); err != nil {
+		return models.Category{}, err
+	}
+	return target, s.categories.Delete(ctx, source.ID)
+}
+
+// parent finds the category named as a parent, which must exist.
+func (s *CategoryService) parent(ctx context.Context, id int) (models.Category, error) {
+	c, err := s.categories.GetByID(ctx, id)
+	if errors.Is(err, ErrNotFound) {
+		return c, invalid("parentId", fmt.Sprintf("no category has id %d", id))
+	}
+	return c, err
+}
+
+// nameFree reports a conflict when a category under parent other than id
+// has name, ignoring case and punctuation. The same name may appear in
+// different branches, such as Fantasy under both Fiction and Children.
+func (s *CategoryService) nameFree(ctx context.Context, parent int, name string, id int) error {
+	siblings, err := s.categories.Children(ctx, parent)
+	if err != nil {
+		return err
+	}
+	sl := slug.Make(name)
+	for _, c := range siblings {
+		if c.ID != id && slug.Make(c.Name) == sl {
+			msg := fmt.Sprintf("a top-level category is already named %q", c.Name)
+			if parent != 0 {
+				msg = fmt.Sprintf("the parent category already has a subcategory named %q", c.Name)
+			}
+			return &repository.Error{Kind: ErrConflict, Field: "name", Msg: msg}
+		}
+	}
+	return nil
+}
+
+// freeSlug makes a slug from name that no category but id has used.
+func (s *CategoryService) freeSlug(ctx context.Context, name string, id int) (string, error) {
+	return slug.Unique(slug.Make(name), func(sl string) (bool, error) {
+		other, err := s.categories.GetBySlug(ctx, sl)
+		if errors.Is(err, ErrNotFound) {
+			return false, nil
+		}
+		return other.ID != id, err
+	})
+}
+
+// checkIDs reports category ids a book names that are not in the tree.
+func (s *CategoryService) checkIDs(ctx context.Context, ids []int) error {
+	for _, id := range ids {
+		_, err := s.categories.GetByID(ctx, id)
+		if errors.Is(err, ErrNotFound) {
+			return invalid("categoryIDs", fmt.Sprintf("no category has id %d", id))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package logic
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif" // decoders for the accepted cover types
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"net/http"
+	"strconv"
+
+	"bookstore/internal/imaging"
+	"bookstore/internal/logging"
+	"bookstore/internal/models"
+	"bookstore/internal/repository"
+	"bookstore/internal/storage"
+)
+
+const (
+	minCoverSide = 100
+	// maxCoverPixels bounds the memory a decoded upload may take.
+	maxCoverPixels = 40_000_000
+)
+
+// coverTypes maps the accepted upload types, as sniffed, to the extension
+// the original is stored with.
+var coverTypes = map[string]string{
+	"image/jpeg": "jpg",
+	"image/png":  "png",
+	"image/gif":  "gif",
+}
+
+// coverSizes are the boxes the served sizes are scaled to fit.
+var coverSizes = []struct {
+	name string
+	w, h int
+}{
+	{models.CoverThumb, 240, 360},
+	{models.CoverDetail, 600, 900},
+}
+
+// CoverService stores book covers in a BlobStore under
+// covers/<book id>/<version>/, where the version is a hash of the upload.
+// Cover URLs therefore never change meaning and can be cached for good.
+type CoverService struct {
+	books repository.BookRepository
+	store storage.BlobStore
+}
+
+func NewCoverService(books repository.BookRepository, store storage.BlobStore) *CoverService {
+	return &CoverService{books: books, store: store}
+}
+
+// UploadCover makes data the book's cover. The original is kept and each
+// size is made from it as a JPEG. The previous cover is removed once the
+// book points at the new one.
+func (s *CoverService) UploadCover(ctx context.Context, id int, data []byte) (models.Book, error) {
+	b, err := s.books.GetByID(ctx, id)
+	if err != nil {
+		return models.Book{}, err
+	}
+
+	contentType := http.DetectContentType(data)
+	ext, ok := coverTypes[contentType]
+	if !ok {
+		return models.Book{}, invalid("cover", "cover must be a JPEG, PNG or GIF image")
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return models.Book{}, invalid("cover", "cover image could not be read")
+	}
+	if cfg.Width < minCoverSide || cfg.Height < minCoverSide {
+		return models.Book{}, invalid("cover", fmt.Sprintf("cover must be at least %dx%d pixels", minCoverSide, minCoverSide))
+	}
+	if cfg.Width*cfg.Height > maxCoverPixels {
+		return models.Book{}, invalid("cover", "cover must be at most 40 megapixels")
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return models.Book{}, invalid("cover", "cover image could not be read")
+	}
+
+	sum := sha256.Sum256(data)
+	version := hex.EncodeToString(sum[:8])
+	prefix := coverPrefix(id, version)
+	if err := s.store.Put(ctx, prefix+"original."+ext, bytes.NewReader(data), int64(len(data)), contentType); err != nil {
+		return models.Book{}, err
+	}
+	for _, size := range coverSizes {
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, imaging.Fit(img, size.w, size.h), &jpeg.Options{Quality: 85}); err != nil {
+			return models.Book{}, err
+		}
+		if err := s.store.Put(ctx, prefix+size.name+".jpg", &buf, int64(buf.Len()), "image/jpeg"); err != nil {
+			return models.Book{}, err
+		}
+	}
+
+	if err := s.books.SetCover(ctx, id, version); err != nil {
+		return models.Book{}, err
+	}
+	if b.Cover != "" && b.Cover != version {
+		s.removeCover(ctx, id, b.Cover)
+	}
+	b.Cover = version
+	return b, nil
+}
+
+func (s *CoverService) DeleteCover(ctx context.Context, id int) (models.Book, error) {
+	b, err := s.books.GetByID(ctx, id)
+	if err != nil || b.Cover == "" {
+		return b, err
+	}
+	if err := s.books.SetCover(ctx, id, ""); err != nil {
+		return models.Book{}, err
+	}
+	s.removeCover(ctx, id, b.Cover)
+	b.Cover = ""
+	return b, nil
+}
+
+// OpenCover returns one size of a cover. The caller closes the body.
+func (s *CoverService) OpenCover(ctx context.Context, id int, version, size string) (io.ReadCloser, storage.Info, error) {
+	known := false
+	for _, cs := range coverSizes {
+		known = known || cs.name == size
+	}
+	if _, err := hex.DecodeString(version); !known || err != nil || version == "" {
+		return nil, storage.Info{}, repository.NotFound("cover not found")
+	}
+
+	body, info, err := s.store.Get(ctx, coverPrefix(id, version)+size+".jpg")
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, storage.Info{}, repository.NotFound("cover not found")
+	}
+	return body, info, err
+}
+
+// removeCover deletes a replaced cover. Failures only leave unused files
+// behind, so they are logged rather than returned.
+func (s *CoverService) removeCover(ctx context.Context, id int, version string) {
+	prefix := coverPrefix(id, version)
+	var keys []string
+	for _, ext := range coverTypes {
+		keys = append(keys, prefix+"original."+ext)
+	}
+	for _, size := range coverSizes {
+		keys = append(keys, prefix+size.name+".jpg")
+	}
+	for _, key := range keys {
+		if err := s.store.Delete(ctx, key); err != nil {
+			logging.From(ctx).Warn("remove old cover", "book_id", id, "key", key, "err", err)
+		}
+	}
+}
+
+func coverPrefix(id int, version string) string {
+	return "covers/" + strconv.Itoa(id) + "/" + version + "/"
+}
@@ -0,0 +1,304 @@
+package logic
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"bookstore/internal/models"
+	"bookstore/internal/repository"
+	"bookstore/internal/slug"
+)
+
+// EntityService manages the authors, publishers, series and genres books
+// refer to, keeping the names stored in books in step with them. Every
+// method taking a kind expects one of models.EntityKinds.
+type EntityService struct {
+	entities repository.Entities
+	books    repository.BookRepository
+}
+
+func NewEntityService(entities repository.Entities, books repository.BookRepository) *EntityService {
+	return &EntityService{entities: entities, books: books}
+}
+
+func (s *EntityService) List(ctx context.Context, kind string) ([]models.Entity, error) {
+	return s.entities.Of(kind).List(ctx)
+}
+
+func (s *EntityService) Get(ctx context.Context, kind string, id int) (models.Entity, error) {
+	if id <= 0 {
+		return models.Entity{}, invalid("id", "invalid id")
+	}
+	return s.entities.Of(kind).GetByID(ctx, id)
+}
+
+// GetBySlug also finds an entity by a slug it has since replaced; compare
+// the result's Slug to redirect.
+func (s *EntityService) GetBySlug(ctx context.Context, kind, slug string) (models.Entity, error) {
+	return s.entities.Of(kind).GetBySlug(ctx, slug)
+}
+
+// Books lists the books referring to an entity: a series in its order,
+// anything else by title.
+func (s *EntityService) Books(ctx context.Context, kind string, id int) ([]models.Book, error) {
+	books, err := s.books.ListByEntity(ctx, kind, id)
+	if err != nil {
+		return nil, err
+	}
+	slices.SortStableFunc(books, func(a, b models.Book) int {
+		if kind == models.EntitySeries && a.SeriesPosition != b.SeriesPosition {
+			// Unnumbered books go last.
+			if a.SeriesPosition == 0 || b.SeriesPosition == 0 {
+				return cmp.Compare(b.SeriesPosition, a.SeriesPosition)
+			}
+			return cmp.Compare(a.SeriesPosition, b.SeriesPosition)
+		}
+		return cmp.Compare(strings.ToLower(a.Title), strings.ToLower(b.Title))
+	})
+	return books, nil
+}
+
+func (s *EntityService) Create(ctx context.Context, kind string, in EntityInput) (models.Entity, error) {
+	in.Name = strings.TrimSpace(in.Name)
+	if err := check(in); err != nil {
+		return models.Entity{}, err
+	}
+	sl := slug.Make(in.Name)
+	if err := s.nameFree(ctx, kind, sl, 0); err != nil {
+		return models.Entity{}, err
+	}
+	return s.entities.Of(kind).Create(ctx, models.Entity{Name: in.Name, Slug: sl, Description: in.Description})
+}
+
+// Update renames the books referring to the entity along with it. Its old
+// slug keeps working as a redirect.
+func (s *EntityService) Update(ctx context.Context, kind string, id int, in EntityInput) (models.Entity, error) {
+	in.Name = strings.TrimSpace(in.Name)
+	if err := check(in); err != nil {
+		return models.Entity{}, err
+	}
+	e, err := s.Get(ctx, kind, id)
+	if err != nil {
+		return models.Entity{}, err
+	}
+	renamed := e.Name != in.Name
+	if sl := slug.Make(in.Name); sl != e.Slug {
+		if err := s.nameFree(ctx, kind, sl, id); err != nil {
+			return models.Entity{}, err
+		}
+		prev := slices.DeleteFunc(e.PreviousSlugs, func(p string) bool { return p == sl })
+		e.Slug, e.PreviousSlugs = sl, append(prev, e.Slug)
+	}
+	e.Name, e.Description = in.Name, in.Description
+
+	if err := s.entities.Of(kind).Update(ctx, e); err != nil {
+		return models.Entity{}, err
+	}
+	if renamed {
+		return e, s.relink(ctx, kind, id, nil)
+	}
+	return e, nil
+}
+
+// Delete refuses to remove an entity books still refer to; merge it into
+// another instead.
+func (s *EntityService) Delete(ctx context.Context, kind string, id int) error {
+	if id <= 0 {
+		return invalid("id", "invalid id")
+	}
+	books, err := s.books.ListByEntity(ctx, kind, id)
+	if err != nil {
+		return err
+	}
+	if len(books) > 0 {
+		return conflict(fmt.Sprintf("%d books refer to this %s; merge it into another instead", len(books), models.EntityNoun(kind)))
+	}
+	return s.entities.Of(kind).Delete(ctx, id)
+}
+
+// Merge moves the books of entity from to entity into and deletes from,
+// whose slugs then redirect to into. It is how duplicates such as
+// "Tolkien" and "J.R.R. Tolkien" become one.
+func (s *EntityService) Merge(ctx context.Context, kind string, into int, in MergeInput) (models.Entity, error) {
+	if err := check(in); err != nil {
+		return models.Entity{}, err
+	}
+	if in.From == into {
+		return models.Entity{}, invalid("from", fmt.Sprintf("cannot merge a %s into itself", models.EntityNoun(kind)))
+	}
+	target, err := s.Get(ctx, kind, into)
+	if err != nil {
+		return models.Entity{}, err
+	}
+	source, err := s.Get(ctx, kind, in.From)
+	if err != nil {
+		return models.Entity{}, err
+	}
+
+	err = s.relink(ctx, kind, source.ID, func(b *models.Book) { repoint(b, kind, source.ID, target.ID) })
+	if err != nil {
+		return models.Entity{}, err
+	}
+	target.PreviousSlugs = append(append(target.PreviousSlugs, source.Slug), source.PreviousSlugs...)
+	if err := s.entities.Of(kind).Update(ctx, target); err != nil {
+		return models.Entity{}, err
+	}
+	return target, s.entities.Of(kind).Delete(ctx, source.ID)
+}
+
+// nameFree reports a conflict when sl is the slug, current or previous, of
+// an entity of kind other than id.
+func (s *EntityService) nameFree(ctx context.Context, kind, sl string, id int) error {
+	other, err := s.entities.Of(kind).GetBySlug(ctx, sl)
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return nil
+	case err != nil:
+		return err
+	case other.ID == id:
+		return nil
+	}
+	return &repository.Error{Kind: ErrConflict, Field: "name",
+		Msg: fmt.Sprintf("name matches the %s %q", models.EntityNoun(kind), other.Name)}
+}
+
+// relink rewrites the books referring to an entity after change, which
+// may point them elsewhere, and then renames them after what they refer to.
+func (s *EntityService) relink(ctx context.Context, kind string, id int, change func(*models.Book)) error {
+	books, err := s.books.ListByEntity(ctx, kind, id)
+	if err != nil {
+		return err
+	}
+	for _, b := range books {
+		if change != nil {
+			change(&b)
+		}
+		if err := s.fillNames(ctx, &b); err != nil {
+			return err
+		}
+		if err := s.books.Update(ctx, b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// repoint makes b refer to entity into of kind instead of from.
+func repoint(b *models.Book, kind string, from, into int) {
+	switch kind {
+	case models.EntityAuthor:
+		ids := b.AuthorIDs[:0]
+		for _, id := range b.AuthorIDs {
+			if id == from {
+				id = into
+			}
+			if !slices.Contains(ids, id) {
+				ids = append(ids, id)
+			}
+		}
+		b.AuthorIDs = ids
+	case models.EntityPublisher:
+		b.PublisherID = into
+	case models.EntitySeries:
+		b.SeriesID = into
+	case models.EntityGenre:
+		b.GenreID = into
+	}
+}
+
+// fillNames names b after the entities it refers to, so ids win over
+// names. An unknown id is invalid.
+func (s *EntityService) fillNames(ctx context.Context, b *models.Book) error {
+	if len(b.AuthorIDs) > 0 {
+		b.Authors = make([]string, len(b.AuthorIDs))
+		for i, id := range b.AuthorIDs {
+			e, err := s.referred(ctx, models.EntityAuthor, "authorIDs", id)
+			if err != nil {
+				return err
+			}
+			b.Authors[i] = e.Name
+		}
+		b.Author = authorLine(b.Authors)
+	}
+	for _, ref := range singleRefs(b) {
+		if *ref.id != 0 {
+			e, err := s.referred(ctx, ref.kind, ref.field, *ref.id)
+			if err != nil {
+				return err
+			}
+			*ref.name = e.Name
+		}
+	}
+	return nil
+}
+
+func (s *EntityService) referred(ctx context.Context, kind, field string, id int) (models.Entity, error) {
+	e, err := s.entities.Of(kind).GetByID(ctx, id)
+	if errors.Is(err, ErrNotFound) {
+		return e, invalid(field, fmt.Sprintf("no %s has id %d", models.EntityNoun(kind), id))
+	}
+	return e, err
+}
+
+// fillIDs points b at the entities named by its names without ids, adding
+// those missing. Names are matched by slug, so "J. R. R. Tolkien" finds
+// "J.R.R. Tolkien", and take the entity's spelling.
+func (s *EntityService) fillIDs(ctx context.Context, b *models.Book) error {
+	if len(b.AuthorIDs) != len(b.Authors) {
+		b.AuthorIDs = make([]int, len(b.Authors))
+		for i, name := range b.Authors {
+			e, err := s.ensure(ctx, models.EntityAuthor, name)
+			if err != nil {
+				return err
+			}
+			b.AuthorIDs[i], b.Authors[i] = e.ID, e.Name
+		}
+		if len(b.Authors) > 0 {
+			b.Author = authorLine(b.Authors)
+		}
+	}
+	for _, ref := range singleRefs(b) {
+		if *ref.id == 0 && *ref.name != "" {
+			e, err := s.ensure(ctx, ref.kind, *ref.name)
+			if err != nil {
+				return err
+			}
+			*ref.id, *ref.name = e.ID, e.Name
+		}
+	}
+	return nil
+}
+
+// ensure finds the entity of kind named name, creating it if there is none.
+func (s *EntityService) ensure(ctx context.Context, kind, name string) (models.Entity, error) {
+	repo := s.entities.Of(kind)
+	sl := slug.Make(name)
+	e, err := repo.GetBySlug(ctx, sl)
+	if !errors.Is(err, ErrNotFound) {
+		return e, err
+	}
+	e, err = repo.Create(ctx, models.Entity{Name: name, Slug: sl})
+	if errors.Is(err, ErrConflict) {
+		return repo.GetBySlug(ctx, sl) // created meanwhile
+	}
+	return e, err
+}
+
+// singleRef is one of the book's references to a single entity.
+type singleRef struct {
+	kind, field string
+	id          *int
+	name        *string
+}
+
+func singleRefs(b *models.Book) []singleRef {
+	return []singleRef{
+		{models.EntityPublisher, "publisherID", &b.PublisherID, &b.Publisher},
+		{models.EntitySeries, "seriesID", &b.SeriesID, &b.Series},
+		{models.EntityGenre, "genreID", &b.GenreID, &b.Genre},
+	}
+}
@@ -0,0 +1,68 @@
+package logic
+
+import (
+	"errors"
+	"strings"
+
+	"bookstore/internal/repository"
+	"bookstore/internal/validate"
+)
+
+var (
+	ErrNotFound     = repository.ErrNotFound
+	ErrConflict     = repository.ErrConflict
+	ErrValidation   = repository.ErrInvalid
+	ErrForbidden    = errors.New("forbidden")
+	ErrUnauthorized = errors.New("invalid credentials")
+)
+
+type FieldError = validate.FieldError
+
+// ValidationError collects every invalid field of an input at once.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		msgs = append(msgs, f.Message)
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func (e *ValidationError) Unwrap() error { return ErrValidation }
+
+// check runs the declarative rules of an input and reports every failing
+// field at once.
+func check(in any) error {
+	if fields := validate.Struct(in); len(fields) > 0 {
+		return &ValidationError{Fields: fields}
+	}
+	return nil
+}
+
+func invalid(field, msg string) error {
+	return &ValidationError{Fields: []FieldError{{Field: field, Message: msg}}}
+}
+
+func notFound(msg string) error {
+	return repository.NotFound(msg)
+}
+
+func conflict(msg string) error {
+	return repository.Conflict(msg)
+}
+
+// FieldErrors extracts the per-field problems of a validation error, if any.
+func FieldErrors(err error) []FieldError {
+	var ve *ValidationError
+	if errors.As(err, &ve) {
+		return ve.Fields
+	}
+	var re *repository.Error
+	if errors.As(err, &re) && re.Field != "" {
+		return []FieldError{{Field: re.Field, Message: re.Msg}}
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package logic
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+
+	"bookstore/internal/logging"
+)
+
+const (
+	ImportQueued  = "queued"
+	ImportRunning = "running"
+	ImportDone    = "done"
+	ImportFailed  = "failed"
+)
+
+// importJobTTL is how long a finished import stays visible to pollers.
+const importJobTTL = 24 * time.Hour
+
+// ImportJob is a catalog import running in the background. Jobs are kept in
+// memory only, so a restart forgets them along with any that were running.
+type ImportJob struct {
+	ID         int           `json:"id"`
+	Status     string        `json:"status"`
+	Format     string        `json:"format"`
+	DryRun     bool          `json:"dryRun"`
+	Source     string        `json:"source,omitempty"` // file name, for imports from the watched directory
+	Report     *ImportReport `json:"report,omitempty"`
+	Error      string        `json:"error,omitempty"`
+	CreatedAt  time.Time     `json:"createdAt"`
+	FinishedAt *time.Time    `json:"finishedAt,omitempty"`
+}
+
+type ImportJobs struct {
+	books *BookService
+
+	mu     sync.Mutex
+	nextID int
+	jobs   map[int]*ImportJob
+}
+
+func NewImportJobs(books *BookService) *ImportJobs {
+	return &ImportJobs{books: books, jobs: map[int]*ImportJob{}}
+}
+
+// Start imports the file at path in the background and removes it when done.
+// The job outlives the request that started it but keeps its request id.
+func (j *ImportJobs) Start(ctx context.Context, path, format string, dryRun bool) ImportJob {
+	job := j.add(format, dryRun, "")
+	snapshot := j.snapshot(job)
+
+	go func() {
+		defer os.Remove(path)
+		j.run(context.WithoutCancel(ctx), job, path)
+	}()
+	return snapshot
+}
+
+func (j *ImportJobs) add(format string, dryRun bool, source string) *ImportJob {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.prune(time.Now())
+	j.nextID++
+	job := &ImportJob{ID: j.nextID, Status: ImportQueued, Format: format, DryRun: dryRun, Source: source, CreatedAt: time.Now()}
+	j.jobs[job.ID] = job
+	return job
+}
+
+func (j *ImportJobs) snapshot(job *ImportJob) ImportJob {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return *job
+}
+
+func (j *ImportJobs) run(ctx context.Context, job *ImportJob, path string) (ImportReport, error) {
+	log := logging.From(ctx).With("import_id", job.ID, "format", job.Format, "dry_run", job.DryRun)
+	if job.Source != "" {
+		log = log.With("source", job.Source)
+	}
+
+	j.update(job, func() { job.Status = ImportRunning })
+
+	report, err := j.importFile(ctx, path, job.Format, job.DryRun)
+	j.update(job, func() {
+		now := time.Now()
+		job.FinishedAt = &now
+		job.Report = &report
+		job.Status = ImportDone
+		if err != nil {
+			job.Status = ImportFailed
+			job.Error = err.Error()
+		}
+	})
+
+	if err != nil {
+		log.Error("book import failed", "rows", report.Total, "err", err)
+		return report, err
+	}
+	log.Info("book import finished", "created", report.Created, "updated", report.Updated,
+		"deleted", report.Deleted, "failed", report.Failed, "unmapped", len(report.Unmapped))
+	return report, nil
+}
+
+func (j *ImportJobs) importFile(ctx context.Context, path, format string, dryRun bool) (ImportReport, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return ImportReport{DryRun: dryRun, Rows: []ImportRow{}}, err
+	}
+	defer f.Close()
+	return j.books.ImportBooks(ctx, f, format, dryRun)
+}
+
+func (j *ImportJobs) update(job *ImportJob, fn func()) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	fn()
+}
+
+func (j *ImportJobs) Get(id int) (ImportJob, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	job, ok := j.jobs[id]
+	if !ok {
+		return ImportJob{}, notFound("import not found")
+	}
+	return *job, nil
+}
+
+// prune forgets finished jobs older than importJobTTL. The caller holds mu.
+func (j *ImportJobs) prune(now time.Time) {
+	for id, job := range j.jobs {
+		if job.FinishedAt != nil && now.Sub(*job.FinishedAt) > importJobTTL {
+			delete(j.jobs, id)
+		}
+	}
+}
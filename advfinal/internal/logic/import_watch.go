@@ -0,0 +1,102 @@
+package logic
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"bookstore/internal/logging"
+)
+
+// Watch imports the catalog files dropped into dir, one at a time, until ctx
+// ends. The format comes from the extension: .csv, .json, or .xml and .onix
+// for ONIX. A file is only picked up once it has not changed for a whole
+// interval, so uploads still in progress are left alone, and names starting
+// with a dot are ignored.
+//
+// Imported files move to dir/done and files that could not be read to
+// dir/failed, each with a <name>.report.json next to it. A file whose import
+// was interrupted by shutdown stays put and is imported again on restart.
+func (j *ImportJobs) Watch(ctx context.Context, dir string, interval time.Duration) {
+	log := logging.From(ctx).With("import_dir", dir)
+	for _, sub := range []string{"done", "failed"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o755); err != nil {
+			log.Error("import directory unusable, not watching", "err", err)
+			return
+		}
+	}
+	log.Info("watching for catalog imports", "interval", interval)
+
+	stuck := map[string]bool{} // imported but could not be moved away
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		j.scan(ctx, log, dir, interval, stuck)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (j *ImportJobs) scan(ctx context.Context, log *slog.Logger, dir string, settle time.Duration, stuck map[string]bool) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		log.Error("read import directory", "err", err)
+		return
+	}
+
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || strings.HasPrefix(name, ".") || stuck[name] {
+			continue
+		}
+		format, err := ParseFormat(filepath.Ext(name))
+		if err != nil {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil || time.Since(info.ModTime()) < settle {
+			continue
+		}
+		if ctx.Err() != nil {
+			return
+		}
+
+		path := filepath.Join(dir, name)
+		job := j.add(format, false, name)
+		report, err := j.run(ctx, job, path)
+		if ctx.Err() != nil {
+			return // interrupted: leave the file for the next run
+		}
+
+		dest := "done"
+		if err != nil {
+			dest = "failed"
+		}
+		// A timestamp keeps repeated deliveries of the same name apart.
+		target := filepath.Join(dir, dest, time.Now().UTC().Format("20060102T150405")+"-"+name)
+		if err := os.Rename(path, target); err != nil {
+			log.Error("move imported file", "file", name, "err", err)
+			stuck[name] = true
+			continue
+		}
+		if err := writeReport(target+".report.json", j.snapshot(job), report); err != nil {
+			log.Error("write import report", "file", name, "err", err)
+		}
+	}
+}
+
+func writeReport(path string, job ImportJob, report ImportReport) error {
+	job.Report = &report
+	data, err := json.MarshalIndent(job, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
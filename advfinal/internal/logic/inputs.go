@@ -0,0 +1,106 @@
+package logic
+
+// Request bodies accepted by the services. Handlers decode JSON straight into
+// these and the services validate them with check, so the API and the HTML
+// forms share the same rules.
+
+type Credentials struct {
+	Email    string `json:"email" validate:"required,max=254"`
+	Password string `json:"password" validate:"required,max=72"`
+}
+
+type Registration struct {
+	Email    string `json:"email" validate:"required,email,max=254"`
+	Password string `json:"password" validate:"password"`
+}
+
+type ProfileInput struct {
+	Name  string `json:"name" validate:"max=100"`
+	Phone string `json:"phone" validate:"phone,max=32"`
+}
+
+type PasswordChange struct {
+	CurrentPassword string `json:"currentPassword" validate:"required"`
+	NewPassword     string `json:"newPassword" validate:"password"`
+}
+
+type EmailChange struct {
+	CurrentPassword string `json:"currentPassword" validate:"required"`
+	Email           string `json:"email" validate:"required,email,max=254"`
+}
+
+type AccountDeletion struct {
+	Password string `json:"password" validate:"required"`
+}
+
+// ItemInput adds a book to a cart or wishlist.
+type ItemInput struct {
+	BookID int `json:"bookId" validate:"min=1"`
+	Qty    int `json:"qty" validate:"min=1,max=99"`
+}
+
+type QtyInput struct {
+	Qty int `json:"qty" validate:"min=1,max=99"`
+}
+
+type OrderInput struct {
+	CartID int `json:"cartId" validate:"min=1"`
+}
+
+type WishlistInput struct {
+	CustomerID int `json:"customerId" validate:"min=0"`
+}
+
+// ReviewInput creates a review; BookID is ignored when editing one.
+type ReviewInput struct {
+	BookID int    `json:"bookId" validate:"min=1"`
+	Rating int    `json:"rating" validate:"min=1,max=5"`
+	Title  string `json:"title" validate:"max=120"`
+	Body   string `json:"body" validate:"required,max=5000"`
+}
+
+type ReviewModeration struct {
+	Status string `json:"status" validate:"required"`
+	Note   string `json:"note" validate:"max=500"`
+}
+
+// SearchInput is a catalog search, read from the query string. Q may be
+// empty to browse by the filters alone.
+type SearchInput struct {
+	Q      string `json:"q" validate:"max=200"`
+	Genre  string `json:"genre" validate:"max=60"`
+	Author string `json:"author" validate:"max=120"`
+	Price  string `json:"price"` // a price band key, such as 10-20
+	Offset int    `json:"offset" validate:"min=0,max=10000"`
+	Limit  int    `json:"limit" validate:"min=0,max=50"`
+}
+
+// SuggestInput is what a shopper has typed into the search box so far.
+type SuggestInput struct {
+	Q     string `json:"q" validate:"max=100"`
+	Limit int    `json:"limit" validate:"min=0,max=10"`
+}
+
+// EntityInput creates or renames an author, publisher, series or genre.
+type EntityInput struct {
+	Name        string `json:"name" validate:"required,max=120"`
+	Description string `json:"description" validate:"max=5000"`
+}
+
+// MergeInput names the entity or category merged into the one addressed.
+type MergeInput struct {
+	From int `json:"from" validate:"min=1"`
+}
+
+// CategoryInput creates or renames a category. ParentID places a new one
+// and is ignored when editing; MoveInput moves a category.
+type CategoryInput struct {
+	Name        string `json:"name" validate:"required,max=120"`
+	ParentID    int    `json:"parentId" validate:"min=0"`
+	Description string `json:"description" validate:"max=5000"`
+}
+
+// MoveInput names a category's new parent, 0 for the top of the tree.
+type MoveInput struct {
+	ParentID int `json:"parentId" validate:"min=0"`
+}
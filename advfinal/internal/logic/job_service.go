@@ -0,0 +1,58 @@
+package logic
+
+import (
+	"context"
+	"time"
+
+	"bookstore/internal/logging"
+	"bookstore/internal/models"
+	"bookstore/internal/repository"
+)
+
+// JobService manages order jobs that failed in the workers. Built without a
+// cart repository (as in the admin CLI) it cannot retry cart jobs.
+type JobService struct {
+	failed       repository.FailedJobRepository
+	cartRepo     repository.CartRepository
+	wishlistRepo repository.WishlistRepository
+}
+
+func NewJobService(failed repository.FailedJobRepository, cartRepo repository.CartRepository, wishlistRepo repository.WishlistRepository) *JobService {
+	return &JobService{failed: failed, cartRepo: cartRepo, wishlistRepo: wishlistRepo}
+}
+
+func (s *JobService) FailedJobs(ctx context.Context) []models.FailedJob {
+	return s.failed.GetAll(ctx)
+}
+
+// RetryJob runs a failed job again. On success the record is removed;
+// otherwise its error and attempt count are updated and the error returned.
+func (s *JobService) RetryJob(ctx context.Context, id int) error {
+	fj, err := s.failed.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	job := OrderJob{
+		Type:       OrderJobType(fj.Type),
+		OrderID:    fj.OrderID,
+		CartID:     fj.CartID,
+		WishlistID: fj.WishlistID,
+		RequestID:  fj.RequestID,
+	}
+	log := logging.From(ctx).With("failed_job_id", fj.ID, "job_type", fj.Type, "order_id", fj.OrderID)
+	if err := runOrderJob(ctx, log, job, s.cartRepo, s.wishlistRepo); err != nil {
+		fj.Error = err.Error()
+		fj.Attempts++
+		fj.FailedAt = time.Now()
+		if uerr := s.failed.Update(ctx, fj); uerr != nil {
+			return uerr
+		}
+		return err
+	}
+	return s.failed.Delete(ctx, id)
+}
+
+func (s *JobService) DiscardJob(ctx context.Context, id int) error {
+	return s.failed.Delete(ctx, id)
+}
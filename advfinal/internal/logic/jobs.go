@@ -1,9 +1,19 @@
 package logic
 
 import (
-	"log"
+	"context"
+	"errors"
+	"log/slog"
+	"sync/atomic"
+	"time"
 
+	"bookstore/internal/logging"
+	"bookstore/internal/metrics"
+	"bookstore/internal/models"
 	"bookstore/internal/repository"
+	"bookstore/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type OrderJobType string
@@ -19,42 +29,156 @@ type OrderJob struct {
 	OrderID    int
 	CartID     int
 	WishlistID int
+	RequestID  string            // request that produced the job, for joining logs
+	Trace      map[string]string // propagated trace context, see tracing.Inject
+}
+
+// OrderJobQueue is created by StartOrderWorkerPool.
+var OrderJobQueue chan OrderJob
+
+// orderPool counts running and busy order workers for the readiness probe.
+var orderPool struct {
+	workers atomic.Int32
+	busy    atomic.Int32
 }
 
-var OrderJobQueue = make(chan OrderJob, 100)
+type PoolStatus struct {
+	Workers  int `json:"workers"`
+	Busy     int `json:"busy"`
+	Backlog  int `json:"backlog"`
+	Capacity int `json:"capacity"`
+}
 
-func StartOrderWorkerPool(workerCount int, cartRepo repository.CartRepository, wishlistRepo repository.WishlistRepository) {
-	log.Printf("[ORDER WORKERS] starting %d workers...\n", workerCount)
+func OrderPoolStatus() PoolStatus {
+	return PoolStatus{
+		Workers:  int(orderPool.workers.Load()),
+		Busy:     int(orderPool.busy.Load()),
+		Backlog:  len(OrderJobQueue),
+		Capacity: cap(OrderJobQueue),
+	}
+}
+
+// newOrderJob stamps the job with the request id and trace context of ctx.
+func newOrderJob(ctx context.Context, job OrderJob) OrderJob {
+	job.RequestID = logging.RequestID(ctx)
+	job.Trace = tracing.Inject(ctx)
+	return job
+}
+
+// enqueue hands a job to the workers without blocking the request. When the
+// queue is full, or was never created, the job runs in the request instead:
+// the order it follows up on is already placed, so it must not be lost.
+func enqueue(ctx context.Context, job OrderJob, cartRepo repository.CartRepository, wishlistRepo repository.WishlistRepository) {
+	job = newOrderJob(ctx, job)
+	select {
+	case OrderJobQueue <- job:
+		return
+	default:
+	}
+
+	log := logging.From(ctx).With("job_type", job.Type, "order_id", job.OrderID)
+	log.Warn("order queue full, running job in the request")
+	err := runOrderJob(ctx, log, job, cartRepo, wishlistRepo)
+	metrics.JobDone(string(job.Type), err)
+}
+
+// StartOrderWorkerPool creates OrderJobQueue and starts its workers. Jobs
+// that fail are recorded in failedJobs for an operator to retry.
+func StartOrderWorkerPool(workerCount, queueSize int, cartRepo repository.CartRepository, wishlistRepo repository.WishlistRepository, failedJobs repository.FailedJobRepository) {
+	OrderJobQueue = make(chan OrderJob, queueSize)
+	slog.Info("starting order workers", "count", workerCount, "queue_size", queueSize)
 
 	for i := 1; i <= workerCount; i++ {
 		go func(workerID int) {
-			log.Printf("[ORDER WORKER %d] started\n", workerID)
+			slog.Debug("order worker started", "worker", "order", "worker_id", workerID)
+			orderPool.workers.Add(1)
+			defer orderPool.workers.Add(-1)
 
 			for job := range OrderJobQueue {
-				log.Printf("[ORDER WORKER %d] got job: type=%s orderId=%d cartId=%d wishlistId=%d\n",
-					workerID, job.Type, job.OrderID, job.CartID, job.WishlistID)
-
-				switch job.Type {
-				case JobClearCart:
-					if err := cartRepo.ClearCart(job.CartID); err != nil {
-						log.Printf("[ORDER WORKER %d] ClearCart failed: %v\n", workerID, err)
-					} else {
-						log.Printf("[ORDER WORKER %d] cart cleared: cartId=%d\n", workerID, job.CartID)
-					}
+				orderPool.busy.Add(1)
+				ctx := logging.WithRequestID(tracing.Extract(context.Background(), job.Trace), job.RequestID)
+				ctx, span := tracing.Start(ctx, "OrderJob "+string(job.Type),
+					attribute.String("job.type", string(job.Type)),
+					attribute.Int("order.id", job.OrderID),
+				)
+				log := logging.From(ctx).With(
+					"worker", "order",
+					"worker_id", workerID,
+					"job_type", job.Type,
+					"order_id", job.OrderID,
+				)
 
-				case JobClearWishlist:
-					if err := wishlistRepo.Delete(job.WishlistID); err != nil {
-						log.Printf("[ORDER WORKER %d] Delete wishlist failed: %v\n", workerID, err)
-					} else {
-						log.Printf("[ORDER WORKER %d] wishlist cleared: wishlistId=%d\n", workerID, job.WishlistID)
+				err := runOrderJob(ctx, log, job, cartRepo, wishlistRepo)
+				if err != nil {
+					if _, rerr := failedJobs.Create(ctx, failedJob(job, err)); rerr != nil {
+						log.Error("record failed job", "err", rerr)
 					}
-				case JobAuditOrderCreated:
-					log.Printf("[ORDER WORKER %d] audit: order created orderId=%d\n", workerID, job.OrderID)
-
-				default:
-					log.Printf("[ORDER WORKER %d] unknown job type: %s\n", workerID, job.Type)
 				}
+				metrics.JobDone(string(job.Type), err)
+				tracing.End(span, err)
+				orderPool.busy.Add(-1)
 			}
 		}(i)
 	}
 }
+
+// errCartsInServer is returned for cart jobs run outside the server process,
+// which cannot reach the in-memory carts.
+var errCartsInServer = errors.New("cart jobs can only run in the server: carts are kept in its memory")
+
+// runOrderJob performs one job. cartRepo is nil outside the server.
+func runOrderJob(ctx context.Context, log *slog.Logger, job OrderJob, cartRepo repository.CartRepository, wishlistRepo repository.WishlistRepository) error {
+	switch job.Type {
+	case JobClearCart:
+		if cartRepo == nil {
+			return errCartsInServer
+		}
+		if err := cartRepo.ClearCart(ctx, job.CartID); err != nil {
+			log.Error("clear cart failed", "cart_id", job.CartID, "err", err)
+			return err
+		}
+		log.Info("cart cleared", "cart_id", job.CartID)
+
+	case JobClearWishlist:
+		if err := wishlistRepo.Delete(ctx, job.WishlistID); err != nil {
+			log.Error("delete wishlist failed", "wishlist_id", job.WishlistID, "err", err)
+			return err
+		}
+		log.Info("wishlist cleared", "wishlist_id", job.WishlistID)
+
+	case JobAuditOrderCreated:
+		log.Info("audit: order created", "cart_id", job.CartID)
+
+	default:
+		log.Warn("unknown order job type")
+		return errors.New("unknown job type")
+	}
+	return nil
+}
+
+func failedJob(job OrderJob, err error) models.FailedJob {
+	return models.FailedJob{
+		Type:       string(job.Type),
+		OrderID:    job.OrderID,
+		CartID:     job.CartID,
+		WishlistID: job.WishlistID,
+		RequestID:  job.RequestID,
+		Error:      err.Error(),
+		Attempts:   1,
+		FailedAt:   time.Now(),
+	}
+}
+
+// CheckOrderWorkers is a readiness check: it fails when no worker is running
+// or the queue is full, since new orders would then run their follow-up jobs
+// in the request.
+func CheckOrderWorkers(ctx context.Context) (any, error) {
+	st := OrderPoolStatus()
+	switch {
+	case st.Workers == 0:
+		return st, errors.New("no order workers running")
+	case st.Backlog >= st.Capacity:
+		return st, errors.New("order job queue is full")
+	}
+	return st, nil
+}
@@ -1,7 +1,7 @@
 package logic
 
 import (
-	"errors"
+	"context"
 
 	"bookstore/internal/models"
 	"bookstore/internal/repository"
@@ -15,24 +15,31 @@ func NewOrderCRUDService(repo repository.OrderRepository) *OrderCRUDService {
 	return &OrderCRUDService{repo: repo}
 }
 
-func (s *OrderCRUDService) ListOrders() []models.Order {
-	return s.repo.GetAll()
+func (s *OrderCRUDService) ListOrders(ctx context.Context) []models.Order {
+	return s.repo.GetAll(ctx)
 }
 
-func (s *OrderCRUDService) GetOrder(id int) (models.Order, []models.OrderItem, error) {
-	return s.repo.GetByID(id)
+func (s *OrderCRUDService) GetOrder(ctx context.Context, id int) (models.Order, []models.OrderItem, error) {
+	return s.repo.GetByID(ctx, id)
 }
 
-func (s *OrderCRUDService) UpdateOrder(o models.Order) error {
+func (s *OrderCRUDService) UpdateOrder(ctx context.Context, o models.Order) error {
 	if o.ID <= 0 {
-		return errors.New("order id must be positive")
+		return invalid("id", "order id must be positive")
 	}
-	if o.Total < 0 {
-		return errors.New("total cannot be negative")
+	if err := check(o); err != nil {
+		return err
 	}
-	return s.repo.Update(o)
+	return s.repo.Update(ctx, o)
 }
 
-func (s *OrderCRUDService) DeleteOrder(id int) error {
-	return s.repo.Delete(id)
+func (s *OrderCRUDService) CancelOrder(ctx context.Context, id int) (models.Order, error) {
+	if id <= 0 {
+		return models.Order{}, invalid("id", "order id must be positive")
+	}
+	return s.repo.Cancel(ctx, id)
+}
+
+func (s *OrderCRUDService) DeleteOrder(ctx context.Context, id int) error {
+	return s.repo.Delete(ctx, id)
 }
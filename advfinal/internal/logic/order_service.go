@@ -1,10 +1,16 @@
 package logic
 
 import (
-	"errors"
+	"context"
+	"time"
 
+	"bookstore/internal/logging"
+	"bookstore/internal/metrics"
 	"bookstore/internal/models"
 	"bookstore/internal/repository"
+	"bookstore/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type OrderService struct {
@@ -17,20 +23,24 @@ func NewOrderService(repo repository.OrderRepository, bookRepo repository.BookRe
 	return &OrderService{repo: repo, bookRepo: bookRepo, cartRepo: cartRepo}
 }
 
-func (s *OrderService) CreateOrderFromCart(customerID int, cartID int) (models.Order, []models.OrderItem, error) {
+func (s *OrderService) CreateOrderFromCart(ctx context.Context, customerID int, cartID int) (_ models.Order, _ []models.OrderItem, err error) {
+	ctx, span := tracing.Start(ctx, "OrderService.CreateOrderFromCart",
+		attribute.Int("customer.id", customerID), attribute.Int("cart.id", cartID))
+	defer func() { tracing.End(span, err) }()
+
 	if customerID <= 0 {
-		return models.Order{}, nil, errors.New("customerId must be positive")
+		return models.Order{}, nil, invalid("customerId", "customerId must be positive")
 	}
-	if cartID <= 0 {
-		return models.Order{}, nil, errors.New("cartId must be positive")
+	if err := check(OrderInput{CartID: cartID}); err != nil {
+		return models.Order{}, nil, err
 	}
 
-	_, cartItems, err := s.cartRepo.GetByID(cartID)
+	_, cartItems, err := s.cartRepo.GetByID(ctx, cartID)
 	if err != nil {
 		return models.Order{}, nil, err
 	}
 	if len(cartItems) == 0 {
-		return models.Order{}, nil, errors.New("cart is empty")
+		return models.Order{}, nil, invalid("cartId", "cart is empty")
 	}
 
 	items := make([]models.OrderItem, 0, len(cartItems))
@@ -38,15 +48,15 @@ func (s *OrderService) CreateOrderFromCart(customerID int, cartID int) (models.O
 
 	for _, ci := range cartItems {
 		if ci.BookID <= 0 {
-			return models.Order{}, nil, errors.New("invalid bookId in cart")
+			return models.Order{}, nil, invalid("bookId", "invalid bookId in cart")
 		}
 		if ci.Qty <= 0 {
-			return models.Order{}, nil, errors.New("invalid qty in cart")
+			return models.Order{}, nil, invalid("qty", "invalid qty in cart")
 		}
 
-		b, err := s.bookRepo.GetByID(ci.BookID)
+		b, err := s.bookRepo.GetByID(ctx, ci.BookID)
 		if err != nil {
-			return models.Order{}, nil, errors.New("book not found")
+			return models.Order{}, nil, notFound("book not found")
 		}
 
 		items = append(items, models.OrderItem{
@@ -62,21 +72,21 @@ func (s *OrderService) CreateOrderFromCart(customerID int, cartID int) (models.O
 		CustomerID: customerID,
 		CartID:     cartID,
 		Total:      total,
+		Status:     models.OrderPlaced,
+		CreatedAt:  time.Now(),
 	}
 
-	createdOrder, createdItems, err := s.repo.Create(order, items)
+	createdOrder, createdItems, err := s.repo.Create(ctx, order, items)
 	if err != nil {
 		return models.Order{}, nil, err
 	}
 
-	select {
-	case OrderJobQueue <- OrderJob{Type: JobAuditOrderCreated, OrderID: createdOrder.ID, CartID: cartID}:
-	default:
-	}
-	select {
-	case OrderJobQueue <- OrderJob{Type: JobClearCart, OrderID: createdOrder.ID, CartID: cartID}:
-	default:
-	}
+	metrics.OrderCreated("cart", total)
+	logging.From(ctx).Info("order created",
+		"order_id", createdOrder.ID, "customer_id", customerID, "cart_id", cartID, "total", total)
+
+	enqueue(ctx, OrderJob{Type: JobAuditOrderCreated, OrderID: createdOrder.ID, CartID: cartID}, s.cartRepo, nil)
+	enqueue(ctx, OrderJob{Type: JobClearCart, OrderID: createdOrder.ID, CartID: cartID}, s.cartRepo, nil)
 
 	return createdOrder, createdItems, nil
 }
@@ -0,0 +1,230 @@
+package logic
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"bookstore/internal/models"
+	"bookstore/internal/repository"
+)
+
+// DataExport is everything the store keeps about a single customer.
+type DataExport struct {
+	GeneratedAt  time.Time        `json:"generatedAt"`
+	Profile      map[string]any   `json:"profile"`
+	Carts        []CartExport     `json:"carts"`
+	Orders       []OrderExport    `json:"orders"`
+	Wishlists    []WishlistExport `json:"wishlists"`
+	LoginHistory []models.Session `json:"loginHistory"`
+	Reviews      []models.Review  `json:"reviews"`
+}
+
+type CartExport struct {
+	Cart  models.Cart       `json:"cart"`
+	Items []models.CartItem `json:"items"`
+}
+
+type OrderExport struct {
+	Order models.Order       `json:"order"`
+	Items []models.OrderItem `json:"items"`
+}
+
+type WishlistExport struct {
+	Wishlist models.Wishlist       `json:"wishlist"`
+	Items    []models.WishlistItem `json:"items"`
+}
+
+type PrivacyService struct {
+	users     repository.UserRepository
+	sessions  repository.SessionRepository
+	cartRepo  repository.CartRepository
+	orderRepo repository.OrderRepository
+	wRepo     repository.WishlistRepository
+	reviews   repository.ReviewRepository
+}
+
+func NewPrivacyService(
+	users repository.UserRepository,
+	sessions repository.SessionRepository,
+	cartRepo repository.CartRepository,
+	orderRepo repository.OrderRepository,
+	wRepo repository.WishlistRepository,
+	reviews repository.ReviewRepository,
+) *PrivacyService {
+	return &PrivacyService{
+		users:     users,
+		sessions:  sessions,
+		cartRepo:  cartRepo,
+		orderRepo: orderRepo,
+		wRepo:     wRepo,
+		reviews:   reviews,
+	}
+}
+
+func (s *PrivacyService) Export(ctx context.Context, userID int) (DataExport, error) {
+	u, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return DataExport{}, err
+	}
+
+	out := DataExport{
+		GeneratedAt: time.Now().UTC(),
+		Profile: map[string]any{
+			"id":        u.ID,
+			"email":     u.Email,
+			"role":      u.Role,
+			"name":      u.Name,
+			"phone":     u.Phone,
+			"address":   u.Address,
+			"addresses": u.Addresses,
+			"createdAt": u.CreatedAt,
+		},
+		Carts:        []CartExport{},
+		Orders:       []OrderExport{},
+		Wishlists:    []WishlistExport{},
+		LoginHistory: s.sessions.ListByUser(ctx, userID),
+		Reviews:      s.reviews.ListByUser(ctx, userID),
+	}
+
+	for _, c := range s.cartRepo.GetAll(ctx) {
+		if c.CustomerID != userID {
+			continue
+		}
+		_, items, err := s.cartRepo.GetByID(ctx, c.ID)
+		if err != nil {
+			continue
+		}
+		out.Carts = append(out.Carts, CartExport{Cart: c, Items: items})
+	}
+
+	for _, o := range s.orderRepo.GetByCustomer(ctx, userID) {
+		_, items, err := s.orderRepo.GetByID(ctx, o.ID)
+		if err != nil {
+			return DataExport{}, err
+		}
+		out.Orders = append(out.Orders, OrderExport{Order: o, Items: items})
+	}
+
+	for _, wl := range s.wRepo.GetAll(ctx) {
+		if wl.CustomerID != userID {
+			continue
+		}
+		_, items, err := s.wRepo.GetByID(ctx, wl.ID)
+		if err != nil {
+			continue
+		}
+		out.Wishlists = append(out.Wishlists, WishlistExport{Wishlist: wl, Items: items})
+	}
+
+	return out, nil
+}
+
+// WriteZip writes the export as a ZIP archive with one JSON file per section.
+func (s *PrivacyService) WriteZip(w io.Writer, export DataExport) error {
+	zw := zip.NewWriter(w)
+
+	files := []struct {
+		name string
+		data any
+	}{
+		{"profile.json", export.Profile},
+		{"carts.json", export.Carts},
+		{"orders.json", export.Orders},
+		{"wishlists.json", export.Wishlists},
+		{"login_history.json", export.LoginHistory},
+		{"reviews.json", export.Reviews},
+	}
+
+	for _, f := range files {
+		fw, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     f.name,
+			Method:   zip.Deflate,
+			Modified: export.GeneratedAt,
+		})
+		if err != nil {
+			return err
+		}
+		enc := json.NewEncoder(fw)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(f.data); err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
+
+// DeleteAccount erases the personal data of a user. Orders and their items
+// are kept for accounting, and reviews because they never name their author;
+// both only reference the now anonymous user id.
+func (s *PrivacyService) DeleteAccount(ctx context.Context, userID int) error {
+	u, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if u.DeletedAt != nil {
+		return conflict("account already deleted")
+	}
+
+	for _, c := range s.cartRepo.GetAll(ctx) {
+		if c.CustomerID == userID {
+			_ = s.cartRepo.Delete(ctx, c.ID)
+		}
+	}
+	for _, wl := range s.wRepo.GetAll(ctx) {
+		if wl.CustomerID == userID {
+			if err := s.wRepo.Delete(ctx, wl.ID); err != nil {
+				return err
+			}
+		}
+	}
+	if err := s.sessions.DeleteByUser(ctx, userID); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	anon := models.User{
+		ID:        u.ID,
+		Email:     fmt.Sprintf("deleted-user-%d@invalid", u.ID),
+		Role:      "deleted",
+		CreatedAt: u.CreatedAt,
+		DeletedAt: &now,
+	}
+	return s.users.Update(ctx, anon)
+}
+
+// AdminDeleteAccount is the admin flow. Admins cannot delete themselves
+// this way, nor other admins, so that the store always keeps one.
+func (s *PrivacyService) AdminDeleteAccount(ctx context.Context, adminID, userID int) error {
+	if adminID == userID {
+		return fmt.Errorf("%w: use the account page to delete your own account", ErrForbidden)
+	}
+	u, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if u.Role == "admin" {
+		return fmt.Errorf("%w: admin accounts cannot be deleted", ErrForbidden)
+	}
+	return s.DeleteAccount(ctx, userID)
+}
+
+// DeleteAccountWithPassword is the self-service flow: the user has to
+// confirm with the current password.
+func (s *PrivacyService) DeleteAccountWithPassword(ctx context.Context, userID int, password string) error {
+	if err := check(AccountDeletion{Password: password}); err != nil {
+		return err
+	}
+	u, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !passwordMatches(u.Password, password) {
+		return invalid("password", "password is incorrect")
+	}
+	return s.DeleteAccount(ctx, userID)
+}
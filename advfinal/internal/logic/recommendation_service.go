@@ -0,0 +1,240 @@
+package logic
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"bookstore/internal/logging"
+	"bookstore/internal/models"
+	"bookstore/internal/repository"
+)
+
+const (
+	// wishlistWeight makes wishing for two books together count for half as
+	// much as ordering them together.
+	wishlistWeight = 0.5
+	// maxBasket skips the affinities of larger orders and wishlists, which
+	// say little about any pair and cost quadratic time. They still count
+	// towards popularity.
+	maxBasket   = 50
+	maxRelated  = 20
+	maxPopular  = 50
+	defaultRecs = 6
+	maxRecs     = 20
+)
+
+// RecommendationService suggests books from what customers order and wish
+// for together. Refresh computes the affinities in bulk; the lookups only
+// read the stored result and fall back to the most popular books.
+type RecommendationService struct {
+	recs      repository.RecommendationRepository
+	books     repository.BookRepository
+	orders    repository.OrderRepository
+	wishlists repository.WishlistRepository
+}
+
+func NewRecommendationService(
+	recs repository.RecommendationRepository,
+	books repository.BookRepository,
+	orders repository.OrderRepository,
+	wishlists repository.WishlistRepository,
+) *RecommendationService {
+	return &RecommendationService{recs: recs, books: books, orders: orders, wishlists: wishlists}
+}
+
+// RefreshReport sums up one Refresh.
+type RefreshReport struct {
+	Orders     int       `json:"orders"`
+	Wishlists  int       `json:"wishlists"`
+	Books      int       `json:"books"` // books with related books
+	ComputedAt time.Time `json:"computedAt"`
+}
+
+// Refresh recomputes every recommendation from the orders and wishlists.
+// Each pair of books in a basket adds the basket's weight to their
+// affinity, and each book in it to the book's popularity.
+func (s *RecommendationService) Refresh(ctx context.Context) (RefreshReport, error) {
+	var rep RefreshReport
+	affinity := map[int]map[int]float64{}
+	popularity := map[int]float64{}
+	collect := func(weight float64, count *int) func([]int) error {
+		return func(books []int) error {
+			*count++
+			for _, b := range books {
+				popularity[b] += weight
+			}
+			if len(books) > maxBasket {
+				return nil
+			}
+			for _, a := range books {
+				for _, b := range books {
+					if a == b {
+						continue
+					}
+					if affinity[a] == nil {
+						affinity[a] = map[int]float64{}
+					}
+					affinity[a][b] += weight
+				}
+			}
+			return nil
+		}
+	}
+	if err := s.recs.EachOrderBasket(ctx, collect(1, &rep.Orders)); err != nil {
+		return RefreshReport{}, err
+	}
+	if err := s.recs.EachWishlistBasket(ctx, collect(wishlistWeight, &rep.Wishlists)); err != nil {
+		return RefreshReport{}, err
+	}
+
+	recs := make([]models.Recommendation, 0, len(affinity)+1)
+	recs = append(recs, models.Recommendation{BookID: models.PopularBooks, Related: topScores(popularity, maxPopular)})
+	for id, related := range affinity {
+		recs = append(recs, models.Recommendation{BookID: id, Related: topScores(related, maxRelated)})
+	}
+	rep.Books = len(affinity)
+	rep.ComputedAt = time.Now().UTC().Truncate(time.Millisecond) // as stored by Mongo
+	if err := s.recs.ReplaceAll(ctx, recs, rep.ComputedAt); err != nil {
+		return RefreshReport{}, err
+	}
+	return rep, nil
+}
+
+// Run refreshes the recommendations now and then every interval until ctx
+// ends.
+func (s *RecommendationService) Run(ctx context.Context, interval time.Duration) {
+	log := logging.From(ctx)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if rep, err := s.Refresh(ctx); err != nil {
+			log.Error("refresh recommendations", "err", err)
+		} else {
+			log.Info("recommendations refreshed", "books", rep.Books, "orders", rep.Orders, "wishlists", rep.Wishlists)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ForBook returns up to limit books bought or wished for together with the
+// given one, topped up with popular books.
+func (s *RecommendationService) ForBook(ctx context.Context, bookID, limit int) ([]models.Book, error) {
+	if _, err := s.books.GetByID(ctx, bookID); err != nil {
+		return nil, err
+	}
+	return s.ForBooks(ctx, []int{bookID}, limit)
+}
+
+// ForUser recommends books related to everything the actor has ordered or
+// wished for, leaving those out.
+func (s *RecommendationService) ForUser(ctx context.Context, actor Actor, limit int) ([]models.Book, error) {
+	var seeds []int
+	for _, o := range s.orders.GetByCustomer(ctx, actor.UserID) {
+		if o.Status == models.OrderCancelled {
+			continue
+		}
+		_, items, err := s.orders.GetByID(ctx, o.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, it := range items {
+			seeds = append(seeds, it.BookID)
+		}
+	}
+	for _, wl := range s.wishlists.GetAll(ctx) {
+		if wl.CustomerID != actor.UserID {
+			continue
+		}
+		_, items, err := s.wishlists.GetByID(ctx, wl.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, it := range items {
+			seeds = append(seeds, it.BookID)
+		}
+	}
+	return s.ForBooks(ctx, seeds, limit)
+}
+
+// ForBooks recommends books related to any of bookIDs, such as the
+// contents of a cart, ranked by their summed affinity and leaving bookIDs
+// out.
+func (s *RecommendationService) ForBooks(ctx context.Context, bookIDs []int, limit int) ([]models.Book, error) {
+	if limit <= 0 {
+		limit = defaultRecs
+	}
+	limit = min(limit, maxRecs)
+
+	exclude := map[int]bool{}
+	scores := map[int]float64{}
+	for _, id := range bookIDs {
+		if exclude[id] {
+			continue
+		}
+		exclude[id] = true
+		rec, err := s.related(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range rec {
+			scores[r.BookID] += r.Score
+		}
+	}
+	popular, err := s.related(ctx, models.PopularBooks)
+	if err != nil {
+		return nil, err
+	}
+
+	out := []models.Book{}
+	for _, r := range append(topScores(scores, len(scores)), popular...) {
+		if len(out) == limit {
+			break
+		}
+		if exclude[r.BookID] {
+			continue
+		}
+		exclude[r.BookID] = true
+		b, err := s.books.GetByID(ctx, r.BookID)
+		if errors.Is(err, ErrNotFound) || b.Availability == models.BookUnavailable {
+			continue // deleted or withdrawn since the last refresh
+		}
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, b)
+	}
+	return out, nil
+}
+
+// related is the stored list for bookID, empty before the first refresh
+// or when the book has no affinities.
+func (s *RecommendationService) related(ctx context.Context, bookID int) ([]models.RelatedBook, error) {
+	rec, err := s.recs.Get(ctx, bookID)
+	if errors.Is(err, ErrNotFound) {
+		return nil, nil
+	}
+	return rec.Related, err
+}
+
+// topScores ranks scores, highest first and then by book id so refreshes
+// are stable, and keeps the first n.
+func topScores(scores map[int]float64, n int) []models.RelatedBook {
+	out := make([]models.RelatedBook, 0, len(scores))
+	for id, score := range scores {
+		out = append(out, models.RelatedBook{BookID: id, Score: score})
+	}
+	slices.SortFunc(out, func(a, b models.RelatedBook) int {
+		if c := cmp.Compare(b.Score, a.Score); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.BookID, b.BookID)
+	})
+	return out[:min(n, len(out))]
+}
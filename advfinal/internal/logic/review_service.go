@@ -0,0 +1,246 @@
+package logic
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"bookstore/internal/logging"
+	"bookstore/internal/models"
+	"bookstore/internal/repository"
+)
+
+// ReviewService handles customer reviews. Only buyers of a book may review
+// it, and a review is public, and counted in the book's rating, once a
+// moderator approves it. Editing a review sends it back to moderation.
+type ReviewService struct {
+	reviews repository.ReviewRepository
+	books   repository.BookRepository
+	orders  repository.OrderRepository
+}
+
+func NewReviewService(
+	reviews repository.ReviewRepository,
+	books repository.BookRepository,
+	orders repository.OrderRepository,
+) *ReviewService {
+	return &ReviewService{reviews: reviews, books: books, orders: orders}
+}
+
+// ListForBook returns a book's approved reviews, most helpful first, or
+// newest first when sort is "newest".
+func (s *ReviewService) ListForBook(ctx context.Context, bookID int, sort string) ([]models.Review, error) {
+	if sort != "" && sort != "helpful" && sort != "newest" {
+		return nil, invalid("sort", "sort must be helpful or newest")
+	}
+	if _, err := s.books.GetByID(ctx, bookID); err != nil {
+		return nil, err
+	}
+	out := s.reviews.ListByBook(ctx, bookID, models.ReviewApproved)
+	if sort != "newest" {
+		slices.SortStableFunc(out, func(a, b models.Review) int { return b.Helpful - a.Helpful })
+	}
+	return out, nil
+}
+
+func (s *ReviewService) ListMine(ctx context.Context, actor Actor) []models.Review {
+	return s.reviews.ListByUser(ctx, actor.UserID)
+}
+
+// Pending is the moderation queue, oldest first.
+func (s *ReviewService) Pending(ctx context.Context) []models.Review {
+	return s.reviews.ListByStatus(ctx, models.ReviewPending)
+}
+
+// Get shows approved reviews to anyone and the others only to their author
+// and admins.
+func (s *ReviewService) Get(ctx context.Context, actor Actor, id int) (models.Review, error) {
+	rv, err := s.reviews.GetByID(ctx, id)
+	if err != nil {
+		return models.Review{}, err
+	}
+	if rv.Status != models.ReviewApproved && !actor.CanAccess(rv.UserID) {
+		return models.Review{}, notFound("review not found")
+	}
+	return rv, nil
+}
+
+// Mine returns the actor's review of a book, in any status.
+func (s *ReviewService) Mine(ctx context.Context, actor Actor, bookID int) (models.Review, error) {
+	return s.reviews.GetByBookAndUser(ctx, bookID, actor.UserID)
+}
+
+// Create adds the actor's review of a book they have ordered and not
+// cancelled. It waits for moderation.
+func (s *ReviewService) Create(ctx context.Context, actor Actor, in ReviewInput) (models.Review, error) {
+	in.Title, in.Body = strings.TrimSpace(in.Title), strings.TrimSpace(in.Body)
+	if err := check(in); err != nil {
+		return models.Review{}, err
+	}
+	if _, err := s.books.GetByID(ctx, in.BookID); err != nil {
+		return models.Review{}, err
+	}
+	bought, err := s.Purchased(ctx, actor, in.BookID)
+	if err != nil {
+		return models.Review{}, err
+	}
+	if !bought {
+		return models.Review{}, ErrForbidden
+	}
+
+	now := time.Now()
+	return s.reviews.Create(ctx, models.Review{
+		BookID:    in.BookID,
+		UserID:    actor.UserID,
+		Rating:    in.Rating,
+		Title:     in.Title,
+		Body:      in.Body,
+		Status:    models.ReviewPending,
+		CreatedAt: now,
+		UpdatedAt: now,
+	})
+}
+
+// Purchased reports whether the actor has a standing order for the book.
+func (s *ReviewService) Purchased(ctx context.Context, actor Actor, bookID int) (bool, error) {
+	if actor.UserID <= 0 {
+		return false, nil
+	}
+	for _, o := range s.orders.GetByCustomer(ctx, actor.UserID) {
+		if o.Status == models.OrderCancelled {
+			continue
+		}
+		_, items, err := s.orders.GetByID(ctx, o.ID)
+		if err != nil {
+			return false, err
+		}
+		for _, it := range items {
+			if it.BookID == bookID {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// Update lets the author rewrite their review, which then needs approving
+// again.
+func (s *ReviewService) Update(ctx context.Context, actor Actor, id int, in ReviewInput) (models.Review, error) {
+	rv, err := s.reviews.GetByID(ctx, id)
+	if err != nil {
+		return models.Review{}, err
+	}
+	if rv.UserID != actor.UserID {
+		return models.Review{}, ErrForbidden
+	}
+	in.BookID = rv.BookID
+	in.Title, in.Body = strings.TrimSpace(in.Title), strings.TrimSpace(in.Body)
+	if err := check(in); err != nil {
+		return models.Review{}, err
+	}
+
+	wasApproved := rv.Status == models.ReviewApproved
+	rv.Rating, rv.Title, rv.Body = in.Rating, in.Title, in.Body
+	rv.Status, rv.ModerationNote = models.ReviewPending, ""
+	rv.UpdatedAt = time.Now()
+	if err := s.reviews.Update(ctx, rv); err != nil {
+		return models.Review{}, err
+	}
+	if wasApproved {
+		s.refreshRating(ctx, rv.BookID)
+	}
+	return rv, nil
+}
+
+// Delete removes a review; authors may delete their own, admins any.
+func (s *ReviewService) Delete(ctx context.Context, actor Actor, id int) error {
+	rv, err := s.reviews.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !actor.CanAccess(rv.UserID) {
+		return ErrForbidden
+	}
+	if err := s.reviews.Delete(ctx, id); err != nil {
+		return err
+	}
+	if rv.Status == models.ReviewApproved {
+		s.refreshRating(ctx, rv.BookID)
+	}
+	return nil
+}
+
+// Moderate approves or rejects a review, or returns it to the queue. The
+// note is shown to the author.
+func (s *ReviewService) Moderate(ctx context.Context, id int, in ReviewModeration) (models.Review, error) {
+	in.Note = strings.TrimSpace(in.Note)
+	if err := check(in); err != nil {
+		return models.Review{}, err
+	}
+	switch in.Status {
+	case models.ReviewPending, models.ReviewApproved, models.ReviewRejected:
+	default:
+		return models.Review{}, invalid("status", "status must be pending, approved or rejected")
+	}
+
+	rv, err := s.reviews.GetByID(ctx, id)
+	if err != nil {
+		return models.Review{}, err
+	}
+	changed := rv.Status != in.Status
+	rv.Status, rv.ModerationNote = in.Status, in.Note
+	rv.UpdatedAt = time.Now()
+	if err := s.reviews.Update(ctx, rv); err != nil {
+		return models.Review{}, err
+	}
+	if changed {
+		s.refreshRating(ctx, rv.BookID)
+	}
+	return rv, nil
+}
+
+// Vote adds or withdraws the actor's helpful vote on an approved review of
+// someone else. Voting twice counts once.
+func (s *ReviewService) Vote(ctx context.Context, actor Actor, id int, helpful bool) (models.Review, error) {
+	rv, err := s.reviews.GetByID(ctx, id)
+	if err != nil {
+		return models.Review{}, err
+	}
+	if rv.Status != models.ReviewApproved {
+		return models.Review{}, notFound("review not found")
+	}
+	if rv.UserID == actor.UserID {
+		return models.Review{}, invalid("review", "you cannot vote on your own review")
+	}
+
+	vote := s.reviews.RemoveVote
+	if helpful {
+		vote = s.reviews.AddVote
+	}
+	changed, err := vote(ctx, id, actor.UserID)
+	if err != nil {
+		return models.Review{}, err
+	}
+	switch {
+	case changed && helpful:
+		rv.Helpful++
+	case changed:
+		rv.Helpful--
+	}
+	return rv, nil
+}
+
+// refreshRating recomputes a book's rating after its approved reviews
+// changed. The review change already happened, so failures are logged
+// rather than returned; the next change repairs the rating.
+func (s *ReviewService) refreshRating(ctx context.Context, bookID int) {
+	avg, count, err := s.reviews.RatingStats(ctx, bookID)
+	if err == nil {
+		err = s.books.SetRating(ctx, bookID, avg, count)
+	}
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		logging.From(ctx).Error("refresh book rating", "book_id", bookID, "err", err)
+	}
+}
@@ -0,0 +1,151 @@
+package logic
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"bookstore/internal/logging"
+	"bookstore/internal/models"
+	"bookstore/internal/repository"
+	"bookstore/internal/search"
+)
+
+const (
+	defaultSearch      = 20
+	defaultSuggestions = 5
+	// warmRetry spaces the attempts to load the search data at startup
+	// while the database is unreachable.
+	warmRetry = time.Minute
+)
+
+// SearchService answers catalog searches from a search index and loads the
+// books found from the repository, so results are never staler than the
+// index's list of ids. Suggestions come from memory alone.
+type SearchService struct {
+	index   search.SearchIndex
+	suggest *search.Suggester
+	books   repository.BookRepository
+}
+
+// NewSearchService takes the book repository that keeps index and suggest
+// up to date, as made by search.Synced. suggest may be nil where nothing
+// asks for suggestions, as in the CLI.
+func NewSearchService(index search.SearchIndex, suggest *search.Suggester, books repository.BookRepository) *SearchService {
+	return &SearchService{index: index, suggest: suggest, books: books}
+}
+
+type SearchResult struct {
+	Total  int           `json:"total"`
+	Books  []models.Book `json:"books"`
+	Facets search.Facets `json:"facets"`
+}
+
+func (s *SearchService) Search(ctx context.Context, in SearchInput) (SearchResult, error) {
+	if err := check(in); err != nil {
+		return SearchResult{}, err
+	}
+	if in.Price != "" && !knownPriceBand(in.Price) {
+		keys := make([]string, 0, len(search.PriceBands))
+		for _, b := range search.PriceBands {
+			keys = append(keys, b.Key)
+		}
+		return SearchResult{}, invalid("price", "price must be one of "+strings.Join(keys, ", "))
+	}
+	if in.Limit == 0 {
+		in.Limit = defaultSearch
+	}
+
+	res, err := s.index.Search(ctx, search.Query{
+		Text:   in.Q,
+		Genre:  in.Genre,
+		Author: in.Author,
+		Price:  in.Price,
+		Offset: in.Offset,
+		Limit:  in.Limit,
+	})
+	if err != nil {
+		return SearchResult{}, err
+	}
+
+	out := SearchResult{Total: res.Total, Books: make([]models.Book, 0, len(res.Hits)), Facets: res.Facets}
+	for _, h := range res.Hits {
+		b, err := s.books.GetByID(ctx, h.ID)
+		if errors.Is(err, ErrNotFound) {
+			continue // deleted behind the index's back, as by the CLI
+		}
+		if err != nil {
+			return SearchResult{}, err
+		}
+		out.Books = append(out.Books, b)
+	}
+	return out, nil
+}
+
+func knownPriceBand(key string) bool {
+	for _, b := range search.PriceBands {
+		if b.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+type RebuildReport struct {
+	Books int `json:"books"`
+}
+
+// Rebuild reindexes the whole catalog; searches keep working meanwhile.
+func (s *SearchService) Rebuild(ctx context.Context) (RebuildReport, error) {
+	n, err := s.index.Rebuild(ctx, s.books)
+	if err != nil {
+		return RebuildReport{}, err
+	}
+	return RebuildReport{Books: n}, nil
+}
+
+// Suggest completes in.Q into up to in.Limit titles, authors and genres
+// each.
+func (s *SearchService) Suggest(in SuggestInput) (search.Suggestions, error) {
+	if err := check(in); err != nil {
+		return search.Suggestions{}, err
+	}
+	if in.Limit == 0 {
+		in.Limit = defaultSuggestions
+	}
+	return s.suggest.Suggest(in.Q, in.Limit), nil
+}
+
+// Warm loads the suggestions and, with rebuild, fills the empty search
+// index, retrying each until the database answers. The server runs it in
+// the background at startup.
+func (s *SearchService) Warm(ctx context.Context, rebuild bool) {
+	log := logging.From(ctx)
+	loaded := false
+	for {
+		if !loaded {
+			if err := s.suggest.Load(ctx, s.books); err != nil {
+				log.Error("load search suggestions", "err", err)
+			} else {
+				loaded = true
+			}
+		}
+		if rebuild {
+			if rep, err := s.Rebuild(ctx); err != nil {
+				log.Error("build search index", "err", err)
+			} else {
+				log.Info("search index built", "books", rep.Books)
+				rebuild = false
+			}
+		}
+		if loaded && !rebuild {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(warmRetry):
+		}
+	}
+}
@@ -1,11 +1,17 @@
 package logic
 
 import (
+	"context"
 	"errors"
-	"log"
+	"time"
 
+	"bookstore/internal/logging"
+	"bookstore/internal/metrics"
 	"bookstore/internal/models"
 	"bookstore/internal/repository"
+	"bookstore/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type WishlistService struct {
@@ -26,51 +32,106 @@ func NewWishlistService(
 	}
 }
 
-func (s *WishlistService) CreateWishlist(customerID int) models.Wishlist {
+// CreateWishlist creates a wishlist owned by the actor. Only admins may
+// create one on behalf of another customer.
+func (s *WishlistService) CreateWishlist(ctx context.Context, actor Actor, customerID int) (models.Wishlist, error) {
+	if customerID <= 0 || !actor.IsAdmin() {
+		customerID = actor.UserID
+	}
 	if customerID <= 0 {
-		customerID = 1
+		return models.Wishlist{}, invalid("customerId", "customerId must be positive")
+	}
+
+	wl := s.wRepo.Create(ctx, customerID)
+	if wl.ID == 0 {
+		return models.Wishlist{}, errors.New("could not create wishlist")
+	}
+	return wl, nil
+}
+
+// ListWishlists returns every wishlist for admins and only the actor's own
+// wishlists for everybody else.
+func (s *WishlistService) ListWishlists(ctx context.Context, actor Actor) []models.Wishlist {
+	all := s.wRepo.GetAll(ctx)
+	if actor.IsAdmin() {
+		return all
+	}
+
+	out := make([]models.Wishlist, 0)
+	for _, wl := range all {
+		if wl.CustomerID == actor.UserID {
+			out = append(out, wl)
+		}
 	}
-	return s.wRepo.Create(customerID)
+	return out
 }
 
-func (s *WishlistService) ListWishlists() []models.Wishlist {
-	return s.wRepo.GetAll()
+func (s *WishlistService) GetWishlist(ctx context.Context, actor Actor, id int) (models.Wishlist, []models.WishlistItem, error) {
+	wl, items, err := s.wRepo.GetByID(ctx, id)
+	if err != nil {
+		return models.Wishlist{}, nil, err
+	}
+	if !actor.CanAccess(wl.CustomerID) {
+		return models.Wishlist{}, nil, ErrForbidden
+	}
+	return wl, items, nil
 }
 
-func (s *WishlistService) GetWishlist(id int) (models.Wishlist, []models.WishlistItem, error) {
-	return s.wRepo.GetByID(id)
+func (s *WishlistService) DeleteWishlist(ctx context.Context, actor Actor, id int) error {
+	if _, _, err := s.GetWishlist(ctx, actor, id); err != nil {
+		return err
+	}
+	return s.wRepo.Delete(ctx, id)
 }
 
-func (s *WishlistService) AddItem(wishlistID, bookID, qty int) (models.WishlistItem, error) {
+func (s *WishlistService) AddItem(ctx context.Context, actor Actor, wishlistID, bookID, qty int) (models.WishlistItem, error) {
 	if wishlistID <= 0 {
-		return models.WishlistItem{}, errors.New("wishlistId must be positive")
+		return models.WishlistItem{}, invalid("wishlistId", "wishlistId must be positive")
+	}
+	if err := check(ItemInput{BookID: bookID, Qty: qty}); err != nil {
+		return models.WishlistItem{}, err
 	}
-	if bookID <= 0 {
-		return models.WishlistItem{}, errors.New("bookId must be positive")
+	if _, _, err := s.GetWishlist(ctx, actor, wishlistID); err != nil {
+		return models.WishlistItem{}, err
 	}
-	if qty <= 0 {
-		return models.WishlistItem{}, errors.New("qty must be > 0")
+	if _, err := s.bookRepo.GetByID(ctx, bookID); err != nil {
+		return models.WishlistItem{}, notFound("book not found")
 	}
-	if _, err := s.bookRepo.GetByID(bookID); err != nil {
-		return models.WishlistItem{}, errors.New("book not found")
+	return s.wRepo.AddItem(ctx, wishlistID, bookID, qty)
+}
+
+func (s *WishlistService) DeleteItem(ctx context.Context, actor Actor, wishlistID, itemID int) error {
+	if _, _, err := s.GetWishlist(ctx, actor, wishlistID); err != nil {
+		return err
 	}
-	return s.wRepo.AddItem(wishlistID, bookID, qty)
+	return s.wRepo.DeleteItem(ctx, wishlistID, itemID)
 }
 
-func (s *WishlistService) GiftFromWishlist(wishlistID int, buyerID int) (models.Order, []models.OrderItem, int, error) {
+// GiftFromWishlist buys the items of a wishlist the actor may access, which
+// means their own unless they are an admin; the order is always placed in
+// the actor's name.
+func (s *WishlistService) GiftFromWishlist(ctx context.Context, actor Actor, wishlistID int) (_ models.Order, _ []models.OrderItem, _ int, err error) {
+	ctx, span := tracing.Start(ctx, "WishlistService.GiftFromWishlist",
+		attribute.Int("buyer.id", actor.UserID), attribute.Int("wishlist.id", wishlistID))
+	defer func() { tracing.End(span, err) }()
+
+	buyerID := actor.UserID
 	if wishlistID <= 0 {
-		return models.Order{}, nil, 0, errors.New("wishlistId must be positive")
+		return models.Order{}, nil, 0, invalid("wishlistId", "wishlistId must be positive")
 	}
 	if buyerID <= 0 {
-		return models.Order{}, nil, 0, errors.New("buyerCustomerId must be positive")
+		return models.Order{}, nil, 0, invalid("", "buyer must be authenticated")
 	}
 
-	w, items, err := s.wRepo.GetByID(wishlistID)
+	w, items, err := s.wRepo.GetByID(ctx, wishlistID)
 	if err != nil {
 		return models.Order{}, nil, 0, err
 	}
+	if !actor.CanAccess(w.CustomerID) {
+		return models.Order{}, nil, 0, ErrForbidden
+	}
 	if len(items) == 0 {
-		return models.Order{}, nil, 0, errors.New("wishlist is empty")
+		return models.Order{}, nil, 0, invalid("wishlistId", "wishlist is empty")
 	}
 
 	orderItems := make([]models.OrderItem, 0, len(items))
@@ -78,18 +139,18 @@ func (s *WishlistService) GiftFromWishlist(wishlistID int, buyerID int) (models.
 
 	for _, wi := range items {
 		if wi.BookID <= 0 {
-			return models.Order{}, nil, 0, errors.New("invalid bookId in wishlist")
+			return models.Order{}, nil, 0, invalid("bookId", "invalid bookId in wishlist")
 		}
 		if wi.Qty <= 0 {
-			return models.Order{}, nil, 0, errors.New("invalid qty in wishlist")
+			return models.Order{}, nil, 0, invalid("qty", "invalid qty in wishlist")
 		}
 
-		book, err := s.bookRepo.GetByID(wi.BookID)
+		book, err := s.bookRepo.GetByID(ctx, wi.BookID)
 		if err != nil {
-			return models.Order{}, nil, 0, errors.New("book not found")
+			return models.Order{}, nil, 0, notFound("book not found")
 		}
 		if book.Price < 0 {
-			return models.Order{}, nil, 0, errors.New("book price cannot be negative")
+			return models.Order{}, nil, 0, invalid("price", "book price cannot be negative")
 		}
 
 		orderItems = append(orderItems, models.OrderItem{
@@ -105,20 +166,24 @@ func (s *WishlistService) GiftFromWishlist(wishlistID int, buyerID int) (models.
 		CustomerID: buyerID,
 		CartID:     wishlistID,
 		Total:      total,
+		Status:     models.OrderPlaced,
+		CreatedAt:  time.Now(),
 	}
 
-	createdOrder, createdItems, err := s.orderRepo.Create(order, orderItems)
+	createdOrder, createdItems, err := s.orderRepo.Create(ctx, order, orderItems)
 	if err != nil {
 		return models.Order{}, nil, 0, err
 	}
 
-	log.Printf("[GIFT] enqueue clear wishlist job: wishlistId=%d orderId=%d\n", wishlistID, createdOrder.ID)
+	metrics.OrderCreated("gift", total)
+	logging.From(ctx).Info("gift order created",
+		"order_id", createdOrder.ID, "wishlist_id", wishlistID, "buyer_id", buyerID, "total", total)
 
-	OrderJobQueue <- OrderJob{
+	enqueue(ctx, OrderJob{
 		Type:       JobClearWishlist,
 		OrderID:    createdOrder.ID,
 		WishlistID: wishlistID,
-	}
+	}, nil, s.wRepo)
 
 	return createdOrder, createdItems, w.CustomerID, nil
 }
@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"bookstore/internal/logging"
+)
+
+const ctxAccess ctxKey = "access"
+
+// accessInfo is filled in while the request travels down the chain; the auth
+// layers report the user here because their context changes are not visible
+// to the outer middleware.
+type accessInfo struct {
+	userID int
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	if s.status == 0 {
+		s.status = code
+	}
+	s.ResponseWriter.WriteHeader(code)
+}
+
+func (s *statusRecorder) Write(b []byte) (int, error) {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+	n, err := s.ResponseWriter.Write(b)
+	s.bytes += n
+	return n, err
+}
+
+func (s *statusRecorder) Unwrap() http.ResponseWriter { return s.ResponseWriter }
+
+// AccessLog writes one line per request with the matched route pattern,
+// status, latency and the authenticated user. It must sit inside RequestID,
+// and whatever sits between it and the mux must pass r through unchanged so
+// that r.Pattern is visible after serving.
+func AccessLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		info := &accessInfo{}
+		rec := &statusRecorder{ResponseWriter: w}
+		r = r.WithContext(context.WithValue(r.Context(), ctxAccess, info))
+
+		next.ServeHTTP(rec, r)
+
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+		attrs := []any{
+			"method", r.Method,
+			"path", r.URL.Path,
+			"route", r.Pattern,
+			"status", rec.status,
+			"bytes", rec.bytes,
+			"duration_ms", float64(time.Since(start).Microseconds()) / 1000,
+			"remote_ip", ClientIP(r),
+		}
+		if info.userID > 0 {
+			attrs = append(attrs, "user_id", info.userID)
+		}
+		logging.From(r.Context()).Info("http request", attrs...)
+	})
+}
+
+// NoteUser records the authenticated user for the access log.
+func NoteUser(r *http.Request, userID int) {
+	if info, ok := r.Context().Value(ctxAccess).(*accessInfo); ok {
+		info.userID = userID
+	}
+}
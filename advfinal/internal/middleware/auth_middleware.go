@@ -12,15 +12,23 @@ import (
 type ctxKey string
 
 const (
-	CtxUserID ctxKey = "userId"
-	CtxRole   ctxKey = "role"
+	CtxUserID    ctxKey = "userId"
+	CtxRole      ctxKey = "role"
+	CtxSessionID ctxKey = "sessionId"
 )
 
-func AuthOnly(secret string, next http.HandlerFunc) http.HandlerFunc {
+// SessionCheck reports whether the session behind a token is still active,
+// so that revoked sessions and deleted accounts are rejected before their
+// JWT expires.
+type SessionCheck func(ctx context.Context, userID, sessionID int) bool
+
+// AuthOnly rejects requests without a valid bearer token. active, when not
+// nil, is consulted on every request.
+func AuthOnly(secret string, active SessionCheck, next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		auth := r.Header.Get("Authorization")
 		if !strings.HasPrefix(auth, "Bearer ") {
-			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			WriteError(w, r, http.StatusUnauthorized, "unauthorized", "unauthorized", nil)
 			return
 		}
 
@@ -34,37 +42,47 @@ func AuthOnly(secret string, next http.HandlerFunc) http.HandlerFunc {
 		})
 
 		if err != nil || token == nil || !token.Valid {
-			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			WriteError(w, r, http.StatusUnauthorized, "unauthorized", "unauthorized", nil)
 			return
 		}
 
 		claims, ok := token.Claims.(jwt.MapClaims)
 		if !ok {
-			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			WriteError(w, r, http.StatusUnauthorized, "unauthorized", "unauthorized", nil)
 			return
 		}
 
 		userIDf, ok := claims["userId"].(float64)
 		if !ok {
-			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			WriteError(w, r, http.StatusUnauthorized, "unauthorized", "unauthorized", nil)
 			return
 		}
 		userID := int(userIDf)
 
 		role, _ := claims["role"].(string)
 
+		sidf, _ := claims["sid"].(float64)
+		sessionID := int(sidf)
+		if active != nil && !active(r.Context(), userID, sessionID) {
+			WriteError(w, r, http.StatusUnauthorized, "unauthorized", "unauthorized", nil)
+			return
+		}
+
+		NoteUser(r, userID)
+
 		ctx := context.WithValue(r.Context(), CtxUserID, userID)
 		ctx = context.WithValue(ctx, CtxRole, role)
+		ctx = context.WithValue(ctx, CtxSessionID, sessionID)
 
 		next(w, r.WithContext(ctx))
 	}
 }
 
-func AdminOnly(secret string, next http.HandlerFunc) http.HandlerFunc {
-	return AuthOnly(secret, func(w http.ResponseWriter, r *http.Request) {
+func AdminOnly(secret string, active SessionCheck, next http.HandlerFunc) http.HandlerFunc {
+	return AuthOnly(secret, active, func(w http.ResponseWriter, r *http.Request) {
 		role, _ := r.Context().Value(CtxRole).(string)
 		if role != "admin" {
-			http.Error(w, "forbidden", http.StatusForbidden)
+			WriteError(w, r, http.StatusForbidden, "forbidden", "forbidden", nil)
 			return
 		}
 		next(w, r)
@@ -79,3 +97,8 @@ func Role(r *http.Request) string {
 	role, _ := r.Context().Value(CtxRole).(string)
 	return role
 }
+
+func SessionID(r *http.Request) int {
+	id, _ := r.Context().Value(CtxSessionID).(int)
+	return id
+}
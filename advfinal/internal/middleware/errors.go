@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// ErrorBody is the envelope every API error is returned in.
+type ErrorBody struct {
+	Error ErrorDetail `json:"error"`
+}
+
+type ErrorDetail struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	Fields    any    `json:"fields,omitempty"`
+	RequestID string `json:"requestId,omitempty"`
+}
+
+// WriteError writes the JSON error envelope. fields is optional and holds the
+// per-field problems of a validation error.
+func WriteError(w http.ResponseWriter, r *http.Request, status int, code, message string, fields any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(ErrorBody{Error: ErrorDetail{
+		Code:      code,
+		Message:   message,
+		Fields:    fields,
+		RequestID: RequestIDFrom(r),
+	}})
+}
@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"bookstore/internal/metrics"
+)
+
+// Metrics records request counts and latency per route pattern. Whatever
+// sits between it and the mux must pass r through unchanged so r.Pattern is
+// set afterwards.
+func Metrics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+
+		next.ServeHTTP(rec, r)
+
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+		metrics.ObserveHTTP(r.Method, r.Pattern, rec.status, time.Since(start))
+	})
+}
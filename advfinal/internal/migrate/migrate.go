@@ -0,0 +1,188 @@
+// Package migrate applies versioned schema changes to the Mongo database.
+//
+// Each applied step is recorded in the "migrations" collection under its
+// version, so Up only runs what is missing. Steps must be idempotent: a step
+// that fails half way is rerun from the start on the next Up.
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	collection = "migrations"
+	lockID     = "lock"
+	lockTTL    = 10 * time.Minute
+)
+
+// ErrLocked is returned while another process is migrating the database.
+var ErrLocked = errors.New("migrations are locked by another process")
+
+type Migration struct {
+	Version     int
+	Description string
+	Up          func(ctx context.Context, db *mongo.Database) error
+	// Down may be nil for steps that cannot be undone, such as backfills.
+	Down func(ctx context.Context, db *mongo.Database) error
+}
+
+type record struct {
+	Version     int       `bson:"_id"`
+	Description string    `bson:"description"`
+	AppliedAt   time.Time `bson:"appliedAt"`
+}
+
+type Status struct {
+	Version     int        `json:"version"`
+	Description string     `json:"description"`
+	AppliedAt   *time.Time `json:"appliedAt,omitempty"`
+}
+
+func (s Status) Applied() bool { return s.AppliedAt != nil }
+
+type Migrator struct {
+	db         *mongo.Database
+	col        *mongo.Collection
+	migrations []Migration
+}
+
+// NewMigrator sorts migrations by version; versions must be unique.
+func NewMigrator(db *mongo.Database, migrations []Migration) *Migrator {
+	sorted := append([]Migration(nil), migrations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+	for i := 1; i < len(sorted); i++ {
+		if sorted[i].Version == sorted[i-1].Version {
+			panic(fmt.Sprintf("migrate: duplicate version %d", sorted[i].Version))
+		}
+	}
+	return &Migrator{db: db, col: db.Collection(collection), migrations: sorted}
+}
+
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]Status, 0, len(m.migrations))
+	for _, mg := range m.migrations {
+		st := Status{Version: mg.Version, Description: mg.Description}
+		if rec, ok := applied[mg.Version]; ok {
+			st.AppliedAt = &rec.AppliedAt
+		}
+		out = append(out, st)
+	}
+	return out, nil
+}
+
+// Up applies every pending migration in version order and returns the ones
+// it ran. It stops at the first failure.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var ran []Migration
+	err := m.locked(ctx, func() error {
+		applied, err := m.applied(ctx)
+		if err != nil {
+			return err
+		}
+		for _, mg := range m.migrations {
+			if _, ok := applied[mg.Version]; ok {
+				continue
+			}
+			if err := mg.Up(ctx, m.db); err != nil {
+				return fmt.Errorf("migration %d (%s): %w", mg.Version, mg.Description, err)
+			}
+			rec := record{Version: mg.Version, Description: mg.Description, AppliedAt: time.Now().UTC()}
+			if _, err := m.col.InsertOne(ctx, rec); err != nil {
+				return fmt.Errorf("record migration %d: %w", mg.Version, err)
+			}
+			ran = append(ran, mg)
+		}
+		return nil
+	})
+	return ran, err
+}
+
+// Down reverts the last steps applied migrations, newest first.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var reverted []Migration
+	err := m.locked(ctx, func() error {
+		applied, err := m.applied(ctx)
+		if err != nil {
+			return err
+		}
+		for i := len(m.migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+			mg := m.migrations[i]
+			if _, ok := applied[mg.Version]; !ok {
+				continue
+			}
+			if mg.Down == nil {
+				return fmt.Errorf("migration %d (%s) cannot be reverted", mg.Version, mg.Description)
+			}
+			if err := mg.Down(ctx, m.db); err != nil {
+				return fmt.Errorf("revert migration %d (%s): %w", mg.Version, mg.Description, err)
+			}
+			if _, err := m.col.DeleteOne(ctx, bson.M{"_id": mg.Version}); err != nil {
+				return fmt.Errorf("unrecord migration %d: %w", mg.Version, err)
+			}
+			reverted = append(reverted, mg)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+func (m *Migrator) applied(ctx context.Context) (map[int]record, error) {
+	cur, err := m.col.Find(ctx, bson.M{"_id": bson.M{"$type": "number"}})
+	if err != nil {
+		return nil, err
+	}
+	var recs []record
+	if err := cur.All(ctx, &recs); err != nil {
+		return nil, err
+	}
+
+	out := make(map[int]record, len(recs))
+	for _, r := range recs {
+		out[r.Version] = r
+	}
+	return out, nil
+}
+
+// locked runs fn while holding the lock document, so that several instances
+// migrating at startup do not run the same step twice. A lock left behind by
+// a crashed process expires after lockTTL.
+func (m *Migrator) locked(ctx context.Context, fn func() error) error {
+	host, _ := os.Hostname()
+	now := time.Now().UTC()
+	_, err := m.col.UpdateOne(ctx,
+		bson.M{"_id": lockID, "expiresAt": bson.M{"$lt": now}},
+		bson.M{"$set": bson.M{
+			"owner":     fmt.Sprintf("%s/%d", host, os.Getpid()),
+			"expiresAt": now.Add(lockTTL),
+		}},
+		options.Update().SetUpsert(true),
+	)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrLocked
+	}
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		// Release even if ctx was cancelled mid-migration.
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		_, _ = m.col.DeleteOne(ctx, bson.M{"_id": lockID})
+	}()
+	return fn()
+}
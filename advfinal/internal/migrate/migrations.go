@@ -0,0 +1,607 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"bookstore/internal/isbn"
+	"bookstore/internal/slug"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// All is the schema history, oldest first. Never edit or renumber a step
+// that has shipped; add a new one instead.
+var All = []Migration{
+	{
+		Version:     1,
+		Description: "unique id indexes",
+		Up:          createIndexes(idIndexes()...),
+		Down:        dropIndexes(idIndexes()...),
+	},
+	{
+		Version:     2,
+		Description: "unique user email",
+		Up:          createUniqueEmail,
+		Down:        dropIndexes(emailIndex),
+	},
+	{
+		Version:     3,
+		Description: "lookup indexes for items, orders and sessions",
+		Up:          createIndexes(lookupIndexes...),
+		Down:        dropIndexes(lookupIndexes...),
+	},
+	{
+		Version:     4,
+		Description: "backfill user roles and address lists",
+		Up:          backfillUsers,
+	},
+	{
+		Version:     5,
+		Description: "backfill order status",
+		Up:          backfillOrderStatus,
+	},
+	{
+		Version:     6,
+		Description: "failed_jobs id index",
+		Up:          createIndexes(failedJobsIndex),
+		Down:        dropIndexes(failedJobsIndex),
+	},
+	{
+		Version:     7,
+		Description: "book import keys",
+		Up:          createIndexes(bookKeyIndexes...),
+		Down:        dropIndexes(bookKeyIndexes...),
+	},
+	{
+		Version:     8,
+		Description: "normalized, unique book ISBNs",
+		Up:          uniqueISBN,
+		Down:        sequence(dropIndexes(isbnUniqueIndex), createIndexes(isbnIndex)),
+	},
+	{
+		Version:     9,
+		Description: "book slugs",
+		Up:          sequence(backfillSlugs, createIndexes(slugIndexes...)),
+		Down:        dropIndexes(slugIndexes...),
+	},
+	{
+		Version:     10,
+		Description: "reviews, helpful votes and book rating order",
+		Up:          createIndexes(reviewIndexes...),
+		Down:        dropIndexes(reviewIndexes...),
+	},
+	{
+		Version:     11,
+		Description: "recommendations by book",
+		Up:          createIndexes(recommendationIndexes...),
+		Down:        dropIndexes(recommendationIndexes...),
+	},
+	{
+		Version:     12,
+		Description: "book text index for the mongo search backend",
+		Up:          createIndexes(bookTextIndex),
+		Down:        dropIndexes(bookTextIndex),
+	},
+	{
+		Version:     13,
+		Description: "authors, publishers, series and genres, deduplicated from the book fields",
+		Up:          sequence(createIndexes(entityIndexes()...), backfillEntities),
+		Down:        dropIndexes(entityIndexes()...),
+	},
+	{
+		Version:     14,
+		Description: "category tree and book tags, seeded with a category per genre",
+		Up:          sequence(createIndexes(categoryIndexes...), seedCategories),
+		Down:        dropIndexes(categoryIndexes...),
+	},
+}
+
+type index struct {
+	collection string
+	name       string
+	keys       bson.D
+	unique     bool
+	partial    bson.M // filter of a partial index
+	weights    bson.M // of the fields of a text index
+}
+
+func idIndexes() []index {
+	var out []index
+	for _, col := range []string{"books", "users", "orders", "order_items", "sessions", "wishlists", "wishlist_items"} {
+		out = append(out, index{collection: col, name: "id_unique", keys: bson.D{{Key: "id", Value: 1}}, unique: true})
+	}
+	return out
+}
+
+var emailIndex = index{collection: "users", name: "email_unique", keys: bson.D{{Key: "email", Value: 1}}, unique: true}
+
+var failedJobsIndex = index{collection: "failed_jobs", name: "id_unique", keys: bson.D{{Key: "id", Value: 1}}, unique: true}
+
+var isbnIndex = index{collection: "books", name: "isbn", keys: bson.D{{Key: "isbn", Value: 1}}}
+
+var bookKeyIndexes = []index{
+	isbnIndex,
+	{collection: "books", name: "externalId", keys: bson.D{{Key: "externalId", Value: 1}}},
+}
+
+// isbnUniqueIndex leaves out books without an ISBN, which may be many.
+var isbnUniqueIndex = index{collection: "books", name: "isbn_unique", keys: bson.D{{Key: "isbn", Value: 1}},
+	unique: true, partial: bson.M{"isbn": bson.M{"$gt": ""}}}
+
+var slugIndexes = []index{
+	{collection: "books", name: "slug_unique", keys: bson.D{{Key: "slug", Value: 1}},
+		unique: true, partial: bson.M{"slug": bson.M{"$gt": ""}}},
+	{collection: "books", name: "previousSlugs", keys: bson.D{{Key: "previousSlugs", Value: 1}}},
+}
+
+var reviewIndexes = []index{
+	{collection: "reviews", name: "id_unique", keys: bson.D{{Key: "id", Value: 1}}, unique: true},
+	{collection: "reviews", name: "bookId_userId_unique", keys: bson.D{{Key: "bookId", Value: 1}, {Key: "userId", Value: 1}}, unique: true},
+	{collection: "reviews", name: "status_createdAt", keys: bson.D{{Key: "status", Value: 1}, {Key: "createdAt", Value: 1}}},
+	{collection: "reviews", name: "userId_createdAt", keys: bson.D{{Key: "userId", Value: 1}, {Key: "createdAt", Value: -1}}},
+	{collection: "review_votes", name: "reviewId_userId_unique", keys: bson.D{{Key: "reviewId", Value: 1}, {Key: "userId", Value: 1}}, unique: true},
+	{collection: "books", name: "rating", keys: bson.D{{Key: "rating", Value: -1}, {Key: "ratingCount", Value: -1}}},
+}
+
+var recommendationIndexes = []index{
+	{collection: "recommendations", name: "bookId_unique", keys: bson.D{{Key: "bookId", Value: 1}}, unique: true},
+	{collection: "recommendations", name: "computedAt", keys: bson.D{{Key: "computedAt", Value: 1}}},
+}
+
+// bookTextIndex weighs the fields as the bleve backend boosts them.
+var bookTextIndex = index{collection: "books", name: "text",
+	keys: bson.D{{Key: "title", Value: "text"}, {Key: "authors", Value: "text"}, {Key: "author", Value: "text"},
+		{Key: "genre", Value: "text"}, {Key: "publisher", Value: "text"}, {Key: "description", Value: "text"}},
+	weights: bson.M{"title": 8, "authors": 6, "author": 6, "genre": 3, "publisher": 2, "description": 1}}
+
+// entityIndexes serve the entity collections and finding the books of
+// each entity.
+func entityIndexes() []index {
+	out := []index{
+		{collection: "books", name: "authorIds", keys: bson.D{{Key: "authorIds", Value: 1}}},
+		{collection: "books", name: "publisherId", keys: bson.D{{Key: "publisherId", Value: 1}}},
+		{collection: "books", name: "seriesId_seriesPosition", keys: bson.D{{Key: "seriesId", Value: 1}, {Key: "seriesPosition", Value: 1}}},
+		{collection: "books", name: "genreId", keys: bson.D{{Key: "genreId", Value: 1}}},
+	}
+	for _, col := range []string{"authors", "publishers", "series", "genres"} {
+		out = append(out,
+			index{collection: col, name: "id_unique", keys: bson.D{{Key: "id", Value: 1}}, unique: true},
+			index{collection: col, name: "slug_unique", keys: bson.D{{Key: "slug", Value: 1}}, unique: true},
+			index{collection: col, name: "previousSlugs", keys: bson.D{{Key: "previousSlugs", Value: 1}}},
+		)
+	}
+	return out
+}
+
+// categoryIndexes serve the category tree, the books of a branch and
+// the books of a tag.
+var categoryIndexes = []index{
+	{collection: "categories", name: "id_unique", keys: bson.D{{Key: "id", Value: 1}}, unique: true},
+	{collection: "categories", name: "slug_unique", keys: bson.D{{Key: "slug", Value: 1}}, unique: true},
+	{collection: "categories", name: "previousSlugs", keys: bson.D{{Key: "previousSlugs", Value: 1}}},
+	{collection: "categories", name: "parentId", keys: bson.D{{Key: "parentId", Value: 1}}},
+	{collection: "categories", name: "ancestors", keys: bson.D{{Key: "ancestors", Value: 1}}},
+	{collection: "books", name: "categoryIds", keys: bson.D{{Key: "categoryIds", Value: 1}}},
+	{collection: "books", name: "tags", keys: bson.D{{Key: "tags", Value: 1}}},
+}
+
+var lookupIndexes = []index{
+	{collection: "order_items", name: "orderId", keys: bson.D{{Key: "orderId", Value: 1}}},
+	{collection: "orders", name: "customerId_id", keys: bson.D{{Key: "customerId", Value: 1}, {Key: "id", Value: -1}}},
+	{collection: "wishlist_items", name: "wishlistId", keys: bson.D{{Key: "wishlistId", Value: 1}}},
+	{collection: "wishlists", name: "customerId", keys: bson.D{{Key: "customerId", Value: 1}}},
+	{collection: "sessions", name: "userId_createdAt", keys: bson.D{{Key: "userId", Value: 1}, {Key: "createdAt", Value: -1}}},
+}
+
+// createIndexes is idempotent: Mongo accepts an index that already exists
+// with the same name and spec.
+func createIndexes(indexes ...index) func(context.Context, *mongo.Database) error {
+	return func(ctx context.Context, db *mongo.Database) error {
+		for _, ix := range indexes {
+			opts := options.Index().SetName(ix.name)
+			if ix.unique {
+				opts.SetUnique(true)
+			}
+			if ix.partial != nil {
+				opts.SetPartialFilterExpression(ix.partial)
+			}
+			if ix.weights != nil {
+				// Books have a "language" field, which Mongo would otherwise
+				// read as the language to stem them in, rejecting codes it
+				// does not know.
+				opts.SetWeights(ix.weights).SetLanguageOverride("textLanguage")
+			}
+			_, err := db.Collection(ix.collection).Indexes().CreateOne(ctx, mongo.IndexModel{Keys: ix.keys, Options: opts})
+			if err != nil {
+				return fmt.Errorf("%s.%s: %w", ix.collection, ix.name, err)
+			}
+		}
+		return nil
+	}
+}
+
+func dropIndexes(indexes ...index) func(context.Context, *mongo.Database) error {
+	return func(ctx context.Context, db *mongo.Database) error {
+		for _, ix := range indexes {
+			_, err := db.Collection(ix.collection).Indexes().DropOne(ctx, ix.name)
+			if err != nil && !isMissing(err) {
+				return fmt.Errorf("%s.%s: %w", ix.collection, ix.name, err)
+			}
+		}
+		return nil
+	}
+}
+
+func createUniqueEmail(ctx context.Context, db *mongo.Database) error {
+	err := createIndexes(emailIndex)(ctx, db)
+	if mongo.IsDuplicateKeyError(err) {
+		return fmt.Errorf("users share an email address; merge or rename them and rerun: %w", err)
+	}
+	return err
+}
+
+// uniqueISBN rewrites stored ISBNs as bare ISBN-13s, the form the service
+// now saves, and then makes them unique. ISBNs that fail their checksum are
+// left as they are.
+func uniqueISBN(ctx context.Context, db *mongo.Database) error {
+	books := db.Collection("books")
+	cur, err := books.Find(ctx, bson.M{"isbn": bson.M{"$gt": ""}},
+		options.Find().SetProjection(bson.M{"id": 1, "isbn": 1}))
+	if err != nil {
+		return err
+	}
+	defer cur.Close(ctx)
+
+	for cur.Next(ctx) {
+		var doc struct {
+			ID   int    `bson:"id"`
+			ISBN string `bson:"isbn"`
+		}
+		if err := cur.Decode(&doc); err != nil {
+			return err
+		}
+		n, err := isbn.Normalize(doc.ISBN)
+		if err != nil || n == doc.ISBN {
+			continue
+		}
+		if _, err := books.UpdateOne(ctx, bson.M{"id": doc.ID}, bson.M{"$set": bson.M{"isbn": n}}); err != nil {
+			return err
+		}
+	}
+	if err := cur.Err(); err != nil {
+		return err
+	}
+
+	if err := dropIndexes(isbnIndex)(ctx, db); err != nil {
+		return err
+	}
+	err = createIndexes(isbnUniqueIndex)(ctx, db)
+	if mongo.IsDuplicateKeyError(err) {
+		return fmt.Errorf("books share an ISBN; merge or correct them and rerun: %w", err)
+	}
+	return err
+}
+
+// backfillSlugs gives every book without a slug one made the way the
+// catalog makes them, numbering clashes in id order.
+func backfillSlugs(ctx context.Context, db *mongo.Database) error {
+	books := db.Collection("books")
+	cur, err := books.Find(ctx, bson.M{}, options.Find().
+		SetSort(bson.M{"id": 1}).
+		SetProjection(bson.M{"id": 1, "title": 1, "author": 1, "authors": 1, "slug": 1, "previousSlugs": 1}))
+	if err != nil {
+		return err
+	}
+	defer cur.Close(ctx)
+
+	type doc struct {
+		ID            int      `bson:"id"`
+		Title         string   `bson:"title"`
+		Author        string   `bson:"author"`
+		Authors       []string `bson:"authors"`
+		Slug          string   `bson:"slug"`
+		PreviousSlugs []string `bson:"previousSlugs"`
+	}
+	var todo []doc
+	taken := map[string]bool{}
+	for cur.Next(ctx) {
+		var d doc
+		if err := cur.Decode(&d); err != nil {
+			return err
+		}
+		if d.Slug == "" {
+			todo = append(todo, d)
+		}
+		taken[d.Slug] = true
+		for _, s := range d.PreviousSlugs {
+			taken[s] = true
+		}
+	}
+	if err := cur.Err(); err != nil {
+		return err
+	}
+
+	for _, d := range todo {
+		author := d.Author
+		if len(d.Authors) > 0 {
+			author = d.Authors[0]
+		}
+		s, _ := slug.Unique(slug.Make(d.Title, author), func(s string) (bool, error) { return taken[s], nil })
+		taken[s] = true
+		if _, err := books.UpdateOne(ctx, bson.M{"id": d.ID}, bson.M{"$set": bson.M{"slug": s}}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// backfillEntities turns the author, publisher and genre names of the books
+// into entities and points the books at them. Names are the same entity
+// when their slugs are, as the catalog matches them, and the entity takes
+// the most used spelling, which the books then carry too. Books already
+// pointing at an entity are left alone, so the step can be rerun.
+func backfillEntities(ctx context.Context, db *mongo.Database) error {
+	books := db.Collection("books")
+	cur, err := books.Find(ctx, bson.M{}, options.Find().
+		SetSort(bson.M{"id": 1}).
+		SetProjection(bson.M{"id": 1, "author": 1, "authors": 1, "publisher": 1, "genre": 1,
+			"authorIds": 1, "publisherId": 1, "genreId": 1}))
+	if err != nil {
+		return err
+	}
+	defer cur.Close(ctx)
+
+	type doc struct {
+		ID          int      `bson:"id"`
+		Author      string   `bson:"author"`
+		Authors     []string `bson:"authors"`
+		Publisher   string   `bson:"publisher"`
+		Genre       string   `bson:"genre"`
+		AuthorIDs   []int    `bson:"authorIds"`
+		PublisherID int      `bson:"publisherId"`
+		GenreID     int      `bson:"genreId"`
+	}
+	var todo []doc
+	names := map[string]*entityNames{
+		"authors":    newEntityNames(),
+		"publishers": newEntityNames(),
+		"genres":     newEntityNames(),
+	}
+	for cur.Next(ctx) {
+		var d doc
+		if err := cur.Decode(&d); err != nil {
+			return err
+		}
+		if len(d.Authors) == 0 && strings.TrimSpace(d.Author) != "" {
+			d.Authors = []string{d.Author}
+		}
+		if len(d.AuthorIDs) == 0 {
+			for _, a := range d.Authors {
+				names["authors"].add(a)
+			}
+		}
+		if d.PublisherID == 0 {
+			names["publishers"].add(d.Publisher)
+		}
+		if d.GenreID == 0 {
+			names["genres"].add(d.Genre)
+		}
+		todo = append(todo, d)
+	}
+	if err := cur.Err(); err != nil {
+		return err
+	}
+
+	for col, n := range names {
+		if err := n.store(ctx, db, col); err != nil {
+			return err
+		}
+	}
+
+	for _, d := range todo {
+		set := bson.M{}
+		if len(d.AuthorIDs) == 0 && len(d.Authors) > 0 {
+			ids, authors := []int{}, []string{}
+			for _, a := range d.Authors {
+				if e, ok := names["authors"].get(a); ok {
+					ids, authors = append(ids, e.ID), append(authors, e.Name)
+				}
+			}
+			set["authorIds"], set["authors"] = ids, authors
+			// Like the catalog, shorten long lists to fit the author field.
+			line := strings.Join(authors, ", ")
+			if utf8.RuneCountInString(line) > 120 && len(authors) > 1 {
+				line = authors[0] + " et al."
+			}
+			set["author"] = line
+		}
+		if e, ok := names["publishers"].get(d.Publisher); ok && d.PublisherID == 0 {
+			set["publisherId"], set["publisher"] = e.ID, e.Name
+		}
+		if e, ok := names["genres"].get(d.Genre); ok && d.GenreID == 0 {
+			set["genreId"], set["genre"] = e.ID, e.Name
+		}
+		if len(set) == 0 {
+			continue
+		}
+		if _, err := books.UpdateOne(ctx, bson.M{"id": d.ID}, bson.M{"$set": set}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type entity struct {
+	ID   int    `bson:"id"`
+	Name string `bson:"name"`
+}
+
+// entityNames gathers the spellings of each entity of one kind, by slug.
+type entityNames struct {
+	counts   map[string]map[string]int // slug -> spelling -> books
+	order    []string                  // slugs as first seen
+	entities map[string]entity         // by slug, once stored
+}
+
+func newEntityNames() *entityNames {
+	return &entityNames{counts: map[string]map[string]int{}, entities: map[string]entity{}}
+}
+
+func (n *entityNames) add(name string) {
+	if name = strings.TrimSpace(name); name == "" {
+		return
+	}
+	s := slug.Make(name)
+	if n.counts[s] == nil {
+		n.counts[s] = map[string]int{}
+		n.order = append(n.order, s)
+	}
+	n.counts[s][name]++
+}
+
+func (n *entityNames) get(name string) (entity, bool) {
+	e, ok := n.entities[slug.Make(strings.TrimSpace(name))]
+	return e, ok
+}
+
+// store finds or creates the entity of every slug in col, creating them
+// with ids from col's counter. A slug already stored, as current or
+// previous slug, keeps its entity and name.
+func (n *entityNames) store(ctx context.Context, db *mongo.Database, col string) error {
+	entities := db.Collection(col)
+	for _, s := range n.order {
+		var e entity
+		err := entities.FindOne(ctx, bson.M{"$or": bson.A{bson.M{"slug": s}, bson.M{"previousSlugs": s}}}).Decode(&e)
+		if err == mongo.ErrNoDocuments {
+			e.Name = mostUsed(n.counts[s])
+			if e.ID, err = nextID(ctx, db, col); err != nil {
+				return err
+			}
+			_, err = entities.InsertOne(ctx, bson.M{"id": e.ID, "name": e.Name, "slug": s, "previousSlugs": bson.A{}})
+		}
+		if err != nil {
+			return fmt.Errorf("%s %q: %w", col, s, err)
+		}
+		n.entities[s] = e
+	}
+	return nil
+}
+
+// seedCategories starts the tree with a top-level category for each genre
+// and files the books of the genre under it, skipping books already in a
+// category. A category with the genre's slug is reused, so it can run again.
+func seedCategories(ctx context.Context, db *mongo.Database) error {
+	cur, err := db.Collection("genres").Find(ctx, bson.M{}, options.Find().SetSort(bson.M{"id": 1}))
+	if err != nil {
+		return err
+	}
+	var genres []struct {
+		ID   int    `bson:"id"`
+		Name string `bson:"name"`
+		Slug string `bson:"slug"`
+	}
+	if err := cur.All(ctx, &genres); err != nil {
+		return err
+	}
+
+	categories := db.Collection("categories")
+	for _, g := range genres {
+		var c entity
+		err := categories.FindOne(ctx, bson.M{"$or": bson.A{bson.M{"slug": g.Slug}, bson.M{"previousSlugs": g.Slug}}}).Decode(&c)
+		if err == mongo.ErrNoDocuments {
+			if c.ID, err = nextID(ctx, db, "categories"); err != nil {
+				return err
+			}
+			_, err = categories.InsertOne(ctx, bson.M{"id": c.ID, "name": g.Name, "slug": g.Slug,
+				"previousSlugs": bson.A{}, "parentId": 0, "ancestors": bson.A{}})
+		}
+		if err != nil {
+			return fmt.Errorf("category %q: %w", g.Slug, err)
+		}
+		_, err = db.Collection("books").UpdateMany(ctx,
+			bson.M{"genreId": g.ID, "$or": bson.A{
+				bson.M{"categoryIds": bson.M{"$exists": false}},
+				bson.M{"categoryIds": nil},
+				bson.M{"categoryIds": bson.A{}},
+			}},
+			bson.M{"$set": bson.M{"categoryIds": bson.A{c.ID}}},
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mostUsed picks the spelling used by most books, the first in sort order
+// on a tie.
+func mostUsed(spellings map[string]int) string {
+	best := ""
+	for name, count := range spellings {
+		if c := spellings[best]; count > c || count == c && name < best {
+			best = name
+		}
+	}
+	return best
+}
+
+// nextID takes the next id from the counter of col, as the repositories do.
+func nextID(ctx context.Context, db *mongo.Database, col string) (int, error) {
+	var out struct {
+		Seq int `bson:"seq"`
+	}
+	err := db.Collection("counters").FindOneAndUpdate(ctx,
+		bson.M{"_id": col},
+		bson.M{"$inc": bson.M{"seq": 1}},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&out)
+	return out.Seq, err
+}
+
+func backfillUsers(ctx context.Context, db *mongo.Database) error {
+	users := db.Collection("users")
+	if _, err := users.UpdateMany(ctx,
+		bson.M{"$or": bson.A{bson.M{"role": bson.M{"$exists": false}}, bson.M{"role": ""}}},
+		bson.M{"$set": bson.M{"role": "user"}},
+	); err != nil {
+		return err
+	}
+	_, err := users.UpdateMany(ctx,
+		bson.M{"$or": bson.A{bson.M{"addresses": bson.M{"$exists": false}}, bson.M{"addresses": nil}}},
+		bson.M{"$set": bson.M{"addresses": bson.A{}}},
+	)
+	return err
+}
+
+func backfillOrderStatus(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection("orders").UpdateMany(ctx,
+		bson.M{"$or": bson.A{bson.M{"status": bson.M{"$exists": false}}, bson.M{"status": ""}}},
+		bson.M{"$set": bson.M{"status": "placed"}},
+	)
+	return err
+}
+
+// sequence runs steps in order and stops at the first error.
+func sequence(steps ...func(context.Context, *mongo.Database) error) func(context.Context, *mongo.Database) error {
+	return func(ctx context.Context, db *mongo.Database) error {
+		for _, step := range steps {
+			if err := step(ctx, db); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// isMissing reports whether err says the index or its collection does not
+// exist.
+func isMissing(err error) bool {
+	var se mongo.ServerError
+	return errors.As(err, &se) && (se.HasErrorCode(26) || se.HasErrorCode(27))
+}
@@ -1,27 +1,236 @@
 package models
 
-import "time"
+import (
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"bookstore/internal/isbn"
+	"bookstore/internal/slug"
+)
+
+// Book availabilities. Books stored before availability existed have none
+// and count as available.
+const (
+	BookAvailable   = "available"
+	BookPreorder    = "preorder"
+	BookUnavailable = "unavailable"
+)
+
+// Book formats.
+const (
+	FormatHardcover = "hardcover"
+	FormatPaperback = "paperback"
+	FormatEbook     = "ebook"
+	FormatAudiobook = "audiobook"
+	FormatOther     = "other"
+)
 
 type Book struct {
-	ID          int
-	Title       string
-	Author      string
-	Genre       string
-	Price       float64
-	Description string
+	ID              int
+	ISBN            string   `validate:"isbn"`                      // ISBN-13, bare digits
+	ExternalID      string   `bson:"externalId" validate:"max=100"` // supplier's id, used to match imports
+	Title           string   `validate:"required,max=200"`
+	Author          string   `validate:"required,max=120"` // display form of Authors
+	Authors         []string // in credit order
+	Genre           string   `validate:"max=60"`
+	Publisher       string   `validate:"max=120"`
+	Series          string   `validate:"max=120"`
+	SeriesPosition  int      `bson:"seriesPosition" validate:"min=0,max=10000"` // the book's number in Series, 0 if unnumbered
+	Format          string   `validate:"max=20"`
+	Pages           int      `validate:"min=0,max=100000"`
+	Language        string   `validate:"max=3"`                         // ISO 639 code
+	PublicationDate string   `bson:"publicationDate" validate:"max=10"` // YYYY-MM-DD
+	Price           float64  `validate:"min=0,max=100000"`
+	Availability    string   `validate:"max=20"`
+	Description     string   `validate:"max=5000"`
+
+	// The authors, publisher, series and genre the names above stand for.
+	// The catalog keeps names and ids in step: ids given win, and names
+	// given without ids are looked up, or added, by name.
+	AuthorIDs   []int `bson:"authorIds"` // parallel to Authors
+	PublisherID int   `bson:"publisherId"`
+	SeriesID    int   `bson:"seriesId"`
+	GenreID     int   `bson:"genreId"`
+
+	// CategoryIDs places the book in the category tree, in any number of
+	// places. Tags are free-form lowercase labels such as "dragons".
+	CategoryIDs []int `bson:"categoryIds"`
+	Tags        []string
+
+	// Slug names the book's page. The catalog derives it from title and
+	// author; the slugs it replaced redirect to it.
+	Slug          string
+	PreviousSlugs []string `bson:"previousSlugs"`
+
+	// Cover is the version of the book's cover image, "" when it has none.
+	// Only cover uploads set it.
+	Cover string
+
+	// Rating averages the approved reviews; RatingCount counts them. Only
+	// review moderation changes them.
+	Rating      float64
+	RatingCount int `bson:"ratingCount"`
+}
+
+// ISBN10 is the ISBN-10 form of the book's ISBN, if it has one.
+func (b Book) ISBN10() string {
+	s, _ := isbn.To10(b.ISBN)
+	return s
+}
+
+// Cover image sizes.
+const (
+	CoverThumb  = "thumb"
+	CoverDetail = "detail"
+)
+
+// CoverURL is where the cover is served in size, or "" without a cover.
+func (b Book) CoverURL(size string) string {
+	if b.Cover == "" {
+		return ""
+	}
+	return "/covers/" + strconv.Itoa(b.ID) + "/" + b.Cover + "/" + size + ".jpg"
+}
+
+// Path is the book's page on the storefront.
+func (b Book) Path() string {
+	if b.Slug == "" {
+		return "/catalog/" + strconv.Itoa(b.ID)
+	}
+	return "/books/" + url.PathEscape(b.Slug)
+}
+
+// Stars draws the rating rounded to whole stars, "" when there is none.
+func (b Book) Stars() string {
+	if b.RatingCount == 0 {
+		return ""
+	}
+	return stars(int(math.Round(b.Rating)))
+}
+
+// Entity kinds, named after their collections and the paths of their
+// pages.
+const (
+	EntityAuthor    = "authors"
+	EntityPublisher = "publishers"
+	EntitySeries    = "series"
+	EntityGenre     = "genres"
+)
+
+var EntityKinds = []string{EntityAuthor, EntityPublisher, EntitySeries, EntityGenre}
+
+// EntityNoun names one entity of kind, as in "author not found".
+func EntityNoun(kind string) string {
+	if kind == EntitySeries {
+		return kind
+	}
+	return strings.TrimSuffix(kind, "s")
+}
+
+// Entity is an author, publisher, series or genre, which books refer to by
+// id. Its slug is made from its name alone, so names are unique within a
+// kind; the slugs of renamed and merged entities redirect to it.
+type Entity struct {
+	ID            int      `json:"id" bson:"id"`
+	Name          string   `json:"name" bson:"name"`
+	Slug          string   `json:"slug" bson:"slug"`
+	PreviousSlugs []string `json:"previousSlugs" bson:"previousSlugs"`
+	Description   string   `json:"description,omitempty" bson:"description,omitempty"`
+}
+
+// Path is the entity's page on the storefront, for an entity of kind.
+func (e Entity) Path(kind string) string {
+	return "/" + kind + "/" + url.PathEscape(e.Slug)
+}
+
+// EntityPath is the page of the entity of kind named name, which books can
+// link to by the names they carry.
+func EntityPath(kind, name string) string {
+	return Entity{Slug: slug.Make(name)}.Path(kind)
+}
+
+// Category is a node of the category tree, such as Epic in Fiction >
+// Fantasy > Epic. Ancestors lists the ids from the root down to the parent,
+// so a whole branch is found by one of them. Slugs are unique across the
+// tree; those of renamed and merged categories redirect to it.
+type Category struct {
+	ID            int      `json:"id" bson:"id"`
+	Name          string   `json:"name" bson:"name"`
+	Slug          string   `json:"slug" bson:"slug"`
+	PreviousSlugs []string `json:"previousSlugs" bson:"previousSlugs"`
+	ParentID      int      `json:"parentId" bson:"parentId"` // 0 for a top-level category
+	Ancestors     []int    `json:"ancestors" bson:"ancestors"`
+	Description   string   `json:"description,omitempty" bson:"description,omitempty"`
+}
+
+// Path is the category's page on the storefront.
+func (c Category) Path() string {
+	return "/categories/" + url.PathEscape(c.Slug)
+}
+
+// CategoryNode is a category with its subcategories, as the tree is served.
+type CategoryNode struct {
+	Category
+	Children []CategoryNode `json:"children"`
+}
+
+// TagPath is the page of the books tagged tag.
+func TagPath(tag string) string {
+	return "/tags/" + url.PathEscape(tag)
+}
+
+// TagCount is a tag with the number of books carrying it.
+type TagCount struct {
+	Tag   string `json:"tag" bson:"_id"`
+	Books int    `json:"books" bson:"books"`
+}
+
+// Path is the page of the tag's books.
+func (t TagCount) Path() string {
+	return TagPath(t.Tag)
 }
 
 type User struct {
-	ID       int    `json:"id" bson:"id"`
-	Email    string `json:"email" bson:"email"`
-	Password string `json:"password" bson:"password"`
-	Role     string `json:"role" bson:"role"`
-	Address  string `json:"address,omitempty" bson:"address,omitempty"`
+	ID        int        `json:"id" bson:"id"`
+	Email     string     `json:"email" bson:"email"`
+	Password  string     `json:"password" bson:"password"`
+	Role      string     `json:"role" bson:"role"`
+	Address   string     `json:"address,omitempty" bson:"address,omitempty"`
+	Name      string     `json:"name,omitempty" bson:"name,omitempty"`
+	Phone     string     `json:"phone,omitempty" bson:"phone,omitempty"`
+	Addresses []Address  `json:"addresses" bson:"addresses"`
+	CreatedAt time.Time  `json:"createdAt" bson:"createdAt"`
+	DeletedAt *time.Time `json:"deletedAt,omitempty" bson:"deletedAt,omitempty"`
+}
+
+type Address struct {
+	ID         int    `json:"id" bson:"id"`
+	Label      string `json:"label,omitempty" bson:"label,omitempty" validate:"max=40"`
+	Recipient  string `json:"recipient" bson:"recipient" validate:"required,max=100"`
+	Line1      string `json:"line1" bson:"line1" validate:"required,max=200"`
+	Line2      string `json:"line2,omitempty" bson:"line2,omitempty" validate:"max=200"`
+	City       string `json:"city" bson:"city" validate:"required,max=100"`
+	PostalCode string `json:"postalCode" bson:"postalCode" validate:"max=20"`
+	Country    string `json:"country" bson:"country" validate:"required,max=60"`
+	Default    bool   `json:"default" bson:"default"`
+}
+
+type Session struct {
+	ID        int        `json:"id" bson:"id"`
+	UserID    int        `json:"userId" bson:"userId"`
+	UserAgent string     `json:"userAgent" bson:"userAgent"`
+	IP        string     `json:"ip" bson:"ip"`
+	CreatedAt time.Time  `json:"createdAt" bson:"createdAt"`
+	ExpiresAt time.Time  `json:"expiresAt" bson:"expiresAt"`
+	RevokedAt *time.Time `json:"revokedAt,omitempty" bson:"revokedAt,omitempty"`
 }
 
 type Cart struct {
 	ID         int
-	CustomerID int
+	CustomerID int `validate:"min=1"`
 	CreatedAt  time.Time
 }
 
@@ -32,11 +241,21 @@ type CartItem struct {
 	Qty    int
 }
 
+// Order statuses. Orders written before statuses existed are backfilled to
+// OrderPlaced by migration 5.
+const (
+	OrderPlaced    = "placed"
+	OrderCancelled = "cancelled"
+)
+
 type Order struct {
-	ID         int     `json:"id" bson:"id"`
-	CustomerID int     `json:"customerId" bson:"customerId"`
-	CartID     int     `json:"cartId" bson:"cartId"`
-	Total      float64 `json:"total" bson:"total"`
+	ID          int        `json:"id" bson:"id"`
+	CustomerID  int        `json:"customerId" bson:"customerId"`
+	CartID      int        `json:"cartId" bson:"cartId"`
+	Total       float64    `json:"total" bson:"total" validate:"min=0"`
+	Status      string     `json:"status" bson:"status"`
+	CreatedAt   time.Time  `json:"createdAt" bson:"createdAt"`
+	CancelledAt *time.Time `json:"cancelledAt,omitempty" bson:"cancelledAt,omitempty"`
 }
 
 type OrderItem struct {
@@ -54,6 +273,71 @@ type Payment struct {
 	Status  string
 }
 
+// FailedJob is a background job that returned an error, kept so that an
+// operator can inspect and retry it.
+type FailedJob struct {
+	ID         int       `json:"id" bson:"id"`
+	Type       string    `json:"type" bson:"type"`
+	OrderID    int       `json:"orderId,omitempty" bson:"orderId,omitempty"`
+	CartID     int       `json:"cartId,omitempty" bson:"cartId,omitempty"`
+	WishlistID int       `json:"wishlistId,omitempty" bson:"wishlistId,omitempty"`
+	RequestID  string    `json:"requestId,omitempty" bson:"requestId,omitempty"`
+	Error      string    `json:"error" bson:"error"`
+	Attempts   int       `json:"attempts" bson:"attempts"`
+	FailedAt   time.Time `json:"failedAt" bson:"failedAt"`
+}
+
+// Review statuses. New and edited reviews wait for a moderator; only
+// approved ones are shown and counted in a book's rating.
+const (
+	ReviewPending  = "pending"
+	ReviewApproved = "approved"
+	ReviewRejected = "rejected"
+)
+
+// Review is a customer's opinion of a book they bought, one per book.
+type Review struct {
+	ID             int       `json:"id" bson:"id"`
+	BookID         int       `json:"bookId" bson:"bookId"`
+	UserID         int       `json:"userId" bson:"userId"`
+	Rating         int       `json:"rating" bson:"rating"`
+	Title          string    `json:"title" bson:"title"`
+	Body           string    `json:"body" bson:"body"`
+	Status         string    `json:"status" bson:"status"`
+	ModerationNote string    `json:"moderationNote,omitempty" bson:"moderationNote,omitempty"`
+	Helpful        int       `json:"helpful" bson:"helpful"` // helpful votes
+	CreatedAt      time.Time `json:"createdAt" bson:"createdAt"`
+	UpdatedAt      time.Time `json:"updatedAt" bson:"updatedAt"`
+}
+
+func (r Review) Stars() string {
+	return stars(r.Rating)
+}
+
+// stars draws n of five stars filled.
+func stars(n int) string {
+	n = min(max(n, 0), 5)
+	return strings.Repeat("★", n) + strings.Repeat("☆", 5-n)
+}
+
+// PopularBooks is the BookID of the Recommendation that ranks the whole
+// catalog by popularity, the fallback when a book has too few affinities.
+const PopularBooks = 0
+
+// Recommendation lists the books most often ordered or wished for together
+// with BookID, best first. Recommendations are recomputed as a whole, all
+// sharing one ComputedAt.
+type Recommendation struct {
+	BookID     int           `json:"bookId" bson:"bookId"`
+	Related    []RelatedBook `json:"related" bson:"related"`
+	ComputedAt time.Time     `json:"computedAt" bson:"computedAt"`
+}
+
+type RelatedBook struct {
+	BookID int     `json:"bookId" bson:"bookId"`
+	Score  float64 `json:"score" bson:"score"`
+}
+
 type Wishlist struct {
 	ID         int `json:"id" bson:"id"`
 	CustomerID int `json:"customerId" bson:"customerId"`
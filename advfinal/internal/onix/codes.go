@@ -0,0 +1,168 @@
+package onix
+
+import "bookstore/internal/models"
+
+// shortTags maps the ONIX 3.0 short tags of the elements this package reads
+// to their reference names. Unknown short tags are reported under their
+// short name.
+var shortTags = map[string]string{
+	"ONIXmessage":       "ONIXMessage",
+	"header":            "Header",
+	"product":           "Product",
+	"a001":              "RecordReference",
+	"a002":              "NotificationType",
+	"a199":              "DeletionText",
+	"productidentifier": "ProductIdentifier",
+	"b221":              "ProductIDType",
+	"b244":              "IDValue",
+	"descriptivedetail": "DescriptiveDetail",
+	"titledetail":       "TitleDetail",
+	"b202":              "TitleType",
+	"titleelement":      "TitleElement",
+	"x409":              "TitleElementLevel",
+	"b203":              "TitleText",
+	"b030":              "TitlePrefix",
+	"b031":              "TitleWithoutPrefix",
+	"b029":              "Subtitle",
+	"contributor":       "Contributor",
+	"b034":              "SequenceNumber",
+	"b035":              "ContributorRole",
+	"b036":              "PersonName",
+	"b037":              "PersonNameInverted",
+	"b039":              "NamesBeforeKey",
+	"b040":              "KeyNames",
+	"b047":              "CorporateName",
+	"subject":           "Subject",
+	"x425":              "MainSubject",
+	"b067":              "SubjectSchemeIdentifier",
+	"b069":              "SubjectCode",
+	"b070":              "SubjectHeadingText",
+	"b012":              "ProductForm",
+	"extent":            "Extent",
+	"b218":              "ExtentType",
+	"b219":              "ExtentValue",
+	"b220":              "ExtentUnit",
+	"language":          "Language",
+	"b253":              "LanguageRole",
+	"b252":              "LanguageCode",
+	"publishingdetail":  "PublishingDetail",
+	"publisher":         "Publisher",
+	"b291":              "PublishingRole",
+	"b081":              "PublisherName",
+	"publishingdate":    "PublishingDate",
+	"x448":              "PublishingDateRole",
+	"b306":              "Date",
+	"collateraldetail":  "CollateralDetail",
+	"textcontent":       "TextContent",
+	"x426":              "TextType",
+	"x427":              "ContentAudience",
+	"d104":              "Text",
+	"productsupply":     "ProductSupply",
+	"supplydetail":      "SupplyDetail",
+	"j396":              "ProductAvailability",
+	"price":             "Price",
+	"x462":              "PriceType",
+	"j151":              "PriceAmount",
+	"j152":              "CurrencyCode",
+}
+
+func refName(tag string) string {
+	if ref, ok := shortTags[tag]; ok {
+		return ref
+	}
+	return tag
+}
+
+// Code list 1, notification or update type.
+func notificationAction(code string) (Action, bool) {
+	switch code {
+	case "01", "02", "03", "08", "09":
+		return Replace, true
+	case "04":
+		return Update, true
+	case "05":
+		return Delete, true
+	case "88", "89":
+		return Skip, true
+	}
+	return 0, false
+}
+
+// Code list 5, product identifier type.
+const (
+	idGTIN13 = "03"
+	idISBN13 = "15"
+)
+
+// format maps code list 150, product form.
+func format(code string) string {
+	switch {
+	case code == "":
+		return ""
+	case code == "BB":
+		return models.FormatHardcover
+	case code == "BC":
+		return models.FormatPaperback
+	case code[0] == 'E':
+		return models.FormatEbook
+	case code[0] == 'A':
+		return models.FormatAudiobook
+	}
+	return models.FormatOther
+}
+
+// Code list 23, extent type, and list 24, extent unit.
+const (
+	extentMainContent = "00"
+	extentPages       = "03"
+)
+
+// Code list 22, language role.
+const languageOfText = "01"
+
+// Code list 45, publishing role, and list 163, publishing date role.
+const (
+	publishingRolePublisher = "01"
+	dateRolePublication     = "01"
+)
+
+// Code list 15, title type, and list 149, title element level.
+const (
+	titleDistinctive  = "01"
+	titleLevelProduct = "01"
+)
+
+// Code list 17, contributor role: the roles that make someone an author.
+var authorRoles = map[string]bool{
+	"A01": true, // by (author)
+	"A02": true, // with
+	"A03": true, // screenplay by
+	"A06": true, // composer
+}
+
+// Code list 153, text type.
+const (
+	textShortDescription = "02"
+	textDescription      = "03"
+)
+
+// Code list 58, price type.
+const (
+	priceRRPExTax  = "01"
+	priceRRPIncTax = "02"
+)
+
+// availability maps code list 65, product availability. Codes that say
+// nothing definite, such as 99 (contact supplier), map to "".
+func availability(code string) string {
+	switch code {
+	case "10", "11", "12":
+		return models.BookPreorder
+	case "20", "21", "22", "23":
+		return models.BookAvailable
+	}
+	if len(code) == 2 && code[0] >= '3' && code[0] <= '5' {
+		return models.BookUnavailable
+	}
+	return ""
+}
@@ -0,0 +1,314 @@
+// Package onix reads ONIX for Books 3.0 messages, the XML format publishers
+// use to send catalog metadata, and maps each product to a models.Book.
+//
+// Both reference and short tag names are accepted. Products are read one at
+// a time, so a message of any size is parsed in constant memory.
+package onix
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"bookstore/internal/models"
+	"bookstore/internal/validate"
+)
+
+// Action says what a product record asks the receiver to do.
+type Action int
+
+const (
+	// Replace carries a complete record that replaces any earlier one.
+	Replace Action = iota
+	// Update replaces only the blocks present in the record (notification 04).
+	Update
+	// Delete withdraws the product (notification 05).
+	Delete
+	// Skip marks test records (notifications 88 and 89).
+	Skip
+)
+
+// Product is one <Product> of a message.
+type Product struct {
+	Position int    // 1-based position in the message
+	Ref      string // RecordReference
+	Action   Action
+	Book     models.Book
+
+	// Fields lists the book fields, by their import column name, that an
+	// Update supplies. It is nil for the other actions.
+	Fields []string
+
+	// Errors are values that were present but could not be mapped.
+	Errors []validate.FieldError
+
+	// Unmapped holds the path, relative to <Product>, of every element the
+	// mapping did not use.
+	Unmapped []string
+}
+
+// ErrNotONIX3 is returned for documents that are not ONIX 3.0 messages.
+var ErrNotONIX3 = errors.New("not an ONIX 3.0 message")
+
+type Reader struct {
+	dec      *xml.Decoder
+	currency string
+	started  bool
+	done     bool
+	n        int
+}
+
+// NewReader reads products from r. Prices are taken in currency when the
+// product lists one in it, otherwise in the first currency listed.
+func NewReader(r io.Reader, currency string) *Reader {
+	dec := xml.NewDecoder(r)
+	dec.CharsetReader = charsetReader
+	return &Reader{dec: dec, currency: strings.ToUpper(currency)}
+}
+
+// Next returns the next product, or io.EOF after the last one. Any other
+// error means the document itself is malformed and reading cannot go on.
+func (r *Reader) Next() (Product, error) {
+	if r.done {
+		return Product{}, io.EOF
+	}
+	if !r.started {
+		if err := r.start(); err != nil {
+			return Product{}, err
+		}
+		r.started = true
+	}
+
+	for {
+		tok, err := r.dec.Token()
+		if err == io.EOF {
+			return Product{}, io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return Product{}, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			n := &node{}
+			if err := n.decode(r.dec, t); err != nil {
+				return Product{}, err
+			}
+			if n.name != "Product" {
+				continue // Header, NoProduct and anything else at message level
+			}
+			r.n++
+			return mapProduct(n, r.n, r.currency), nil
+
+		case xml.EndElement:
+			r.done = true
+			return Product{}, io.EOF
+		}
+	}
+}
+
+// start reads up to and including the root element.
+func (r *Reader) start() error {
+	for {
+		tok, err := r.dec.Token()
+		if err == io.EOF {
+			return ErrNotONIX3
+		}
+		if err != nil {
+			return err
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		if refName(start.Name.Local) != "ONIXMessage" {
+			return ErrNotONIX3
+		}
+		for _, a := range start.Attr {
+			if a.Name.Local == "release" && !strings.HasPrefix(a.Value, "3.") {
+				return fmt.Errorf("%w: release %s", ErrNotONIX3, a.Value)
+			}
+		}
+		return nil
+	}
+}
+
+// node is a parsed element. Elements are marked used as the mapping reads
+// them, which is how unmapped data is found.
+type node struct {
+	name string
+	text string
+	kids []*node
+	used bool
+}
+
+func (n *node) decode(dec *xml.Decoder, start xml.StartElement) error {
+	n.name = refName(start.Name.Local)
+
+	// Text may hold XHTML markup, which is flattened rather than mapped.
+	if n.name == "Text" {
+		var b strings.Builder
+		if err := flatten(dec, &b); err != nil {
+			return err
+		}
+		n.text = strings.TrimSpace(b.String())
+		return nil
+	}
+
+	var text strings.Builder
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			kid := &node{}
+			if err := kid.decode(dec, t); err != nil {
+				return err
+			}
+			n.kids = append(n.kids, kid)
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			n.text = strings.TrimSpace(text.String())
+			return nil
+		}
+	}
+}
+
+// flatten writes the character data up to the end of the current element,
+// starting a new line for every block-level XHTML element.
+func flatten(dec *xml.Decoder, b *strings.Builder) error {
+	for depth := 0; ; {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			depth++
+			switch t.Name.Local {
+			case "p", "br", "li", "div", "h1", "h2", "h3", "h4", "h5", "h6":
+				if b.Len() > 0 {
+					b.WriteByte('\n')
+				}
+			}
+		case xml.CharData:
+			b.Write(t)
+		case xml.EndElement:
+			if depth == 0 {
+				return nil
+			}
+			depth--
+		}
+	}
+}
+
+// all returns the children named name.
+func (n *node) all(name string) []*node {
+	if n == nil {
+		return nil
+	}
+	var out []*node
+	for _, k := range n.kids {
+		if k.name == name {
+			out = append(out, k)
+		}
+	}
+	return out
+}
+
+// child returns the first child named name, or nil.
+func (n *node) child(name string) *node {
+	if n == nil {
+		return nil
+	}
+	for _, k := range n.kids {
+		if k.name == name {
+			return k
+		}
+	}
+	return nil
+}
+
+// value returns the text of the first child named name and marks it used.
+func (n *node) value(name string) string {
+	k := n.child(name)
+	if k == nil {
+		return ""
+	}
+	k.used = true
+	return k.text
+}
+
+// has reports whether a flag element such as <MainSubject/> is present.
+func (n *node) has(name string) bool {
+	k := n.child(name)
+	if k != nil {
+		k.used = true
+	}
+	return k != nil
+}
+
+// markAll marks n and everything below it used.
+func (n *node) markAll() {
+	n.used = true
+	for _, k := range n.kids {
+		k.markAll()
+	}
+}
+
+// unmapped collects the paths of unused leaves below n.
+func (n *node) unmapped(prefix string, out []string) []string {
+	for _, k := range n.kids {
+		path := k.name
+		if prefix != "" {
+			path = prefix + "/" + k.name
+		}
+		if len(k.kids) == 0 {
+			if !k.used {
+				out = append(out, path)
+			}
+			continue
+		}
+		out = k.unmapped(path, out)
+	}
+	return out
+}
+
+// charsetReader lets messages declared as Latin-1 through; ONIX 3.0 requires
+// UTF-8 but older feeds still arrive in ISO-8859-1.
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	switch strings.ToLower(charset) {
+	case "iso-8859-1", "latin1", "latin-1":
+		return &latin1Reader{r: input}, nil
+	}
+	return nil, fmt.Errorf("unsupported encoding %q", charset)
+}
+
+type latin1Reader struct {
+	r   io.Reader
+	buf []byte
+}
+
+func (l *latin1Reader) Read(p []byte) (int, error) {
+	// Each Latin-1 byte becomes at most two UTF-8 bytes.
+	if len(p) < 2 {
+		return 0, io.ErrShortBuffer
+	}
+	if cap(l.buf) < len(p)/2 {
+		l.buf = make([]byte, len(p)/2)
+	}
+	in := l.buf[:len(p)/2]
+	n, err := l.r.Read(in)
+	out := p[:0]
+	for _, c := range in[:n] {
+		out = utf8.AppendRune(out, rune(c))
+	}
+	return len(out), err
+}
@@ -0,0 +1,338 @@
+package onix
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"bookstore/internal/models"
+	"bookstore/internal/validate"
+)
+
+// Import column names of the fields each block supplies. A block update
+// replaces all of them, including the ones the block leaves empty, except
+// that a missing price never replaces a known one.
+var (
+	descriptiveFields = []string{"title", "authors", "genre", "format", "pages", "language"}
+	collateralFields  = []string{"description"}
+	publishingFields  = []string{"publisher", "publicationDate"}
+	supplyFields      = []string{"availability"}
+)
+
+func mapProduct(n *node, pos int, currency string) Product {
+	p := Product{Position: pos}
+	p.Ref = n.value("RecordReference")
+	p.Book.ExternalID = p.Ref
+	p.Book.ISBN = isbn(n)
+
+	code := n.value("NotificationType")
+	action, ok := notificationAction(code)
+	if !ok {
+		p.Errors = append(p.Errors, fieldError("notificationType", fmt.Sprintf("unknown notification type %q", code)))
+	}
+	p.Action = action
+
+	// Deletions and test records are not mapped, so nothing in them is
+	// reported as unmapped either.
+	if action == Delete || action == Skip {
+		n.markAll()
+		return p
+	}
+
+	fields := []string{}
+	if dd := n.child("DescriptiveDetail"); dd != nil {
+		p.Book.Title = title(dd)
+		p.Book.Authors = authors(dd)
+		p.Book.Genre = genre(dd)
+		p.Book.Format = format(dd.value("ProductForm"))
+		p.Book.Pages = pages(dd)
+		p.Book.Language = language(dd)
+		fields = append(fields, descriptiveFields...)
+	}
+	if cd := n.child("CollateralDetail"); cd != nil {
+		p.Book.Description = description(cd)
+		fields = append(fields, collateralFields...)
+	}
+	if pd := n.child("PublishingDetail"); pd != nil {
+		p.Book.Publisher = publisher(pd)
+		p.Book.PublicationDate = publicationDate(pd)
+		fields = append(fields, publishingFields...)
+	}
+	ps := n.child("ProductSupply")
+	if ps != nil {
+		p.Book.Availability = supplyAvailability(ps)
+		fields = append(fields, supplyFields...)
+	}
+	if ps != nil || action == Replace {
+		price, err := supplyPrice(ps, currency)
+		switch {
+		case err == nil:
+			p.Book.Price = price
+			fields = append(fields, "price")
+		case p.Book.Availability != models.BookUnavailable:
+			p.Errors = append(p.Errors, fieldError("price", err.Error()))
+		}
+	}
+
+	if action == Update {
+		p.Fields = fields
+	}
+	p.Unmapped = n.unmapped("", nil)
+	return p
+}
+
+func fieldError(field, msg string) validate.FieldError {
+	return validate.FieldError{Field: field, Message: msg}
+}
+
+// isbn returns the ISBN-13, falling back to a GTIN-13 in the ISBN ranges.
+func isbn(n *node) string {
+	gtin := ""
+	for _, id := range n.all("ProductIdentifier") {
+		switch id.value("ProductIDType") {
+		case idISBN13:
+			return id.value("IDValue")
+		case idGTIN13:
+			if v := id.child("IDValue"); v != nil && (strings.HasPrefix(v.text, "978") || strings.HasPrefix(v.text, "979")) {
+				v.used = true
+				gtin = v.text
+			}
+		}
+	}
+	return gtin
+}
+
+// title is the distinctive title of the product, with its subtitle.
+func title(dd *node) string {
+	for _, td := range dd.all("TitleDetail") {
+		if td.value("TitleType") != titleDistinctive {
+			continue
+		}
+		for _, te := range td.all("TitleElement") {
+			if te.value("TitleElementLevel") != titleLevelProduct {
+				continue
+			}
+			t := te.value("TitleText")
+			if t == "" {
+				t = strings.TrimSpace(te.value("TitlePrefix") + " " + te.value("TitleWithoutPrefix"))
+			}
+			if sub := te.value("Subtitle"); sub != "" {
+				t += ": " + sub
+			}
+			return t
+		}
+	}
+	return ""
+}
+
+// authors lists the contributors in sequence order. Only authors are named
+// when there are any; other contributors, such as editors, otherwise. The
+// catalog derives Author from the list.
+func authors(dd *node) []string {
+	type contributor struct {
+		seq    int
+		name   string
+		author bool
+	}
+	var all []contributor
+	for i, c := range dd.all("Contributor") {
+		seq, err := strconv.Atoi(c.value("SequenceNumber"))
+		if err != nil {
+			seq = i + 1
+		}
+		author := false
+		for _, role := range c.all("ContributorRole") {
+			role.used = true
+			author = author || authorRoles[role.text]
+		}
+		if name := contributorName(c); name != "" {
+			all = append(all, contributor{seq: seq, name: name, author: author})
+		}
+	}
+	sort.SliceStable(all, func(i, j int) bool { return all[i].seq < all[j].seq })
+
+	var names, others []string
+	for _, c := range all {
+		if c.author {
+			names = append(names, c.name)
+		} else {
+			others = append(others, c.name)
+		}
+	}
+	if len(names) == 0 {
+		names = others
+	}
+	return names
+}
+
+func contributorName(c *node) string {
+	if name := c.value("PersonName"); name != "" {
+		return name
+	}
+	if key := c.value("KeyNames"); key != "" {
+		return strings.TrimSpace(c.value("NamesBeforeKey") + " " + key)
+	}
+	if name := c.value("PersonNameInverted"); name != "" {
+		return name
+	}
+	return c.value("CorporateName")
+}
+
+// genre is the heading of the main subject, else of the first subject that
+// has one. Subjects given only as codes are left unmapped.
+func genre(dd *node) string {
+	first := ""
+	for _, s := range dd.all("Subject") {
+		h := s.child("SubjectHeadingText")
+		if h == nil || h.text == "" {
+			continue
+		}
+		h.used = true
+		s.value("SubjectSchemeIdentifier")
+		if s.has("MainSubject") {
+			return h.text
+		}
+		if first == "" {
+			first = h.text
+		}
+	}
+	return first
+}
+
+// pages is the main content page count, 0 if not given in pages.
+func pages(dd *node) int {
+	for _, e := range dd.all("Extent") {
+		if e.value("ExtentType") != extentMainContent || e.value("ExtentUnit") != extentPages {
+			continue
+		}
+		n, err := strconv.Atoi(e.value("ExtentValue"))
+		if err == nil {
+			return n
+		}
+	}
+	return 0
+}
+
+// language is the language of the text.
+func language(dd *node) string {
+	for _, l := range dd.all("Language") {
+		if l.value("LanguageRole") == languageOfText {
+			return l.value("LanguageCode")
+		}
+	}
+	return ""
+}
+
+// publisher is the name of the main publisher, else of the first one.
+func publisher(pd *node) string {
+	first := ""
+	for _, p := range pd.all("Publisher") {
+		name := p.value("PublisherName")
+		if p.value("PublishingRole") == publishingRolePublisher {
+			return name
+		}
+		if first == "" {
+			first = name
+		}
+	}
+	return first
+}
+
+// publicationDate converts the publication date to YYYY-MM-DD. Dates given
+// only to the month or year are left unmapped.
+func publicationDate(pd *node) string {
+	for _, d := range pd.all("PublishingDate") {
+		if d.value("PublishingDateRole") != dateRolePublication {
+			continue
+		}
+		v := d.child("Date")
+		if v == nil || len(v.text) != 8 {
+			return ""
+		}
+		v.used = true
+		return v.text[:4] + "-" + v.text[4:6] + "-" + v.text[6:]
+	}
+	return ""
+}
+
+// description prefers the full description over the short one.
+func description(cd *node) string {
+	short := ""
+	for _, tc := range cd.all("TextContent") {
+		switch tc.value("TextType") {
+		case textDescription:
+			tc.value("ContentAudience")
+			return tc.value("Text")
+		case textShortDescription:
+			tc.value("ContentAudience")
+			if t := tc.value("Text"); short == "" {
+				short = t
+			}
+		}
+	}
+	return short
+}
+
+func supplyAvailability(ps *node) string {
+	for _, sd := range ps.all("SupplyDetail") {
+		if a := availability(sd.value("ProductAvailability")); a != "" {
+			return a
+		}
+	}
+	return ""
+}
+
+// supplyPrice picks the recommended retail price, with tax if given, in
+// currency. An empty currency accepts the first one listed.
+func supplyPrice(ps *node, currency string) (float64, error) {
+	var best *node
+	rank := func(pr *node) int {
+		switch pr.value("PriceType") {
+		case priceRRPIncTax:
+			return 2
+		case priceRRPExTax:
+			return 1
+		}
+		return 0
+	}
+
+	// Prices in other currencies are deliberately ignored, not unmapped.
+	want := currency
+	var considered []*node
+	for _, sd := range ps.all("SupplyDetail") {
+		for _, pr := range sd.all("Price") {
+			cur := strings.ToUpper(pr.value("CurrencyCode"))
+			if want == "" {
+				want = cur
+			}
+			if cur != want {
+				pr.markAll()
+				continue
+			}
+			considered = append(considered, pr)
+			if r := rank(pr); best == nil || r > rank(best) {
+				best = pr
+			}
+		}
+	}
+	if best == nil {
+		if currency == "" {
+			return 0, errors.New("product has no price")
+		}
+		return 0, fmt.Errorf("product has no price in %s", currency)
+	}
+	for _, pr := range considered {
+		if pr != best {
+			pr.markAll()
+		}
+	}
+
+	amount := best.value("PriceAmount")
+	price, err := strconv.ParseFloat(amount, 64)
+	if err != nil {
+		return 0, fmt.Errorf("price amount %q is not a number", amount)
+	}
+	return price, nil
+}
@@ -2,67 +2,249 @@ package repository
 
 import (
 	"context"
-	"errors"
-	"time"
+	"strings"
 
 	"bookstore/internal/models"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type BookRepository interface {
-	Create(book models.Book) (models.Book, error)
-	GetByID(id int) (models.Book, error)
-	GetAll() []models.Book
-	Update(book models.Book) error
-	Delete(id int) error
+	Create(ctx context.Context, book models.Book) (models.Book, error)
+	GetByID(ctx context.Context, id int) (models.Book, error)
+	GetByISBN(ctx context.Context, isbn string) (models.Book, error)
+	GetByExternalID(ctx context.Context, externalID string) (models.Book, error)
+	GetBySlug(ctx context.Context, slug string) (models.Book, error)
+	GetAll(ctx context.Context) []models.Book
+	ListByEntity(ctx context.Context, kind string, id int) ([]models.Book, error)
+	ListByCategories(ctx context.Context, ids []int) ([]models.Book, error)
+	ListByTag(ctx context.Context, tag string) ([]models.Book, error)
+	TagCounts(ctx context.Context) ([]models.TagCount, error)
+	Each(ctx context.Context, fn func(models.Book) error) error
+	Update(ctx context.Context, book models.Book) error
+	SetCover(ctx context.Context, id int, cover string) error
+	SetRating(ctx context.Context, id int, rating float64, count int) error
+	Delete(ctx context.Context, id int) error
+}
+
+var (
+	errISBNTaken = &Error{Kind: ErrConflict, Field: "isbn", Msg: "another book has this isbn"}
+	errSlugTaken = &Error{Kind: ErrConflict, Field: "slug", Msg: "another book has this slug"}
+)
+
+// bookWriteError tells clashes on the unique ISBN and slug indexes
+// (migrations 8 and 9) from other failures.
+func bookWriteError(err error) error {
+	switch {
+	case !mongo.IsDuplicateKeyError(err):
+		return err
+	case strings.Contains(err.Error(), "isbn_unique"):
+		return errISBNTaken
+	case strings.Contains(err.Error(), "slug_unique"):
+		return errSlugTaken
+	}
+	return err
 }
 
 type BookRepo struct {
 	col      *mongo.Collection
 	counters *CounterRepo
+	timeouts Timeouts
 }
 
-func NewBookRepo(db *mongo.Database) *BookRepo {
+func NewBookRepo(db *mongo.Database, t Timeouts) *BookRepo {
 	return &BookRepo{
 		col:      db.Collection("books"),
-		counters: NewCounterRepo(db),
+		counters: NewCounterRepo(db, t),
+		timeouts: t,
 	}
 }
 
-func (r *BookRepo) Create(book models.Book) (models.Book, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (r *BookRepo) Create(ctx context.Context, book models.Book) (_ models.Book, err error) {
+	ctx, done := instrument(ctx, "BookRepo", "Create")
+	defer done(&err)
+
+	ctx, cancel := r.timeouts.write(ctx)
 	defer cancel()
 
-	id, err := r.counters.Next("books")
+	id, err := r.counters.Next(ctx, "books")
 	if err != nil {
 		return models.Book{}, err
 	}
 	book.ID = id
 
-	_, err = r.col.InsertOne(ctx, book)
-	if err != nil {
-		return models.Book{}, err
+	if _, err := r.col.InsertOne(ctx, book); err != nil {
+		return models.Book{}, bookWriteError(err)
 	}
 
 	return book, nil
 }
 
-func (r *BookRepo) GetByID(id int) (models.Book, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (r *BookRepo) GetByID(ctx context.Context, id int) (_ models.Book, err error) {
+	ctx, done := instrument(ctx, "BookRepo", "GetByID")
+	defer done(&err)
+
+	ctx, cancel := r.timeouts.read(ctx)
+	defer cancel()
+
+	var b models.Book
+	err = r.col.FindOne(ctx, bson.M{"id": id}).Decode(&b)
+	if err == mongo.ErrNoDocuments {
+		return models.Book{}, NotFound("book not found")
+	}
+	return b, err
+}
+
+func (r *BookRepo) GetByISBN(ctx context.Context, isbn string) (_ models.Book, err error) {
+	ctx, done := instrument(ctx, "BookRepo", "GetByISBN")
+	defer done(&err)
+
+	ctx, cancel := r.timeouts.read(ctx)
+	defer cancel()
+
+	var b models.Book
+	err = r.col.FindOne(ctx, bson.M{"isbn": isbn}).Decode(&b)
+	if err == mongo.ErrNoDocuments {
+		return models.Book{}, NotFound("book not found")
+	}
+	return b, err
+}
+
+func (r *BookRepo) GetByExternalID(ctx context.Context, externalID string) (_ models.Book, err error) {
+	ctx, done := instrument(ctx, "BookRepo", "GetByExternalID")
+	defer done(&err)
+
+	ctx, cancel := r.timeouts.read(ctx)
 	defer cancel()
 
 	var b models.Book
-	err := r.col.FindOne(ctx, bson.M{"id": id}).Decode(&b)
+	err = r.col.FindOne(ctx, bson.M{"externalId": externalID}).Decode(&b)
 	if err == mongo.ErrNoDocuments {
-		return models.Book{}, errors.New("book not found")
+		return models.Book{}, NotFound("book not found")
 	}
 	return b, err
 }
 
-func (r *BookRepo) GetAll() []models.Book {
-	ctx, cancel := context.WithTimeout(context.Background(), 8*time.Second)
+// GetBySlug also finds a book by a slug it no longer uses.
+func (r *BookRepo) GetBySlug(ctx context.Context, slug string) (_ models.Book, err error) {
+	ctx, done := instrument(ctx, "BookRepo", "GetBySlug")
+	defer done(&err)
+
+	ctx, cancel := r.timeouts.read(ctx)
+	defer cancel()
+
+	var b models.Book
+	err = r.col.FindOne(ctx, bson.M{"$or": bson.A{
+		bson.M{"slug": slug},
+		bson.M{"previousSlugs": slug},
+	}}).Decode(&b)
+	if err == mongo.ErrNoDocuments {
+		return models.Book{}, NotFound("book not found")
+	}
+	return b, err
+}
+
+// entityFields are the book fields referring to each entity kind.
+var entityFields = map[string]string{
+	models.EntityAuthor:    "authorIds",
+	models.EntityPublisher: "publisherId",
+	models.EntitySeries:    "seriesId",
+	models.EntityGenre:     "genreId",
+}
+
+// ListByEntity returns the books referring to the entity of kind with id,
+// in id order.
+func (r *BookRepo) ListByEntity(ctx context.Context, kind string, id int) (_ []models.Book, err error) {
+	ctx, done := instrument(ctx, "BookRepo", "ListByEntity")
+	defer done(&err)
+
+	return r.list(ctx, bson.M{entityFields[kind]: id})
+}
+
+// ListByCategories returns the books in any of the categories ids, in id
+// order.
+func (r *BookRepo) ListByCategories(ctx context.Context, ids []int) (_ []models.Book, err error) {
+	ctx, done := instrument(ctx, "BookRepo", "ListByCategories")
+	defer done(&err)
+
+	return r.list(ctx, bson.M{"categoryIds": bson.M{"$in": ids}})
+}
+
+// ListByTag returns the books tagged tag, in id order.
+func (r *BookRepo) ListByTag(ctx context.Context, tag string) (_ []models.Book, err error) {
+	ctx, done := instrument(ctx, "BookRepo", "ListByTag")
+	defer done(&err)
+
+	return r.list(ctx, bson.M{"tags": tag})
+}
+
+func (r *BookRepo) list(ctx context.Context, filter bson.M) ([]models.Book, error) {
+	ctx, cancel := r.timeouts.read(ctx)
+	defer cancel()
+
+	cur, err := r.col.Find(ctx, filter, options.Find().SetSort(bson.M{"id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	out := []models.Book{}
+	err = cur.All(ctx, &out)
+	return out, err
+}
+
+// TagCounts returns every tag in use with the number of its books, most
+// used first.
+func (r *BookRepo) TagCounts(ctx context.Context) (_ []models.TagCount, err error) {
+	ctx, done := instrument(ctx, "BookRepo", "TagCounts")
+	defer done(&err)
+
+	ctx, cancel := r.timeouts.read(ctx)
+	defer cancel()
+
+	cur, err := r.col.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$unwind", Value: "$tags"}},
+		{{Key: "$group", Value: bson.M{"_id": "$tags", "books": bson.M{"$sum": 1}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "books", Value: -1}, {Key: "_id", Value: 1}}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	out := []models.TagCount{}
+	err = cur.All(ctx, &out)
+	return out, err
+}
+
+// Each streams every book in id order to fn and stops at its first error.
+// Only ctx bounds it: a full catalog can take longer than the read timeout.
+func (r *BookRepo) Each(ctx context.Context, fn func(models.Book) error) (err error) {
+	ctx, done := instrument(ctx, "BookRepo", "Each")
+	defer done(&err)
+
+	cur, err := r.col.Find(ctx, bson.M{}, options.Find().SetSort(bson.M{"id": 1}))
+	if err != nil {
+		return err
+	}
+	defer cur.Close(ctx)
+
+	for cur.Next(ctx) {
+		var b models.Book
+		if err := cur.Decode(&b); err != nil {
+			return err
+		}
+		if err := fn(b); err != nil {
+			return err
+		}
+	}
+	return cur.Err()
+}
+
+func (r *BookRepo) GetAll(ctx context.Context) []models.Book {
+	var err error
+	ctx, done := instrument(ctx, "BookRepo", "GetAll")
+	defer done(&err)
+
+	ctx, cancel := r.timeouts.read(ctx)
 	defer cancel()
 
 	cur, err := r.col.Find(ctx, bson.M{})
@@ -81,22 +263,90 @@ func (r *BookRepo) GetAll() []models.Book {
 	return out
 }
 
-func (r *BookRepo) Update(book models.Book) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+// Update writes a book's fields other than its cover and rating; see
+// bookFields.
+func (r *BookRepo) Update(ctx context.Context, book models.Book) (err error) {
+	ctx, done := instrument(ctx, "BookRepo", "Update")
+	defer done(&err)
+
+	ctx, cancel := r.timeouts.write(ctx)
 	defer cancel()
 
-	res, err := r.col.UpdateOne(ctx, bson.M{"id": book.ID}, bson.M{"$set": book})
+	fields, err := bookFields(book)
 	if err != nil {
 		return err
 	}
+	res, err := r.col.UpdateOne(ctx, bson.M{"id": book.ID}, bson.M{"$set": fields})
+	if err != nil {
+		return bookWriteError(err)
+	}
 	if res.MatchedCount == 0 {
-		return errors.New("book not found")
+		return NotFound("book not found")
 	}
 	return nil
 }
 
-func (r *BookRepo) Delete(id int) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+// bookFields is what Update writes: everything but the cover and rating,
+// which only SetCover and SetRating change. Callers read a book, edit it and
+// write it back, and must not undo an upload or a review that landed in
+// between.
+func bookFields(book models.Book) (bson.M, error) {
+	raw, err := bson.Marshal(book)
+	if err != nil {
+		return nil, err
+	}
+	var fields bson.M
+	if err := bson.Unmarshal(raw, &fields); err != nil {
+		return nil, err
+	}
+	delete(fields, "cover")
+	delete(fields, "rating")
+	delete(fields, "ratingCount")
+	return fields, nil
+}
+
+// SetCover changes only the cover, so it cannot undo a concurrent edit.
+func (r *BookRepo) SetCover(ctx context.Context, id int, cover string) (err error) {
+	ctx, done := instrument(ctx, "BookRepo", "SetCover")
+	defer done(&err)
+
+	ctx, cancel := r.timeouts.write(ctx)
+	defer cancel()
+
+	res, err := r.col.UpdateOne(ctx, bson.M{"id": id}, bson.M{"$set": bson.M{"cover": cover}})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return NotFound("book not found")
+	}
+	return nil
+}
+
+// SetRating stores a book's review average and count, like SetCover leaving
+// the rest of the book alone.
+func (r *BookRepo) SetRating(ctx context.Context, id int, rating float64, count int) (err error) {
+	ctx, done := instrument(ctx, "BookRepo", "SetRating")
+	defer done(&err)
+
+	ctx, cancel := r.timeouts.write(ctx)
+	defer cancel()
+
+	res, err := r.col.UpdateOne(ctx, bson.M{"id": id}, bson.M{"$set": bson.M{"rating": rating, "ratingCount": count}})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return NotFound("book not found")
+	}
+	return nil
+}
+
+func (r *BookRepo) Delete(ctx context.Context, id int) (err error) {
+	ctx, done := instrument(ctx, "BookRepo", "Delete")
+	defer done(&err)
+
+	ctx, cancel := r.timeouts.write(ctx)
 	defer cancel()
 
 	res, err := r.col.DeleteOne(ctx, bson.M{"id": id})
@@ -104,7 +354,7 @@ func (r *BookRepo) Delete(id int) error {
 		return err
 	}
 	if res.DeletedCount == 0 {
-		return errors.New("book not found")
+		return NotFound("book not found")
 	}
 	return nil
 }
@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"testing"
+
+	"bookstore/internal/models"
+)
+
+func TestBookFieldsLeaveCoverAndRating(t *testing.T) {
+	fields, err := bookFields(models.Book{ID: 1, Title: "Dune", Cover: "v1", Rating: 4.5, RatingCount: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"cover", "rating", "ratingCount"} {
+		if _, ok := fields[key]; ok {
+			t.Errorf("Update would overwrite %q", key)
+		}
+	}
+	if fields["title"] != "Dune" {
+		t.Errorf("title = %v, want Dune", fields["title"])
+	}
+}
+
+func TestBookFieldsUseCamelCase(t *testing.T) {
+	fields, err := bookFields(models.Book{ID: 1, ExternalID: "x", PublicationDate: "2001-01-01",
+		AuthorIDs: []int{2}, SeriesID: 3, SeriesPosition: 1, PreviousSlugs: []string{"old"}, CategoryIDs: []int{4}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, field := range entityFields {
+		if _, ok := fields[field]; !ok {
+			t.Errorf("%s books are looked up by %q, which Book does not store", key, field)
+		}
+	}
+	for _, key := range []string{"externalId", "publicationDate", "previousSlugs", "seriesPosition", "categoryIds"} {
+		if _, ok := fields[key]; !ok {
+			t.Errorf("no %q in %v", key, fields)
+		}
+	}
+}
@@ -1,24 +1,25 @@
 package repository
 
 import (
-	"errors"
+	"context"
 	"sync"
 
 	"bookstore/internal/models"
+	"bookstore/internal/tracing"
 )
 
 type CartRepository interface {
-	Create(customerID int) models.Cart
-	GetAll() []models.Cart
-	GetByID(id int) (models.Cart, []models.CartItem, error)
-	Update(cart models.Cart) error
-	Delete(id int) error
+	Create(ctx context.Context, customerID int) models.Cart
+	GetAll(ctx context.Context) []models.Cart
+	GetByID(ctx context.Context, id int) (models.Cart, []models.CartItem, error)
+	Update(ctx context.Context, cart models.Cart) error
+	Delete(ctx context.Context, id int) error
 
-	AddItem(cartID int, bookID int, qty int) (models.CartItem, error)
-	UpdateItem(cartID int, itemID int, qty int) error
-	DeleteItem(cartID int, itemID int) error
+	AddItem(ctx context.Context, cartID int, bookID int, qty int) (models.CartItem, error)
+	UpdateItem(ctx context.Context, cartID int, itemID int, qty int) error
+	DeleteItem(ctx context.Context, cartID int, itemID int) error
 
-	ClearCart(cartID int) error
+	ClearCart(ctx context.Context, cartID int) error
 }
 
 type CartRepo struct {
@@ -40,7 +41,10 @@ func NewCartRepo() *CartRepo {
 	}
 }
 
-func (r *CartRepo) Create(customerID int) models.Cart {
+func (r *CartRepo) Create(ctx context.Context, customerID int) models.Cart {
+	_, span := tracing.Start(ctx, "CartRepo.Create")
+	defer span.End()
+
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
@@ -54,7 +58,10 @@ func (r *CartRepo) Create(customerID int) models.Cart {
 	return c
 }
 
-func (r *CartRepo) GetAll() []models.Cart {
+func (r *CartRepo) GetAll(ctx context.Context) []models.Cart {
+	_, span := tracing.Start(ctx, "CartRepo.GetAll")
+	defer span.End()
+
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
@@ -65,50 +72,62 @@ func (r *CartRepo) GetAll() []models.Cart {
 	return out
 }
 
-func (r *CartRepo) GetByID(id int) (models.Cart, []models.CartItem, error) {
+func (r *CartRepo) GetByID(ctx context.Context, id int) (_ models.Cart, _ []models.CartItem, err error) {
+	_, span := tracing.Start(ctx, "CartRepo.GetByID")
+	defer func() { endSpan(span, err) }()
+
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
 	c, ok := r.carts[id]
 	if !ok {
-		return models.Cart{}, nil, errors.New("cart not found")
+		return models.Cart{}, nil, NotFound("cart not found")
 	}
 	items := append([]models.CartItem(nil), r.items[id]...)
 	return c, items, nil
 }
 
-func (r *CartRepo) Update(cart models.Cart) error {
+func (r *CartRepo) Update(ctx context.Context, cart models.Cart) (err error) {
+	_, span := tracing.Start(ctx, "CartRepo.Update")
+	defer func() { endSpan(span, err) }()
+
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
 	if _, ok := r.carts[cart.ID]; !ok {
-		return errors.New("cart not found")
+		return NotFound("cart not found")
 	}
 	r.carts[cart.ID] = cart
 	return nil
 }
 
-func (r *CartRepo) Delete(id int) error {
+func (r *CartRepo) Delete(ctx context.Context, id int) (err error) {
+	_, span := tracing.Start(ctx, "CartRepo.Delete")
+	defer func() { endSpan(span, err) }()
+
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
 	if _, ok := r.carts[id]; !ok {
-		return errors.New("cart not found")
+		return NotFound("cart not found")
 	}
 	delete(r.carts, id)
 	delete(r.items, id)
 	return nil
 }
 
-func (r *CartRepo) AddItem(cartID int, bookID int, qty int) (models.CartItem, error) {
+func (r *CartRepo) AddItem(ctx context.Context, cartID int, bookID int, qty int) (_ models.CartItem, err error) {
+	_, span := tracing.Start(ctx, "CartRepo.AddItem")
+	defer func() { endSpan(span, err) }()
+
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
 	if _, ok := r.carts[cartID]; !ok {
-		return models.CartItem{}, errors.New("cart not found")
+		return models.CartItem{}, NotFound("cart not found")
 	}
 	if qty <= 0 {
-		return models.CartItem{}, errors.New("qty must be positive")
+		return models.CartItem{}, Invalid("qty", "qty must be positive")
 	}
 
 	items := r.items[cartID]
@@ -131,12 +150,15 @@ func (r *CartRepo) AddItem(cartID int, bookID int, qty int) (models.CartItem, er
 	return it, nil
 }
 
-func (r *CartRepo) UpdateItem(cartID int, itemID int, qty int) error {
+func (r *CartRepo) UpdateItem(ctx context.Context, cartID int, itemID int, qty int) (err error) {
+	_, span := tracing.Start(ctx, "CartRepo.UpdateItem")
+	defer func() { endSpan(span, err) }()
+
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
 	if qty <= 0 {
-		return errors.New("qty must be positive")
+		return Invalid("qty", "qty must be positive")
 	}
 
 	items := r.items[cartID]
@@ -147,10 +169,13 @@ func (r *CartRepo) UpdateItem(cartID int, itemID int, qty int) error {
 			return nil
 		}
 	}
-	return errors.New("item not found")
+	return NotFound("item not found")
 }
 
-func (r *CartRepo) DeleteItem(cartID int, itemID int) error {
+func (r *CartRepo) DeleteItem(ctx context.Context, cartID int, itemID int) (err error) {
+	_, span := tracing.Start(ctx, "CartRepo.DeleteItem")
+	defer func() { endSpan(span, err) }()
+
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
@@ -165,18 +190,21 @@ func (r *CartRepo) DeleteItem(cartID int, itemID int) error {
 		out = append(out, it)
 	}
 	if !found {
-		return errors.New("item not found")
+		return NotFound("item not found")
 	}
 	r.items[cartID] = out
 	return nil
 }
 
-func (r *CartRepo) ClearCart(cartID int) error {
+func (r *CartRepo) ClearCart(ctx context.Context, cartID int) (err error) {
+	_, span := tracing.Start(ctx, "CartRepo.ClearCart")
+	defer func() { endSpan(span, err) }()
+
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
 	if _, ok := r.carts[cartID]; !ok {
-		return errors.New("cart not found")
+		return NotFound("cart not found")
 	}
 	r.items[cartID] = []models.CartItem{}
 	return nil
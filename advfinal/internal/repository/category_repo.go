@@ -0,0 +1,175 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"bookstore/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type CategoryRepository interface {
+	Create(ctx context.Context, c models.Category) (models.Category, error)
+	GetByID(ctx context.Context, id int) (models.Category, error)
+	GetBySlug(ctx context.Context, slug string) (models.Category, error)
+	List(ctx context.Context) ([]models.Category, error)
+	Children(ctx context.Context, id int) ([]models.Category, error)
+	Descendants(ctx context.Context, id int) ([]models.Category, error)
+	Update(ctx context.Context, c models.Category) error
+	Delete(ctx context.Context, id int) error
+}
+
+var errCategorySlugTaken = &Error{Kind: ErrConflict, Field: "name", Msg: "another category has this slug"}
+
+type CategoryRepo struct {
+	col      *mongo.Collection
+	counters *CounterRepo
+	timeouts Timeouts
+}
+
+func NewCategoryRepo(db *mongo.Database, t Timeouts) *CategoryRepo {
+	return &CategoryRepo{
+		col:      db.Collection("categories"),
+		counters: NewCounterRepo(db, t),
+		timeouts: t,
+	}
+}
+
+func (r *CategoryRepo) Create(ctx context.Context, c models.Category) (_ models.Category, err error) {
+	ctx, done := instrument(ctx, "CategoryRepo", "Create")
+	defer done(&err)
+
+	ctx, cancel := r.timeouts.write(ctx)
+	defer cancel()
+
+	id, err := r.counters.Next(ctx, "categories")
+	if err != nil {
+		return models.Category{}, err
+	}
+	c.ID = id
+	if c.PreviousSlugs == nil {
+		c.PreviousSlugs = []string{}
+	}
+	if c.Ancestors == nil {
+		c.Ancestors = []int{}
+	}
+
+	_, err = r.col.InsertOne(ctx, c)
+	if mongo.IsDuplicateKeyError(err) {
+		return models.Category{}, errCategorySlugTaken
+	}
+	if err != nil {
+		return models.Category{}, err
+	}
+	return c, nil
+}
+
+func (r *CategoryRepo) GetByID(ctx context.Context, id int) (_ models.Category, err error) {
+	ctx, done := instrument(ctx, "CategoryRepo", "GetByID")
+	defer done(&err)
+
+	return r.findOne(ctx, bson.M{"id": id})
+}
+
+// GetBySlug also finds a category by a slug it no longer uses, preferring
+// the one whose current slug it is.
+func (r *CategoryRepo) GetBySlug(ctx context.Context, slug string) (_ models.Category, err error) {
+	ctx, done := instrument(ctx, "CategoryRepo", "GetBySlug")
+	defer done(&err)
+
+	c, err := r.findOne(ctx, bson.M{"slug": slug})
+	if !errors.Is(err, ErrNotFound) {
+		return c, err
+	}
+	return r.findOne(ctx, bson.M{"previousSlugs": slug})
+}
+
+func (r *CategoryRepo) findOne(ctx context.Context, filter bson.M) (models.Category, error) {
+	ctx, cancel := r.timeouts.read(ctx)
+	defer cancel()
+
+	var c models.Category
+	err := r.col.FindOne(ctx, filter).Decode(&c)
+	if err == mongo.ErrNoDocuments {
+		return models.Category{}, NotFound("category not found")
+	}
+	return c, err
+}
+
+// List returns the whole tree, flat and by name.
+func (r *CategoryRepo) List(ctx context.Context) (_ []models.Category, err error) {
+	ctx, done := instrument(ctx, "CategoryRepo", "List")
+	defer done(&err)
+
+	return r.find(ctx, bson.M{})
+}
+
+// Children returns the categories directly under id, by name; id 0 gives
+// the top-level ones.
+func (r *CategoryRepo) Children(ctx context.Context, id int) (_ []models.Category, err error) {
+	ctx, done := instrument(ctx, "CategoryRepo", "Children")
+	defer done(&err)
+
+	return r.find(ctx, bson.M{"parentId": id})
+}
+
+// Descendants returns every category below id, at any depth, by name.
+func (r *CategoryRepo) Descendants(ctx context.Context, id int) (_ []models.Category, err error) {
+	ctx, done := instrument(ctx, "CategoryRepo", "Descendants")
+	defer done(&err)
+
+	return r.find(ctx, bson.M{"ancestors": id})
+}
+
+func (r *CategoryRepo) find(ctx context.Context, filter bson.M) ([]models.Category, error) {
+	ctx, cancel := r.timeouts.read(ctx)
+	defer cancel()
+
+	cur, err := r.col.Find(ctx, filter, options.Find().SetSort(bson.M{"slug": 1}))
+	if err != nil {
+		return nil, err
+	}
+	out := []models.Category{}
+	err = cur.All(ctx, &out)
+	return out, err
+}
+
+func (r *CategoryRepo) Update(ctx context.Context, c models.Category) (err error) {
+	ctx, done := instrument(ctx, "CategoryRepo", "Update")
+	defer done(&err)
+
+	ctx, cancel := r.timeouts.write(ctx)
+	defer cancel()
+
+	res, err := r.col.UpdateOne(ctx, bson.M{"id": c.ID}, bson.M{"$set": c})
+	if mongo.IsDuplicateKeyError(err) {
+		return errCategorySlugTaken
+	}
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return NotFound("category not found")
+	}
+	return nil
+}
+
+func (r *CategoryRepo) Delete(ctx context.Context, id int) (err error) {
+	ctx, done := instrument(ctx, "CategoryRepo", "Delete")
+	defer done(&err)
+
+	ctx, cancel := r.timeouts.write(ctx)
+	defer cancel()
+
+	res, err := r.col.DeleteOne(ctx, bson.M{"id": id})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return NotFound("category not found")
+	}
+	return nil
+}
@@ -2,7 +2,7 @@ package repository
 
 import (
 	"context"
-	"time"
+	"fmt"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -10,15 +10,19 @@ import (
 )
 
 type CounterRepo struct {
-	col *mongo.Collection
+	col      *mongo.Collection
+	timeouts Timeouts
 }
 
-func NewCounterRepo(db *mongo.Database) *CounterRepo {
-	return &CounterRepo{col: db.Collection("counters")}
+func NewCounterRepo(db *mongo.Database, t Timeouts) *CounterRepo {
+	return &CounterRepo{col: db.Collection("counters"), timeouts: t}
 }
 
-func (r *CounterRepo) Next(name string) (int, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (r *CounterRepo) Next(ctx context.Context, name string) (_ int, err error) {
+	ctx, done := instrument(ctx, "CounterRepo", "Next")
+	defer done(&err)
+
+	ctx, cancel := r.timeouts.write(ctx)
 	defer cancel()
 
 	opts := options.FindOneAndUpdate().
@@ -35,6 +39,73 @@ func (r *CounterRepo) Next(name string) (int, error) {
 	var out struct {
 		Seq int `bson:"seq"`
 	}
-	err := res.Decode(&out)
+	err = res.Decode(&out)
 	return out.Seq, err
 }
+
+type Counter struct {
+	Name string `json:"name" bson:"_id"`
+	Seq  int    `json:"seq" bson:"seq"`
+}
+
+func (r *CounterRepo) List(ctx context.Context) (_ []Counter, err error) {
+	ctx, done := instrument(ctx, "CounterRepo", "List")
+	defer done(&err)
+
+	ctx, cancel := r.timeouts.read(ctx)
+	defer cancel()
+
+	cur, err := r.col.Find(ctx, bson.M{}, options.Find().SetSort(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	out := []Counter{}
+	err = cur.All(ctx, &out)
+	return out, err
+}
+
+// MaxID returns the highest id stored in the collection the counter numbers;
+// every counter is named after its collection.
+func (r *CounterRepo) MaxID(ctx context.Context, name string) (_ int, err error) {
+	ctx, done := instrument(ctx, "CounterRepo", "MaxID")
+	defer done(&err)
+
+	ctx, cancel := r.timeouts.read(ctx)
+	defer cancel()
+
+	var doc struct {
+		ID int `bson:"id"`
+	}
+	err = r.col.Database().Collection(name).FindOne(ctx, bson.M{},
+		options.FindOne().SetSort(bson.M{"id": -1}).SetProjection(bson.M{"id": 1}),
+	).Decode(&doc)
+	if err == mongo.ErrNoDocuments {
+		return 0, nil
+	}
+	return doc.ID, err
+}
+
+// Set moves a counter so that the next id handed out is seq+1. It refuses to
+// go below the highest id in use, which would hand out duplicates.
+func (r *CounterRepo) Set(ctx context.Context, name string, seq int) (err error) {
+	maxID, err := r.MaxID(ctx, name)
+	if err != nil {
+		return err
+	}
+	if seq < maxID {
+		return Invalid("seq", fmt.Sprintf("%s already holds id %d", name, maxID))
+	}
+
+	ctx, done := instrument(ctx, "CounterRepo", "Set")
+	defer done(&err)
+
+	ctx, cancel := r.timeouts.write(ctx)
+	defer cancel()
+
+	_, err = r.col.UpdateOne(ctx,
+		bson.M{"_id": name},
+		bson.M{"$set": bson.M{"seq": seq}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
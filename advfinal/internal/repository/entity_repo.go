@@ -0,0 +1,188 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"bookstore/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// EntityRepository stores the entities of one kind: authors, publishers,
+// series or genres.
+type EntityRepository interface {
+	Create(ctx context.Context, e models.Entity) (models.Entity, error)
+	GetByID(ctx context.Context, id int) (models.Entity, error)
+	GetBySlug(ctx context.Context, slug string) (models.Entity, error)
+	List(ctx context.Context) ([]models.Entity, error)
+	Update(ctx context.Context, e models.Entity) error
+	Delete(ctx context.Context, id int) error
+}
+
+// Entities holds the repository of each entity kind.
+type Entities struct {
+	Authors    EntityRepository
+	Publishers EntityRepository
+	Series     EntityRepository
+	Genres     EntityRepository
+}
+
+func NewEntities(db *mongo.Database, t Timeouts) Entities {
+	return Entities{
+		Authors:    NewEntityRepo(db, models.EntityAuthor, t),
+		Publishers: NewEntityRepo(db, models.EntityPublisher, t),
+		Series:     NewEntityRepo(db, models.EntitySeries, t),
+		Genres:     NewEntityRepo(db, models.EntityGenre, t),
+	}
+}
+
+// Of returns the repository of kind, or nil for an unknown kind.
+func (e Entities) Of(kind string) EntityRepository {
+	switch kind {
+	case models.EntityAuthor:
+		return e.Authors
+	case models.EntityPublisher:
+		return e.Publishers
+	case models.EntitySeries:
+		return e.Series
+	case models.EntityGenre:
+		return e.Genres
+	}
+	return nil
+}
+
+// EntityRepo keeps one kind in the collection of the same name.
+type EntityRepo struct {
+	kind     string
+	noun     string
+	col      *mongo.Collection
+	counters *CounterRepo
+	timeouts Timeouts
+}
+
+func NewEntityRepo(db *mongo.Database, kind string, t Timeouts) *EntityRepo {
+	return &EntityRepo{
+		kind:     kind,
+		noun:     models.EntityNoun(kind),
+		col:      db.Collection(kind),
+		counters: NewCounterRepo(db, t),
+		timeouts: t,
+	}
+}
+
+// Create relies on the unique slug index of migration 13 to keep names
+// unique.
+func (r *EntityRepo) Create(ctx context.Context, e models.Entity) (_ models.Entity, err error) {
+	ctx, done := instrument(ctx, "EntityRepo", "Create")
+	defer done(&err)
+
+	ctx, cancel := r.timeouts.write(ctx)
+	defer cancel()
+
+	id, err := r.counters.Next(ctx, r.kind)
+	if err != nil {
+		return models.Entity{}, err
+	}
+	e.ID = id
+	if e.PreviousSlugs == nil {
+		e.PreviousSlugs = []string{}
+	}
+
+	_, err = r.col.InsertOne(ctx, e)
+	if mongo.IsDuplicateKeyError(err) {
+		return models.Entity{}, &Error{Kind: ErrConflict, Field: "name", Msg: "another " + r.noun + " has this name"}
+	}
+	if err != nil {
+		return models.Entity{}, err
+	}
+	return e, nil
+}
+
+func (r *EntityRepo) GetByID(ctx context.Context, id int) (_ models.Entity, err error) {
+	ctx, done := instrument(ctx, "EntityRepo", "GetByID")
+	defer done(&err)
+
+	return r.findOne(ctx, bson.M{"id": id})
+}
+
+// GetBySlug also finds an entity by a slug it no longer uses, preferring
+// the one whose current slug it is.
+func (r *EntityRepo) GetBySlug(ctx context.Context, slug string) (_ models.Entity, err error) {
+	ctx, done := instrument(ctx, "EntityRepo", "GetBySlug")
+	defer done(&err)
+
+	e, err := r.findOne(ctx, bson.M{"slug": slug})
+	if !errors.Is(err, ErrNotFound) {
+		return e, err
+	}
+	return r.findOne(ctx, bson.M{"previousSlugs": slug})
+}
+
+func (r *EntityRepo) findOne(ctx context.Context, filter bson.M) (models.Entity, error) {
+	ctx, cancel := r.timeouts.read(ctx)
+	defer cancel()
+
+	var e models.Entity
+	err := r.col.FindOne(ctx, filter).Decode(&e)
+	if err == mongo.ErrNoDocuments {
+		return models.Entity{}, NotFound(r.noun + " not found")
+	}
+	return e, err
+}
+
+// List returns every entity of the kind by name.
+func (r *EntityRepo) List(ctx context.Context) (_ []models.Entity, err error) {
+	ctx, done := instrument(ctx, "EntityRepo", "List")
+	defer done(&err)
+
+	ctx, cancel := r.timeouts.read(ctx)
+	defer cancel()
+
+	cur, err := r.col.Find(ctx, bson.M{}, options.Find().SetSort(bson.M{"slug": 1}))
+	if err != nil {
+		return nil, err
+	}
+	out := []models.Entity{}
+	err = cur.All(ctx, &out)
+	return out, err
+}
+
+func (r *EntityRepo) Update(ctx context.Context, e models.Entity) (err error) {
+	ctx, done := instrument(ctx, "EntityRepo", "Update")
+	defer done(&err)
+
+	ctx, cancel := r.timeouts.write(ctx)
+	defer cancel()
+
+	res, err := r.col.UpdateOne(ctx, bson.M{"id": e.ID}, bson.M{"$set": e})
+	if mongo.IsDuplicateKeyError(err) {
+		return &Error{Kind: ErrConflict, Field: "name", Msg: "another " + r.noun + " has this name"}
+	}
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return NotFound(r.noun + " not found")
+	}
+	return nil
+}
+
+func (r *EntityRepo) Delete(ctx context.Context, id int) (err error) {
+	ctx, done := instrument(ctx, "EntityRepo", "Delete")
+	defer done(&err)
+
+	ctx, cancel := r.timeouts.write(ctx)
+	defer cancel()
+
+	res, err := r.col.DeleteOne(ctx, bson.M{"id": id})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return NotFound(r.noun + " not found")
+	}
+	return nil
+}
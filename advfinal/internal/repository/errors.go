@@ -0,0 +1,35 @@
+package repository
+
+import "errors"
+
+// Sentinel kinds shared by every repository. Callers test them with
+// errors.Is; the concrete *Error keeps the human readable message.
+var (
+	ErrNotFound = errors.New("not found")
+	ErrConflict = errors.New("conflict")
+	ErrInvalid  = errors.New("invalid input")
+)
+
+type Error struct {
+	Kind  error
+	Field string
+	Msg   string
+}
+
+func (e *Error) Error() string { return e.Msg }
+
+func (e *Error) Unwrap() error { return e.Kind }
+
+func NotFound(msg string) error {
+	return &Error{Kind: ErrNotFound, Msg: msg}
+}
+
+func Conflict(msg string) error {
+	return &Error{Kind: ErrConflict, Msg: msg}
+}
+
+// Invalid reports bad input for a single field; field may be empty when the
+// problem is not tied to one.
+func Invalid(field, msg string) error {
+	return &Error{Kind: ErrInvalid, Field: field, Msg: msg}
+}
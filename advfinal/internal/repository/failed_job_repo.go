@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"context"
+
+	"bookstore/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type FailedJobRepository interface {
+	Create(ctx context.Context, job models.FailedJob) (models.FailedJob, error)
+	GetByID(ctx context.Context, id int) (models.FailedJob, error)
+	GetAll(ctx context.Context) []models.FailedJob
+	Update(ctx context.Context, job models.FailedJob) error
+	Delete(ctx context.Context, id int) error
+}
+
+type FailedJobRepo struct {
+	col      *mongo.Collection
+	counters *CounterRepo
+	timeouts Timeouts
+}
+
+func NewFailedJobRepo(db *mongo.Database, t Timeouts) *FailedJobRepo {
+	return &FailedJobRepo{
+		col:      db.Collection("failed_jobs"),
+		counters: NewCounterRepo(db, t),
+		timeouts: t,
+	}
+}
+
+func (r *FailedJobRepo) Create(ctx context.Context, job models.FailedJob) (_ models.FailedJob, err error) {
+	ctx, done := instrument(ctx, "FailedJobRepo", "Create")
+	defer done(&err)
+
+	ctx, cancel := r.timeouts.write(ctx)
+	defer cancel()
+
+	id, err := r.counters.Next(ctx, "failed_jobs")
+	if err != nil {
+		return models.FailedJob{}, err
+	}
+	job.ID = id
+
+	_, err = r.col.InsertOne(ctx, job)
+	return job, err
+}
+
+func (r *FailedJobRepo) GetByID(ctx context.Context, id int) (_ models.FailedJob, err error) {
+	ctx, done := instrument(ctx, "FailedJobRepo", "GetByID")
+	defer done(&err)
+
+	ctx, cancel := r.timeouts.read(ctx)
+	defer cancel()
+
+	var j models.FailedJob
+	err = r.col.FindOne(ctx, bson.M{"id": id}).Decode(&j)
+	if err == mongo.ErrNoDocuments {
+		return models.FailedJob{}, NotFound("failed job not found")
+	}
+	return j, err
+}
+
+func (r *FailedJobRepo) GetAll(ctx context.Context) []models.FailedJob {
+	var err error
+	ctx, done := instrument(ctx, "FailedJobRepo", "GetAll")
+	defer done(&err)
+
+	ctx, cancel := r.timeouts.read(ctx)
+	defer cancel()
+
+	cur, err := r.col.Find(ctx, bson.M{}, options.Find().SetSort(bson.M{"id": 1}))
+	if err != nil {
+		return []models.FailedJob{}
+	}
+	defer cur.Close(ctx)
+
+	out := []models.FailedJob{}
+	for cur.Next(ctx) {
+		var j models.FailedJob
+		if cur.Decode(&j) == nil {
+			out = append(out, j)
+		}
+	}
+
+	return out
+}
+
+func (r *FailedJobRepo) Update(ctx context.Context, job models.FailedJob) (err error) {
+	ctx, done := instrument(ctx, "FailedJobRepo", "Update")
+	defer done(&err)
+
+	ctx, cancel := r.timeouts.write(ctx)
+	defer cancel()
+
+	res, err := r.col.ReplaceOne(ctx, bson.M{"id": job.ID}, job)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return NotFound("failed job not found")
+	}
+	return nil
+}
+
+func (r *FailedJobRepo) Delete(ctx context.Context, id int) (err error) {
+	ctx, done := instrument(ctx, "FailedJobRepo", "Delete")
+	defer done(&err)
+
+	ctx, cancel := r.timeouts.write(ctx)
+	defer cancel()
+
+	res, err := r.col.DeleteOne(ctx, bson.M{"id": id})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return NotFound("failed job not found")
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"bookstore/internal/metrics"
+	"bookstore/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrument times and traces one Mongo-backed repository call. The returned
+// context carries the span, so the driver's command spans nest under it.
+// Call done with a pointer to the method's error, usually a named result,
+// so that failures are recorded on the span:
+//
+//	ctx, done := instrument(ctx, "BookRepo", "GetByID")
+//	defer done(&err)
+func instrument(ctx context.Context, repo, method string) (context.Context, func(*error)) {
+	stop := metrics.ObserveMongo(repo, method)
+	ctx, span := tracing.Start(ctx, repo+"."+method, attribute.String("db.system", "mongodb"))
+	return ctx, func(errp *error) {
+		var err error
+		if errp != nil {
+			err = *errp
+		}
+		endSpan(span, err)
+		stop()
+	}
+}
+
+// endSpan ends a repository span, recording err unless it only says that
+// nothing matched: a missing document is an answer, not a failed call.
+func endSpan(span trace.Span, err error) {
+	if errors.Is(err, ErrNotFound) {
+		err = nil
+	}
+	tracing.End(span, err)
+}
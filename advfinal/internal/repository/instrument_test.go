@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"bookstore/internal/tracing"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestSpansRecordErrors(t *testing.T) {
+	exp := tracetest.NewInMemoryExporter()
+	tp := tracing.NewProvider(exp)
+	otel.SetTracerProvider(tp)
+	t.Cleanup(func() { _ = tp.Shutdown(context.Background()) })
+
+	ctx := context.Background()
+
+	// Nobody listens on port 1, so every Mongo call fails.
+	client, err := mongo.Connect(ctx, options.Client().
+		ApplyURI("mongodb://127.0.0.1:1").
+		SetServerSelectionTimeout(50*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	books := NewBookRepo(client.Database("test"), Timeouts{Read: time.Second, Write: time.Second})
+	if _, err := books.GetByID(ctx, 1); err == nil {
+		t.Fatal("GetByID succeeded without a database")
+	}
+	_ = books.GetAll(ctx)
+
+	carts := NewCartRepo()
+	c := carts.Create(ctx, 1)
+	if err := carts.UpdateItem(ctx, c.ID, 1, 0); err == nil {
+		t.Fatal("UpdateItem accepted qty 0")
+	}
+	if _, _, err := carts.GetByID(ctx, 99); err == nil {
+		t.Fatal("GetByID found a missing cart")
+	}
+
+	_ = tp.ForceFlush(ctx)
+	status := map[string]codes.Code{}
+	for _, s := range exp.GetSpans() {
+		status[s.Name] = s.Status.Code
+	}
+
+	for name, want := range map[string]codes.Code{
+		"BookRepo.GetByID":    codes.Error,
+		"BookRepo.GetAll":     codes.Error,
+		"CartRepo.Create":     codes.Unset,
+		"CartRepo.UpdateItem": codes.Error,
+		"CartRepo.GetByID":    codes.Unset, // not found is not a failure
+	} {
+		got, ok := status[name]
+		if !ok {
+			t.Errorf("no %s span", name)
+			continue
+		}
+		if got != want {
+			t.Errorf("%s status = %v, want %v", name, got, want)
+		}
+	}
+}
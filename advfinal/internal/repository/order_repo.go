@@ -2,55 +2,62 @@ package repository
 
 import (
 	"context"
-	"errors"
 	"time"
 
 	"bookstore/internal/models"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type OrderRepository interface {
-	Create(order models.Order, items []models.OrderItem) (models.Order, []models.OrderItem, error)
-	GetByID(id int) (models.Order, []models.OrderItem, error)
-	GetAll() []models.Order
-	Update(order models.Order) error
-	Delete(id int) error
+	Create(ctx context.Context, order models.Order, items []models.OrderItem) (models.Order, []models.OrderItem, error)
+	GetByID(ctx context.Context, id int) (models.Order, []models.OrderItem, error)
+	GetAll(ctx context.Context) []models.Order
+	GetByCustomer(ctx context.Context, customerID int) []models.Order
+	Update(ctx context.Context, order models.Order) error
+	Cancel(ctx context.Context, id int) (models.Order, error)
+	Delete(ctx context.Context, id int) error
 }
 
 type OrderRepo struct {
 	ordersCol *mongo.Collection
 	itemsCol  *mongo.Collection
 	counters  *CounterRepo
+	timeouts  Timeouts
 }
 
-func NewOrderRepo(db *mongo.Database) *OrderRepo {
+func NewOrderRepo(db *mongo.Database, t Timeouts) *OrderRepo {
 	return &OrderRepo{
 		ordersCol: db.Collection("orders"),
 		itemsCol:  db.Collection("order_items"),
-		counters:  NewCounterRepo(db),
+		counters:  NewCounterRepo(db, t),
+		timeouts:  t,
 	}
 }
 
-func (r *OrderRepo) Create(order models.Order, items []models.OrderItem) (models.Order, []models.OrderItem, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+func (r *OrderRepo) Create(ctx context.Context, order models.Order, items []models.OrderItem) (_ models.Order, _ []models.OrderItem, err error) {
+	ctx, done := instrument(ctx, "OrderRepo", "Create")
+	defer done(&err)
+
+	ctx, cancel := r.timeouts.write(ctx)
 	defer cancel()
 
 	if order.CustomerID <= 0 {
-		return models.Order{}, nil, errors.New("customerId must be positive")
+		return models.Order{}, nil, Invalid("customerId", "customerId must be positive")
 	}
 	if order.CartID <= 0 {
-		return models.Order{}, nil, errors.New("cartId must be positive")
+		return models.Order{}, nil, Invalid("cartId", "cartId must be positive")
 	}
 	if len(items) == 0 {
-		return models.Order{}, nil, errors.New("order items required")
+		return models.Order{}, nil, Invalid("items", "order items required")
 	}
 	if order.Total < 0 {
-		return models.Order{}, nil, errors.New("total cannot be negative")
+		return models.Order{}, nil, Invalid("total", "total cannot be negative")
 	}
 
-	orderID, err := r.counters.Next("orders")
+	orderID, err := r.counters.Next(ctx, "orders")
 	if err != nil {
 		return models.Order{}, nil, err
 	}
@@ -67,18 +74,18 @@ func (r *OrderRepo) Create(order models.Order, items []models.OrderItem) (models
 	for _, it := range items {
 		if it.BookID <= 0 {
 			_, _ = r.ordersCol.DeleteOne(ctx, bson.M{"id": order.ID})
-			return models.Order{}, nil, errors.New("bookId must be positive")
+			return models.Order{}, nil, Invalid("bookId", "bookId must be positive")
 		}
 		if it.Qty <= 0 {
 			_, _ = r.ordersCol.DeleteOne(ctx, bson.M{"id": order.ID})
-			return models.Order{}, nil, errors.New("qty must be positive")
+			return models.Order{}, nil, Invalid("qty", "qty must be positive")
 		}
 		if it.Price < 0 {
 			_, _ = r.ordersCol.DeleteOne(ctx, bson.M{"id": order.ID})
-			return models.Order{}, nil, errors.New("price cannot be negative")
+			return models.Order{}, nil, Invalid("price", "price cannot be negative")
 		}
 
-		itemID, err := r.counters.Next("order_items")
+		itemID, err := r.counters.Next(ctx, "order_items")
 		if err != nil {
 			_, _ = r.ordersCol.DeleteOne(ctx, bson.M{"id": order.ID})
 			return models.Order{}, nil, err
@@ -103,14 +110,17 @@ func (r *OrderRepo) Create(order models.Order, items []models.OrderItem) (models
 	return order, outItems, nil
 }
 
-func (r *OrderRepo) GetByID(id int) (models.Order, []models.OrderItem, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+func (r *OrderRepo) GetByID(ctx context.Context, id int) (_ models.Order, _ []models.OrderItem, err error) {
+	ctx, done := instrument(ctx, "OrderRepo", "GetByID")
+	defer done(&err)
+
+	ctx, cancel := r.timeouts.read(ctx)
 	defer cancel()
 
 	var o models.Order
-	err := r.ordersCol.FindOne(ctx, bson.M{"id": id}).Decode(&o)
+	err = r.ordersCol.FindOne(ctx, bson.M{"id": id}).Decode(&o)
 	if err == mongo.ErrNoDocuments {
-		return models.Order{}, nil, errors.New("order not found")
+		return models.Order{}, nil, NotFound("order not found")
 	}
 	if err != nil {
 		return models.Order{}, nil, err
@@ -133,8 +143,12 @@ func (r *OrderRepo) GetByID(id int) (models.Order, []models.OrderItem, error) {
 	return o, items, nil
 }
 
-func (r *OrderRepo) GetAll() []models.Order {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+func (r *OrderRepo) GetAll(ctx context.Context) []models.Order {
+	var err error
+	ctx, done := instrument(ctx, "OrderRepo", "GetAll")
+	defer done(&err)
+
+	ctx, cancel := r.timeouts.read(ctx)
 	defer cancel()
 
 	cur, err := r.ordersCol.Find(ctx, bson.M{})
@@ -154,21 +168,50 @@ func (r *OrderRepo) GetAll() []models.Order {
 	return out
 }
 
-func (r *OrderRepo) Update(order models.Order) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 8*time.Second)
+func (r *OrderRepo) GetByCustomer(ctx context.Context, customerID int) []models.Order {
+	var err error
+	ctx, done := instrument(ctx, "OrderRepo", "GetByCustomer")
+	defer done(&err)
+
+	ctx, cancel := r.timeouts.read(ctx)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.M{"id": -1})
+	cur, err := r.ordersCol.Find(ctx, bson.M{"customerId": customerID}, opts)
+	if err != nil {
+		return []models.Order{}
+	}
+	defer cur.Close(ctx)
+
+	out := []models.Order{}
+	for cur.Next(ctx) {
+		var o models.Order
+		if cur.Decode(&o) == nil {
+			out = append(out, o)
+		}
+	}
+
+	return out
+}
+
+func (r *OrderRepo) Update(ctx context.Context, order models.Order) (err error) {
+	ctx, done := instrument(ctx, "OrderRepo", "Update")
+	defer done(&err)
+
+	ctx, cancel := r.timeouts.write(ctx)
 	defer cancel()
 
 	if order.ID <= 0 {
-		return errors.New("order id must be positive")
+		return Invalid("id", "order id must be positive")
 	}
 	if order.CustomerID <= 0 {
-		return errors.New("customerId must be positive")
+		return Invalid("customerId", "customerId must be positive")
 	}
 	if order.CartID <= 0 {
-		return errors.New("cartId must be positive")
+		return Invalid("cartId", "cartId must be positive")
 	}
 	if order.Total < 0 {
-		return errors.New("total cannot be negative")
+		return Invalid("total", "total cannot be negative")
 	}
 
 	res, err := r.ordersCol.UpdateOne(ctx, bson.M{"id": order.ID}, bson.M{"$set": bson.M{
@@ -180,13 +223,45 @@ func (r *OrderRepo) Update(order models.Order) error {
 		return err
 	}
 	if res.MatchedCount == 0 {
-		return errors.New("order not found")
+		return NotFound("order not found")
 	}
 	return nil
 }
 
-func (r *OrderRepo) Delete(id int) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+// Cancel marks a placed order cancelled and returns it; cancelling twice is a
+// conflict.
+func (r *OrderRepo) Cancel(ctx context.Context, id int) (_ models.Order, err error) {
+	ctx, done := instrument(ctx, "OrderRepo", "Cancel")
+	defer done(&err)
+
+	ctx, cancel := r.timeouts.write(ctx)
+	defer cancel()
+
+	var o models.Order
+	err = r.ordersCol.FindOneAndUpdate(ctx,
+		bson.M{"id": id, "status": bson.M{"$ne": models.OrderCancelled}},
+		bson.M{"$set": bson.M{"status": models.OrderCancelled, "cancelledAt": time.Now()}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&o)
+	if err != mongo.ErrNoDocuments {
+		return o, err
+	}
+
+	n, err := r.ordersCol.CountDocuments(ctx, bson.M{"id": id})
+	if err != nil {
+		return models.Order{}, err
+	}
+	if n == 0 {
+		return models.Order{}, NotFound("order not found")
+	}
+	return models.Order{}, Conflict("order already cancelled")
+}
+
+func (r *OrderRepo) Delete(ctx context.Context, id int) (err error) {
+	ctx, done := instrument(ctx, "OrderRepo", "Delete")
+	defer done(&err)
+
+	ctx, cancel := r.timeouts.write(ctx)
 	defer cancel()
 
 	res, err := r.ordersCol.DeleteOne(ctx, bson.M{"id": id})
@@ -194,7 +269,7 @@ func (r *OrderRepo) Delete(id int) error {
 		return err
 	}
 	if res.DeletedCount == 0 {
-		return errors.New("order not found")
+		return NotFound("order not found")
 	}
 
 	_, _ = r.itemsCol.DeleteMany(ctx, bson.M{"orderId": id})
@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"bookstore/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type RecommendationRepository interface {
+	Get(ctx context.Context, bookID int) (models.Recommendation, error)
+	// ReplaceAll stores recs, all computed at the given time, and drops
+	// the recommendations of earlier runs.
+	ReplaceAll(ctx context.Context, recs []models.Recommendation, computedAt time.Time) error
+
+	// EachOrderBasket calls fn with the distinct books of every order that
+	// was not cancelled, and EachWishlistBasket with those of every
+	// wishlist.
+	EachOrderBasket(ctx context.Context, fn func(bookIDs []int) error) error
+	EachWishlistBasket(ctx context.Context, fn func(bookIDs []int) error) error
+}
+
+type RecommendationRepo struct {
+	col           *mongo.Collection
+	orderItemsCol *mongo.Collection
+	wishItemsCol  *mongo.Collection
+	timeouts      Timeouts
+}
+
+func NewRecommendationRepo(db *mongo.Database, t Timeouts) *RecommendationRepo {
+	return &RecommendationRepo{
+		col:           db.Collection("recommendations"),
+		orderItemsCol: db.Collection("order_items"),
+		wishItemsCol:  db.Collection("wishlist_items"),
+		timeouts:      t,
+	}
+}
+
+func (r *RecommendationRepo) Get(ctx context.Context, bookID int) (_ models.Recommendation, err error) {
+	ctx, done := instrument(ctx, "RecommendationRepo", "Get")
+	defer done(&err)
+
+	ctx, cancel := r.timeouts.read(ctx)
+	defer cancel()
+
+	var rec models.Recommendation
+	err = r.col.FindOne(ctx, bson.M{"bookId": bookID}).Decode(&rec)
+	if err == mongo.ErrNoDocuments {
+		return models.Recommendation{}, NotFound("no recommendations for this book")
+	}
+	return rec, err
+}
+
+// replaceBatch bounds the upserts sent, and timed, in one bulk write.
+const replaceBatch = 500
+
+// ReplaceAll upserts first and deletes after, so readers always find a
+// complete set, old or new.
+func (r *RecommendationRepo) ReplaceAll(ctx context.Context, recs []models.Recommendation, computedAt time.Time) (err error) {
+	ctx, done := instrument(ctx, "RecommendationRepo", "ReplaceAll")
+	defer done(&err)
+
+	for start := 0; start < len(recs); start += replaceBatch {
+		batch := recs[start:min(start+replaceBatch, len(recs))]
+		writes := make([]mongo.WriteModel, 0, len(batch))
+		for _, rec := range batch {
+			rec.ComputedAt = computedAt
+			writes = append(writes, mongo.NewReplaceOneModel().
+				SetFilter(bson.M{"bookId": rec.BookID}).SetReplacement(rec).SetUpsert(true))
+		}
+		if err := r.bulkWrite(ctx, writes); err != nil {
+			return err
+		}
+	}
+
+	wctx, cancel := r.timeouts.write(ctx)
+	defer cancel()
+	_, err = r.col.DeleteMany(wctx, bson.M{"computedAt": bson.M{"$lt": computedAt}})
+	return err
+}
+
+func (r *RecommendationRepo) bulkWrite(ctx context.Context, writes []mongo.WriteModel) error {
+	ctx, cancel := r.timeouts.write(ctx)
+	defer cancel()
+
+	_, err := r.col.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+	return err
+}
+
+// Like BookRepo.Each, the basket scans read the whole collection and are
+// only bounded by the caller's context.
+func (r *RecommendationRepo) EachOrderBasket(ctx context.Context, fn func([]int) error) (err error) {
+	ctx, done := instrument(ctx, "RecommendationRepo", "EachOrderBasket")
+	defer done(&err)
+
+	return r.eachBasket(ctx, r.orderItemsCol, mongo.Pipeline{
+		{{Key: "$group", Value: bson.M{"_id": "$orderId", "books": bson.M{"$addToSet": "$bookId"}}}},
+		{{Key: "$lookup", Value: bson.M{"from": "orders", "localField": "_id", "foreignField": "id", "as": "order"}}},
+		{{Key: "$match", Value: bson.M{"order.status": bson.M{"$ne": models.OrderCancelled}}}},
+		{{Key: "$project", Value: bson.M{"books": 1}}},
+	}, fn)
+}
+
+func (r *RecommendationRepo) EachWishlistBasket(ctx context.Context, fn func([]int) error) (err error) {
+	ctx, done := instrument(ctx, "RecommendationRepo", "EachWishlistBasket")
+	defer done(&err)
+
+	return r.eachBasket(ctx, r.wishItemsCol, mongo.Pipeline{
+		{{Key: "$group", Value: bson.M{"_id": "$wishlistId", "books": bson.M{"$addToSet": "$bookId"}}}},
+	}, fn)
+}
+
+func (r *RecommendationRepo) eachBasket(ctx context.Context, col *mongo.Collection, pipeline mongo.Pipeline, fn func([]int) error) error {
+	cur, err := col.Aggregate(ctx, pipeline, options.Aggregate().SetAllowDiskUse(true))
+	if err != nil {
+		return err
+	}
+	defer cur.Close(ctx)
+
+	for cur.Next(ctx) {
+		var basket struct {
+			Books []int `bson:"books"`
+		}
+		if err := cur.Decode(&basket); err != nil {
+			return err
+		}
+		if err := fn(basket.Books); err != nil {
+			return err
+		}
+	}
+	return cur.Err()
+}
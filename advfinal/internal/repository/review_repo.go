@@ -0,0 +1,263 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"bookstore/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type ReviewRepository interface {
+	Create(ctx context.Context, review models.Review) (models.Review, error)
+	GetByID(ctx context.Context, id int) (models.Review, error)
+	GetByBookAndUser(ctx context.Context, bookID, userID int) (models.Review, error)
+	ListByBook(ctx context.Context, bookID int, status string) []models.Review
+	ListByStatus(ctx context.Context, status string) []models.Review
+	ListByUser(ctx context.Context, userID int) []models.Review
+	Update(ctx context.Context, review models.Review) error
+	Delete(ctx context.Context, id int) error
+
+	// AddVote and RemoveVote report whether the user's helpful vote changed.
+	AddVote(ctx context.Context, reviewID, userID int) (bool, error)
+	RemoveVote(ctx context.Context, reviewID, userID int) (bool, error)
+
+	// RatingStats averages the ratings of a book's approved reviews.
+	RatingStats(ctx context.Context, bookID int) (float64, int, error)
+}
+
+type ReviewRepo struct {
+	col      *mongo.Collection
+	votesCol *mongo.Collection
+	counters *CounterRepo
+	timeouts Timeouts
+}
+
+func NewReviewRepo(db *mongo.Database, t Timeouts) *ReviewRepo {
+	return &ReviewRepo{
+		col:      db.Collection("reviews"),
+		votesCol: db.Collection("review_votes"),
+		counters: NewCounterRepo(db, t),
+		timeouts: t,
+	}
+}
+
+// Create relies on the unique (bookId, userId) index of migration 10 to
+// keep one review per user and book.
+func (r *ReviewRepo) Create(ctx context.Context, review models.Review) (_ models.Review, err error) {
+	ctx, done := instrument(ctx, "ReviewRepo", "Create")
+	defer done(&err)
+
+	ctx, cancel := r.timeouts.write(ctx)
+	defer cancel()
+
+	id, err := r.counters.Next(ctx, "reviews")
+	if err != nil {
+		return models.Review{}, err
+	}
+	review.ID = id
+
+	_, err = r.col.InsertOne(ctx, review)
+	if mongo.IsDuplicateKeyError(err) {
+		return models.Review{}, Conflict("you have already reviewed this book")
+	}
+	if err != nil {
+		return models.Review{}, err
+	}
+	return review, nil
+}
+
+func (r *ReviewRepo) GetByID(ctx context.Context, id int) (_ models.Review, err error) {
+	ctx, done := instrument(ctx, "ReviewRepo", "GetByID")
+	defer done(&err)
+
+	ctx, cancel := r.timeouts.read(ctx)
+	defer cancel()
+
+	var rv models.Review
+	err = r.col.FindOne(ctx, bson.M{"id": id}).Decode(&rv)
+	if err == mongo.ErrNoDocuments {
+		return models.Review{}, NotFound("review not found")
+	}
+	return rv, err
+}
+
+func (r *ReviewRepo) GetByBookAndUser(ctx context.Context, bookID, userID int) (_ models.Review, err error) {
+	ctx, done := instrument(ctx, "ReviewRepo", "GetByBookAndUser")
+	defer done(&err)
+
+	ctx, cancel := r.timeouts.read(ctx)
+	defer cancel()
+
+	var rv models.Review
+	err = r.col.FindOne(ctx, bson.M{"bookId": bookID, "userId": userID}).Decode(&rv)
+	if err == mongo.ErrNoDocuments {
+		return models.Review{}, NotFound("review not found")
+	}
+	return rv, err
+}
+
+// ListByBook returns a book's reviews with the given status, newest first.
+func (r *ReviewRepo) ListByBook(ctx context.Context, bookID int, status string) []models.Review {
+	var err error
+	ctx, done := instrument(ctx, "ReviewRepo", "ListByBook")
+	defer done(&err)
+
+	out, err := r.find(ctx, bson.M{"bookId": bookID, "status": status}, bson.M{"createdAt": -1})
+	return out
+}
+
+// ListByStatus returns the reviews with the given status, oldest first so
+// a moderation queue is worked in order.
+func (r *ReviewRepo) ListByStatus(ctx context.Context, status string) []models.Review {
+	var err error
+	ctx, done := instrument(ctx, "ReviewRepo", "ListByStatus")
+	defer done(&err)
+
+	out, err := r.find(ctx, bson.M{"status": status}, bson.M{"createdAt": 1})
+	return out
+}
+
+func (r *ReviewRepo) ListByUser(ctx context.Context, userID int) []models.Review {
+	var err error
+	ctx, done := instrument(ctx, "ReviewRepo", "ListByUser")
+	defer done(&err)
+
+	out, err := r.find(ctx, bson.M{"userId": userID}, bson.M{"createdAt": -1})
+	return out
+}
+
+func (r *ReviewRepo) find(ctx context.Context, filter, sort bson.M) ([]models.Review, error) {
+	ctx, cancel := r.timeouts.read(ctx)
+	defer cancel()
+
+	cur, err := r.col.Find(ctx, filter, options.Find().SetSort(sort))
+	if err != nil {
+		return []models.Review{}, err
+	}
+	defer cur.Close(ctx)
+
+	out := []models.Review{}
+	for cur.Next(ctx) {
+		var rv models.Review
+		if cur.Decode(&rv) == nil {
+			out = append(out, rv)
+		}
+	}
+	return out, nil
+}
+
+// Update writes the fields a user or moderator may change. The helpful
+// count is left to the vote methods.
+func (r *ReviewRepo) Update(ctx context.Context, review models.Review) (err error) {
+	ctx, done := instrument(ctx, "ReviewRepo", "Update")
+	defer done(&err)
+
+	ctx, cancel := r.timeouts.write(ctx)
+	defer cancel()
+
+	res, err := r.col.UpdateOne(ctx, bson.M{"id": review.ID}, bson.M{"$set": bson.M{
+		"rating":         review.Rating,
+		"title":          review.Title,
+		"body":           review.Body,
+		"status":         review.Status,
+		"moderationNote": review.ModerationNote,
+		"updatedAt":      review.UpdatedAt,
+	}})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return NotFound("review not found")
+	}
+	return nil
+}
+
+func (r *ReviewRepo) Delete(ctx context.Context, id int) (err error) {
+	ctx, done := instrument(ctx, "ReviewRepo", "Delete")
+	defer done(&err)
+
+	ctx, cancel := r.timeouts.write(ctx)
+	defer cancel()
+
+	res, err := r.col.DeleteOne(ctx, bson.M{"id": id})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return NotFound("review not found")
+	}
+
+	_, _ = r.votesCol.DeleteMany(ctx, bson.M{"reviewId": id})
+	return nil
+}
+
+// AddVote records the vote first; the unique (reviewId, userId) index
+// makes a repeated vote a no-op instead of a second increment.
+func (r *ReviewRepo) AddVote(ctx context.Context, reviewID, userID int) (_ bool, err error) {
+	ctx, done := instrument(ctx, "ReviewRepo", "AddVote")
+	defer done(&err)
+
+	ctx, cancel := r.timeouts.write(ctx)
+	defer cancel()
+
+	_, err = r.votesCol.InsertOne(ctx, bson.M{"reviewId": reviewID, "userId": userID, "createdAt": time.Now()})
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	_, err = r.col.UpdateOne(ctx, bson.M{"id": reviewID}, bson.M{"$inc": bson.M{"helpful": 1}})
+	return err == nil, err
+}
+
+func (r *ReviewRepo) RemoveVote(ctx context.Context, reviewID, userID int) (_ bool, err error) {
+	ctx, done := instrument(ctx, "ReviewRepo", "RemoveVote")
+	defer done(&err)
+
+	ctx, cancel := r.timeouts.write(ctx)
+	defer cancel()
+
+	res, err := r.votesCol.DeleteOne(ctx, bson.M{"reviewId": reviewID, "userId": userID})
+	if err != nil || res.DeletedCount == 0 {
+		return false, err
+	}
+	_, err = r.col.UpdateOne(ctx, bson.M{"id": reviewID}, bson.M{"$inc": bson.M{"helpful": -1}})
+	return err == nil, err
+}
+
+func (r *ReviewRepo) RatingStats(ctx context.Context, bookID int) (_ float64, _ int, err error) {
+	ctx, done := instrument(ctx, "ReviewRepo", "RatingStats")
+	defer done(&err)
+
+	ctx, cancel := r.timeouts.read(ctx)
+	defer cancel()
+
+	cur, err := r.col.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"bookId": bookID, "status": models.ReviewApproved}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   nil,
+			"avg":   bson.M{"$avg": "$rating"},
+			"count": bson.M{"$sum": 1},
+		}}},
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	defer cur.Close(ctx)
+
+	var stats struct {
+		Avg   float64 `bson:"avg"`
+		Count int     `bson:"count"`
+	}
+	if cur.Next(ctx) {
+		if err := cur.Decode(&stats); err != nil {
+			return 0, 0, err
+		}
+	}
+	return stats.Avg, stats.Count, cur.Err()
+}
@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"bookstore/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type SessionRepository interface {
+	Create(ctx context.Context, session models.Session) (models.Session, error)
+	GetByID(ctx context.Context, id int) (models.Session, error)
+	ListByUser(ctx context.Context, userID int) []models.Session
+	Revoke(ctx context.Context, id int) error
+	RevokeAllExcept(ctx context.Context, userID int, keepID int) error
+	DeleteByUser(ctx context.Context, userID int) error
+}
+
+type SessionRepo struct {
+	col      *mongo.Collection
+	counters *CounterRepo
+	timeouts Timeouts
+}
+
+func NewSessionRepo(db *mongo.Database, t Timeouts) *SessionRepo {
+	return &SessionRepo{
+		col:      db.Collection("sessions"),
+		counters: NewCounterRepo(db, t),
+		timeouts: t,
+	}
+}
+
+func (r *SessionRepo) Create(ctx context.Context, session models.Session) (_ models.Session, err error) {
+	ctx, done := instrument(ctx, "SessionRepo", "Create")
+	defer done(&err)
+
+	ctx, cancel := r.timeouts.write(ctx)
+	defer cancel()
+
+	if session.UserID <= 0 {
+		return models.Session{}, Invalid("userId", "userId must be positive")
+	}
+
+	id, err := r.counters.Next(ctx, "sessions")
+	if err != nil {
+		return models.Session{}, err
+	}
+	session.ID = id
+
+	_, err = r.col.InsertOne(ctx, session)
+	if err != nil {
+		return models.Session{}, err
+	}
+	return session, nil
+}
+
+func (r *SessionRepo) GetByID(ctx context.Context, id int) (_ models.Session, err error) {
+	ctx, done := instrument(ctx, "SessionRepo", "GetByID")
+	defer done(&err)
+
+	ctx, cancel := r.timeouts.read(ctx)
+	defer cancel()
+
+	var s models.Session
+	err = r.col.FindOne(ctx, bson.M{"id": id}).Decode(&s)
+	if err == mongo.ErrNoDocuments {
+		return models.Session{}, NotFound("session not found")
+	}
+	return s, err
+}
+
+func (r *SessionRepo) ListByUser(ctx context.Context, userID int) []models.Session {
+	var err error
+	ctx, done := instrument(ctx, "SessionRepo", "ListByUser")
+	defer done(&err)
+
+	ctx, cancel := r.timeouts.read(ctx)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.M{"createdAt": -1})
+	cur, err := r.col.Find(ctx, bson.M{"userId": userID}, opts)
+	if err != nil {
+		return []models.Session{}
+	}
+	defer cur.Close(ctx)
+
+	out := []models.Session{}
+	for cur.Next(ctx) {
+		var s models.Session
+		if cur.Decode(&s) == nil {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func (r *SessionRepo) Revoke(ctx context.Context, id int) (err error) {
+	ctx, done := instrument(ctx, "SessionRepo", "Revoke")
+	defer done(&err)
+
+	ctx, cancel := r.timeouts.write(ctx)
+	defer cancel()
+
+	res, err := r.col.UpdateOne(ctx,
+		bson.M{"id": id, "revokedAt": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"revokedAt": time.Now()}},
+	)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return NotFound("session not found")
+	}
+	return nil
+}
+
+func (r *SessionRepo) RevokeAllExcept(ctx context.Context, userID int, keepID int) (err error) {
+	ctx, done := instrument(ctx, "SessionRepo", "RevokeAllExcept")
+	defer done(&err)
+
+	ctx, cancel := r.timeouts.write(ctx)
+	defer cancel()
+
+	_, err = r.col.UpdateMany(ctx,
+		bson.M{"userId": userID, "id": bson.M{"$ne": keepID}, "revokedAt": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"revokedAt": time.Now()}},
+	)
+	return err
+}
+
+func (r *SessionRepo) DeleteByUser(ctx context.Context, userID int) (err error) {
+	ctx, done := instrument(ctx, "SessionRepo", "DeleteByUser")
+	defer done(&err)
+
+	ctx, cancel := r.timeouts.write(ctx)
+	defer cancel()
+
+	_, err = r.col.DeleteMany(ctx, bson.M{"userId": userID})
+	return err
+}
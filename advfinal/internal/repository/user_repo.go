@@ -2,7 +2,6 @@ package repository
 
 import (
 	"context"
-	"errors"
 	"time"
 
 	"bookstore/internal/models"
@@ -12,97 +11,123 @@ import (
 )
 
 type UserRepository interface {
-	Create(user models.User) error
-	GetByEmail(email string) (models.User, error)
-	GetByID(id int) (models.User, error)
-	Update(user models.User) error
+	Create(ctx context.Context, user models.User) error
+	GetByEmail(ctx context.Context, email string) (models.User, error)
+	GetByID(ctx context.Context, id int) (models.User, error)
+	Update(ctx context.Context, user models.User) error
 }
 
 type UserRepo struct {
 	col      *mongo.Collection
 	counters *CounterRepo
+	timeouts Timeouts
 }
 
-func NewUserRepo(db *mongo.Database) *UserRepo {
+func NewUserRepo(db *mongo.Database, t Timeouts) *UserRepo {
 	return &UserRepo{
 		col:      db.Collection("users"),
-		counters: NewCounterRepo(db),
+		counters: NewCounterRepo(db, t),
+		timeouts: t,
 	}
 }
 
-func (r *UserRepo) Create(user models.User) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (r *UserRepo) Create(ctx context.Context, user models.User) (err error) {
+	ctx, done := instrument(ctx, "UserRepo", "Create")
+	defer done(&err)
+
+	ctx, cancel := r.timeouts.write(ctx)
 	defer cancel()
 
 	if user.Email == "" {
-		return errors.New("email required")
+		return Invalid("email", "email required")
 	}
 	if user.Password == "" {
-		return errors.New("password required")
+		return Invalid("password", "password required")
 	}
 	if user.Role == "" {
 		user.Role = "user"
 	}
+	if user.CreatedAt.IsZero() {
+		user.CreatedAt = time.Now()
+	}
 
 	exists, err := r.col.CountDocuments(ctx, bson.M{"email": user.Email})
 	if err != nil {
 		return err
 	}
 	if exists > 0 {
-		return errors.New("email already exists")
+		return Conflict("email already exists")
 	}
 
-	id, err := r.counters.Next("users")
+	id, err := r.counters.Next(ctx, "users")
 	if err != nil {
 		return err
 	}
 	user.ID = id
 
+	// The count above is only a fast path; the unique email index (migration
+	// 2) settles concurrent registrations.
 	_, err = r.col.InsertOne(ctx, user)
+	if mongo.IsDuplicateKeyError(err) {
+		return Conflict("email already exists")
+	}
 	return err
 }
 
-func (r *UserRepo) GetByEmail(email string) (models.User, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (r *UserRepo) GetByEmail(ctx context.Context, email string) (_ models.User, err error) {
+	ctx, done := instrument(ctx, "UserRepo", "GetByEmail")
+	defer done(&err)
+
+	ctx, cancel := r.timeouts.read(ctx)
 	defer cancel()
 
 	var u models.User
-	err := r.col.FindOne(ctx, bson.M{"email": email}).Decode(&u)
+	err = r.col.FindOne(ctx, bson.M{"email": email}).Decode(&u)
 	if err == mongo.ErrNoDocuments {
-		return models.User{}, errors.New("user not found")
+		return models.User{}, NotFound("user not found")
 	}
 	return u, err
 }
 
-func (r *UserRepo) GetByID(id int) (models.User, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (r *UserRepo) GetByID(ctx context.Context, id int) (_ models.User, err error) {
+	ctx, done := instrument(ctx, "UserRepo", "GetByID")
+	defer done(&err)
+
+	ctx, cancel := r.timeouts.read(ctx)
 	defer cancel()
 
 	var u models.User
-	err := r.col.FindOne(ctx, bson.M{"id": id}).Decode(&u)
+	err = r.col.FindOne(ctx, bson.M{"id": id}).Decode(&u)
 	if err == mongo.ErrNoDocuments {
-		return models.User{}, errors.New("user not found")
+		return models.User{}, NotFound("user not found")
 	}
 	return u, err
 }
-func (r *UserRepo) Update(user models.User) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (r *UserRepo) Update(ctx context.Context, user models.User) (err error) {
+	ctx, done := instrument(ctx, "UserRepo", "Update")
+	defer done(&err)
+
+	ctx, cancel := r.timeouts.write(ctx)
 	defer cancel()
 
 	if user.ID <= 0 {
-		return errors.New("invalid user id")
+		return Invalid("id", "invalid user id")
 	}
 
-	res, err := r.col.UpdateOne(
+	// Replace rather than $set so cleared optional fields are really removed.
+	res, err := r.col.ReplaceOne(
 		ctx,
 		bson.M{"id": user.ID},
-		bson.M{"$set": user},
+		user,
 	)
+	if mongo.IsDuplicateKeyError(err) {
+		return Conflict("email already exists")
+	}
 	if err != nil {
 		return err
 	}
 	if res.MatchedCount == 0 {
-		return errors.New("user not found")
+		return NotFound("user not found")
 	}
 	return nil
 }
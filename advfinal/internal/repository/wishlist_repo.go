@@ -2,8 +2,6 @@ package repository
 
 import (
 	"context"
-	"errors"
-	"time"
 
 	"bookstore/internal/models"
 
@@ -12,38 +10,44 @@ import (
 )
 
 type WishlistRepository interface {
-	Create(customerID int) models.Wishlist
-	GetAll() []models.Wishlist
-	GetByID(id int) (models.Wishlist, []models.WishlistItem, error)
-	Delete(id int) error
+	Create(ctx context.Context, customerID int) models.Wishlist
+	GetAll(ctx context.Context) []models.Wishlist
+	GetByID(ctx context.Context, id int) (models.Wishlist, []models.WishlistItem, error)
+	Delete(ctx context.Context, id int) error
 
-	AddItem(wishlistID int, bookID int, qty int) (models.WishlistItem, error)
-	DeleteItem(wishlistID int, itemID int) error
+	AddItem(ctx context.Context, wishlistID int, bookID int, qty int) (models.WishlistItem, error)
+	DeleteItem(ctx context.Context, wishlistID int, itemID int) error
 }
 
 type WishlistRepo struct {
 	wishlistsCol *mongo.Collection
 	itemsCol     *mongo.Collection
 	counters     *CounterRepo
+	timeouts     Timeouts
 }
 
-func NewWishlistRepo(db *mongo.Database) *WishlistRepo {
+func NewWishlistRepo(db *mongo.Database, t Timeouts) *WishlistRepo {
 	return &WishlistRepo{
 		wishlistsCol: db.Collection("wishlists"),
 		itemsCol:     db.Collection("wishlist_items"),
-		counters:     NewCounterRepo(db),
+		counters:     NewCounterRepo(db, t),
+		timeouts:     t,
 	}
 }
 
-func (r *WishlistRepo) Create(customerID int) models.Wishlist {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (r *WishlistRepo) Create(ctx context.Context, customerID int) models.Wishlist {
+	var err error
+	ctx, done := instrument(ctx, "WishlistRepo", "Create")
+	defer done(&err)
+
+	ctx, cancel := r.timeouts.write(ctx)
 	defer cancel()
 
 	if customerID <= 0 {
 		customerID = 1
 	}
 
-	id, err := r.counters.Next("wishlists")
+	id, err := r.counters.Next(ctx, "wishlists")
 	if err != nil {
 		return models.Wishlist{}
 	}
@@ -61,8 +65,12 @@ func (r *WishlistRepo) Create(customerID int) models.Wishlist {
 	return w
 }
 
-func (r *WishlistRepo) GetAll() []models.Wishlist {
-	ctx, cancel := context.WithTimeout(context.Background(), 8*time.Second)
+func (r *WishlistRepo) GetAll(ctx context.Context) []models.Wishlist {
+	var err error
+	ctx, done := instrument(ctx, "WishlistRepo", "GetAll")
+	defer done(&err)
+
+	ctx, cancel := r.timeouts.read(ctx)
 	defer cancel()
 
 	cur, err := r.wishlistsCol.Find(ctx, bson.M{})
@@ -81,14 +89,17 @@ func (r *WishlistRepo) GetAll() []models.Wishlist {
 	return out
 }
 
-func (r *WishlistRepo) GetByID(id int) (models.Wishlist, []models.WishlistItem, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 8*time.Second)
+func (r *WishlistRepo) GetByID(ctx context.Context, id int) (_ models.Wishlist, _ []models.WishlistItem, err error) {
+	ctx, done := instrument(ctx, "WishlistRepo", "GetByID")
+	defer done(&err)
+
+	ctx, cancel := r.timeouts.read(ctx)
 	defer cancel()
 
 	var w models.Wishlist
-	err := r.wishlistsCol.FindOne(ctx, bson.M{"id": id}).Decode(&w)
+	err = r.wishlistsCol.FindOne(ctx, bson.M{"id": id}).Decode(&w)
 	if err == mongo.ErrNoDocuments {
-		return models.Wishlist{}, nil, errors.New("wishlist not found")
+		return models.Wishlist{}, nil, NotFound("wishlist not found")
 	}
 	if err != nil {
 		return models.Wishlist{}, nil, err
@@ -111,8 +122,11 @@ func (r *WishlistRepo) GetByID(id int) (models.Wishlist, []models.WishlistItem,
 	return w, items, nil
 }
 
-func (r *WishlistRepo) Delete(id int) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 8*time.Second)
+func (r *WishlistRepo) Delete(ctx context.Context, id int) (err error) {
+	ctx, done := instrument(ctx, "WishlistRepo", "Delete")
+	defer done(&err)
+
+	ctx, cancel := r.timeouts.write(ctx)
 	defer cancel()
 
 	res, err := r.wishlistsCol.DeleteOne(ctx, bson.M{"id": id})
@@ -120,24 +134,27 @@ func (r *WishlistRepo) Delete(id int) error {
 		return err
 	}
 	if res.DeletedCount == 0 {
-		return errors.New("wishlist not found")
+		return NotFound("wishlist not found")
 	}
 
 	_, _ = r.itemsCol.DeleteMany(ctx, bson.M{"wishlistId": id})
 	return nil
 }
 
-func (r *WishlistRepo) AddItem(wishlistID int, bookID int, qty int) (models.WishlistItem, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+func (r *WishlistRepo) AddItem(ctx context.Context, wishlistID int, bookID int, qty int) (_ models.WishlistItem, err error) {
+	ctx, done := instrument(ctx, "WishlistRepo", "AddItem")
+	defer done(&err)
+
+	ctx, cancel := r.timeouts.write(ctx)
 	defer cancel()
 
 	if qty <= 0 {
-		return models.WishlistItem{}, errors.New("qty must be > 0")
+		return models.WishlistItem{}, Invalid("qty", "qty must be > 0")
 	}
 
-	err := r.wishlistsCol.FindOne(ctx, bson.M{"id": wishlistID}).Err()
+	err = r.wishlistsCol.FindOne(ctx, bson.M{"id": wishlistID}).Err()
 	if err == mongo.ErrNoDocuments {
-		return models.WishlistItem{}, errors.New("wishlist not found")
+		return models.WishlistItem{}, NotFound("wishlist not found")
 	}
 	if err != nil {
 		return models.WishlistItem{}, err
@@ -157,7 +174,7 @@ func (r *WishlistRepo) AddItem(wishlistID int, bookID int, qty int) (models.Wish
 		return models.WishlistItem{}, res.Err()
 	}
 
-	itemID, err := r.counters.Next("wishlist_items")
+	itemID, err := r.counters.Next(ctx, "wishlist_items")
 	if err != nil {
 		return models.WishlistItem{}, err
 	}
@@ -177,8 +194,11 @@ func (r *WishlistRepo) AddItem(wishlistID int, bookID int, qty int) (models.Wish
 	return it, nil
 }
 
-func (r *WishlistRepo) DeleteItem(wishlistID int, itemID int) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 8*time.Second)
+func (r *WishlistRepo) DeleteItem(ctx context.Context, wishlistID int, itemID int) (err error) {
+	ctx, done := instrument(ctx, "WishlistRepo", "DeleteItem")
+	defer done(&err)
+
+	ctx, cancel := r.timeouts.write(ctx)
 	defer cancel()
 
 	res, err := r.itemsCol.DeleteOne(ctx, bson.M{"wishlistId": wishlistID, "id": itemID})
@@ -186,7 +206,7 @@ func (r *WishlistRepo) DeleteItem(wishlistID int, itemID int) error {
 		return err
 	}
 	if res.DeletedCount == 0 {
-		return errors.New("item not found")
+		return NotFound("item not found")
 	}
 	return nil
 }
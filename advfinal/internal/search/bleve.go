@@ -0,0 +1,448 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"bookstore/internal/isbn"
+	"bookstore/internal/models"
+	"bookstore/internal/repository"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/analysis/analyzer/custom"
+	"github.com/blevesearch/bleve/v2/analysis/char/asciifolding"
+	"github.com/blevesearch/bleve/v2/analysis/token/lowercase"
+	"github.com/blevesearch/bleve/v2/analysis/tokenizer/unicode"
+	"github.com/blevesearch/bleve/v2/mapping"
+	"github.com/blevesearch/bleve/v2/search"
+	"github.com/blevesearch/bleve/v2/search/query"
+)
+
+// foldedAnalyzer splits text into words, lowercased and stripped of
+// accents. It keeps stop words and does not stem: typo tolerance already
+// finds most inflections, and "the" may be part of a title.
+const foldedAnalyzer = "folded"
+
+// textFields are searched by every word of a query, weighted by boost.
+var textFields = []struct {
+	name  string
+	boost float64
+}{
+	{"title", 4},
+	{"authors", 3},
+	{"genre", 1.5},
+	{"publisher", 1},
+	{"description", 0.5},
+}
+
+// A word found only with a typo, or as the start of a longer one, counts
+// for less than the exact word.
+const (
+	fuzzyWeight  = 0.4
+	prefixWeight = 0.6
+	isbnBoost    = 10
+	minPrefix    = 2 // runes
+	rebuildBatch = 500
+)
+
+// openConfig makes opening an index another process holds, such as the
+// CLI while the server runs, fail instead of wait. It is a new map each
+// time, as bleve writes the store's path into it.
+func openConfig() map[string]any {
+	return map[string]any{"bolt_timeout": "2s"}
+}
+
+// document is what the index keeps of a book.
+type document struct {
+	Title       string   `json:"title"`
+	Authors     []string `json:"authors"`
+	Genre       string   `json:"genre"`
+	Publisher   string   `json:"publisher"`
+	Description string   `json:"description"`
+	ISBN        string   `json:"isbn"`
+	Price       float64  `json:"price"`
+}
+
+func documentOf(b models.Book) document {
+	return document{
+		Title:       b.Title,
+		Authors:     authorsOf(b),
+		Genre:       b.Genre,
+		Publisher:   b.Publisher,
+		Description: b.Description,
+		ISBN:        b.ISBN,
+		Price:       b.Price,
+	}
+}
+
+func docID(id int) string { return strconv.Itoa(id) }
+
+// BleveIndex keeps the index in a directory, which only one process can
+// have open at a time.
+type BleveIndex struct {
+	dir string
+
+	rebuilding sync.Mutex // one Rebuild at a time
+
+	mu  sync.RWMutex // guards idx and pending
+	idx bleve.Index
+	// pending records the books written during a Rebuild, nil for deleted
+	// ones, to apply them to the new index before it replaces the old.
+	pending map[int]*models.Book
+}
+
+// OpenBleve opens the index in dir, creating it if there is none. empty
+// reports an index without books, new or left so by a failed Rebuild,
+// which the caller should Rebuild.
+func OpenBleve(dir string) (idx *BleveIndex, empty bool, err error) {
+	bi, err := bleve.OpenUsing(dir, openConfig())
+	if err == bleve.ErrorIndexPathDoesNotExist {
+		bi, err = newBleve(dir)
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("search index %s: %w", dir, err)
+	}
+	n, err := bi.DocCount()
+	if err != nil {
+		bi.Close()
+		return nil, false, fmt.Errorf("search index %s: %w", dir, err)
+	}
+	return &BleveIndex{dir: dir, idx: bi}, n == 0, nil
+}
+
+func newBleve(dir string) (bleve.Index, error) {
+	if err := os.MkdirAll(filepath.Dir(dir), 0o755); err != nil {
+		return nil, err
+	}
+	m, err := indexMapping()
+	if err != nil {
+		return nil, err
+	}
+	return bleve.NewUsing(dir, m, bleve.Config.DefaultIndexType, bleve.Config.DefaultKVStore, openConfig())
+}
+
+// indexMapping indexes the text fields for search and, unanalyzed, the
+// genre and authors for facets and filters.
+func indexMapping() (mapping.IndexMapping, error) {
+	im := bleve.NewIndexMapping()
+	err := im.AddCustomAnalyzer(foldedAnalyzer, map[string]any{
+		"type":          custom.Name,
+		"char_filters":  []string{asciifolding.Name},
+		"tokenizer":     unicode.Name,
+		"token_filters": []string{lowercase.Name},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	text := func() *mapping.FieldMapping {
+		fm := bleve.NewTextFieldMapping()
+		fm.Analyzer = foldedAnalyzer
+		fm.Store = false
+		fm.IncludeTermVectors = false
+		fm.IncludeInAll = false
+		return fm
+	}
+	keyword := func(name string) *mapping.FieldMapping {
+		fm := bleve.NewKeywordFieldMapping()
+		fm.Name = name
+		fm.Store = false
+		fm.IncludeTermVectors = false
+		fm.IncludeInAll = false
+		return fm
+	}
+	price := bleve.NewNumericFieldMapping()
+	price.Store = false
+	price.IncludeInAll = false
+
+	doc := bleve.NewDocumentStaticMapping()
+	doc.AddFieldMappingsAt("title", text())
+	doc.AddFieldMappingsAt("authors", text(), keyword("authorFacet"))
+	doc.AddFieldMappingsAt("genre", text(), keyword("genreFacet"))
+	doc.AddFieldMappingsAt("publisher", text())
+	doc.AddFieldMappingsAt("description", text())
+	doc.AddFieldMappingsAt("isbn", keyword("isbn"))
+	doc.AddFieldMappingsAt("price", price)
+
+	im.DefaultMapping = doc
+	im.DefaultAnalyzer = foldedAnalyzer
+	return im, nil
+}
+
+func (b *BleveIndex) Index(ctx context.Context, book models.Book) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.pending != nil {
+		b.pending[book.ID] = &book
+	}
+	return b.idx.Index(docID(book.ID), documentOf(book))
+}
+
+func (b *BleveIndex) Delete(ctx context.Context, id int) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.pending != nil {
+		b.pending[id] = nil
+	}
+	return b.idx.Delete(docID(id))
+}
+
+func (b *BleveIndex) Search(ctx context.Context, q Query) (Result, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	bq, err := b.query(q)
+	if err != nil {
+		return Result{}, err
+	}
+	req := bleve.NewSearchRequestOptions(bq, q.Limit, q.Offset, false)
+	req.AddFacet("genre", bleve.NewFacetRequest("genreFacet", facetSize))
+	req.AddFacet("author", bleve.NewFacetRequest("authorFacet", facetSize))
+	prices := bleve.NewFacetRequest("price", len(PriceBands))
+	for _, band := range PriceBands {
+		lo, hi := band.Min, band.Max
+		if hi == 0 {
+			prices.AddNumericRange(band.Key, &lo, nil)
+		} else {
+			prices.AddNumericRange(band.Key, &lo, &hi)
+		}
+	}
+	req.AddFacet("price", prices)
+
+	res, err := b.idx.SearchInContext(ctx, req)
+	if err != nil {
+		return Result{}, err
+	}
+
+	out := Result{Total: int(res.Total), Hits: make([]Hit, 0, len(res.Hits))}
+	for _, h := range res.Hits {
+		id, err := strconv.Atoi(h.ID)
+		if err != nil {
+			continue
+		}
+		out.Hits = append(out.Hits, Hit{ID: id, Score: h.Score})
+	}
+	out.Facets.Genre = termCounts(res.Facets["genre"])
+	out.Facets.Author = termCounts(res.Facets["author"])
+	counts := map[string]int{}
+	if f := res.Facets["price"]; f != nil {
+		for _, r := range f.NumericRanges {
+			counts[r.Name] = r.Count
+		}
+	}
+	out.Facets.Price = priceCounts(counts)
+	return out, nil
+}
+
+// query requires every word of the text and every filter to match.
+func (b *BleveIndex) query(q Query) (query.Query, error) {
+	var must []query.Query
+	if text := strings.TrimSpace(q.Text); text != "" {
+		must = append(must, b.textQuery(text))
+	}
+	if q.Genre != "" {
+		tq := bleve.NewTermQuery(q.Genre)
+		tq.SetField("genreFacet")
+		must = append(must, tq)
+	}
+	if q.Author != "" {
+		tq := bleve.NewTermQuery(q.Author)
+		tq.SetField("authorFacet")
+		must = append(must, tq)
+	}
+	if q.Price != "" {
+		band, err := priceBand(q.Price)
+		if err != nil {
+			return nil, err
+		}
+		lo, inclusive := band.Min, true
+		var hi *float64
+		if band.Max != 0 {
+			hi = &band.Max
+		}
+		rq := bleve.NewNumericRangeInclusiveQuery(&lo, hi, &inclusive, nil)
+		rq.SetField("price")
+		must = append(must, rq)
+	}
+	if len(must) == 0 {
+		return bleve.NewMatchAllQuery(), nil
+	}
+	return bleve.NewConjunctionQuery(must...), nil
+}
+
+// textQuery matches each word of text in any text field, exactly, with a
+// typo or, for the last word, which may still be being typed, as a
+// prefix. Text that is an ISBN also finds the book by it.
+func (b *BleveIndex) textQuery(text string) query.Query {
+	tokens := b.idx.Mapping().AnalyzerNamed(foldedAnalyzer).Analyze([]byte(text))
+	words := make([]query.Query, 0, len(tokens))
+	for i, tok := range tokens {
+		term := string(tok.Term)
+		last := i == len(tokens)-1
+		var alts []query.Query
+		for _, f := range textFields {
+			tq := bleve.NewTermQuery(term)
+			tq.SetField(f.name)
+			tq.SetBoost(f.boost)
+			alts = append(alts, tq)
+
+			if fz := fuzziness(term); fz > 0 {
+				fq := bleve.NewFuzzyQuery(term)
+				fq.SetField(f.name)
+				fq.SetFuzziness(fz)
+				fq.SetBoost(f.boost * fuzzyWeight)
+				alts = append(alts, fq)
+			}
+			if last && utf8.RuneCountInString(term) >= minPrefix {
+				pq := bleve.NewPrefixQuery(term)
+				pq.SetField(f.name)
+				pq.SetBoost(f.boost * prefixWeight)
+				alts = append(alts, pq)
+			}
+		}
+		words = append(words, bleve.NewDisjunctionQuery(alts...))
+	}
+
+	var q query.Query = bleve.NewMatchNoneQuery()
+	if len(words) > 0 {
+		q = bleve.NewConjunctionQuery(words...)
+	}
+	if n, err := isbn.Normalize(text); err == nil {
+		tq := bleve.NewTermQuery(n)
+		tq.SetField("isbn")
+		tq.SetBoost(isbnBoost)
+		q = bleve.NewDisjunctionQuery(q, tq)
+	}
+	return q
+}
+
+// fuzziness is the number of typos tolerated in a word: none in short
+// words, where one already changes the meaning, and at most two.
+func fuzziness(word string) int {
+	switch n := utf8.RuneCountInString(word); {
+	case n < 4:
+		return 0
+	case n < 8:
+		return 1
+	default:
+		return 2
+	}
+}
+
+func termCounts(f *search.FacetResult) []FacetCount {
+	out := []FacetCount{}
+	if f == nil {
+		return out
+	}
+	for _, t := range f.Terms.Terms() {
+		out = append(out, FacetCount{Value: t.Term, Count: t.Count})
+	}
+	return out
+}
+
+func priceCounts(counts map[string]int) []FacetCount {
+	out := []FacetCount{}
+	for _, band := range PriceBands {
+		if n := counts[band.Key]; n > 0 {
+			out = append(out, FacetCount{Value: band.Key, Label: band.Label, Count: n})
+		}
+	}
+	return out
+}
+
+// Rebuild builds the new index beside the live one and swaps it in, so
+// searches see the old index until it is complete. Books written in the
+// meantime are applied to both.
+func (b *BleveIndex) Rebuild(ctx context.Context, books repository.BookRepository) (int, error) {
+	b.rebuilding.Lock()
+	defer b.rebuilding.Unlock()
+
+	tmp := b.dir + ".rebuild"
+	if err := os.RemoveAll(tmp); err != nil {
+		return 0, err
+	}
+	next, err := newBleve(tmp)
+	if err != nil {
+		return 0, err
+	}
+
+	b.mu.Lock()
+	b.pending = map[int]*models.Book{}
+	b.mu.Unlock()
+
+	n, err := fill(ctx, next, books)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	pending := b.pending
+	b.pending = nil
+	for id, book := range pending {
+		if err != nil {
+			break
+		}
+		if book == nil {
+			err = next.Delete(docID(id))
+		} else {
+			err = next.Index(docID(id), documentOf(*book))
+		}
+	}
+	if cerr := next.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.RemoveAll(tmp)
+		return 0, err
+	}
+
+	if err := b.idx.Close(); err != nil {
+		return 0, err
+	}
+	if err := os.RemoveAll(b.dir); err != nil {
+		return 0, err
+	}
+	if err := os.Rename(tmp, b.dir); err != nil {
+		return 0, err
+	}
+	idx, err := bleve.OpenUsing(b.dir, openConfig())
+	if err != nil {
+		// Searches fail on the closed index until the server restarts.
+		return 0, fmt.Errorf("search index %s: %w", b.dir, err)
+	}
+	b.idx = idx
+	return n, nil
+}
+
+func fill(ctx context.Context, idx bleve.Index, books repository.BookRepository) (int, error) {
+	n := 0
+	batch := idx.NewBatch()
+	err := books.Each(ctx, func(book models.Book) error {
+		if err := batch.Index(docID(book.ID), documentOf(book)); err != nil {
+			return err
+		}
+		n++
+		if batch.Size() < rebuildBatch {
+			return nil
+		}
+		err := idx.Batch(batch)
+		batch.Reset()
+		return err
+	})
+	if err == nil && batch.Size() > 0 {
+		err = idx.Batch(batch)
+	}
+	return n, err
+}
+
+func (b *BleveIndex) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.idx.Close()
+}
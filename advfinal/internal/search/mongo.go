@@ -0,0 +1,183 @@
+package search
+
+import (
+	"context"
+	"math"
+	"strings"
+
+	"bookstore/internal/metrics"
+	"bookstore/internal/models"
+	"bookstore/internal/repository"
+	"bookstore/internal/tracing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// MongoIndex searches the books collection through its text index
+// (migration 12), which Mongo keeps up to date itself. Words are stemmed
+// but must be spelled right, and a book matching any of them is found,
+// ranked by how many it matches and where.
+type MongoIndex struct {
+	col      *mongo.Collection
+	timeouts repository.Timeouts
+}
+
+func NewMongoIndex(db *mongo.Database, t repository.Timeouts) *MongoIndex {
+	return &MongoIndex{col: db.Collection("books"), timeouts: t}
+}
+
+func (m *MongoIndex) Index(ctx context.Context, book models.Book) error { return nil }
+
+func (m *MongoIndex) Delete(ctx context.Context, id int) error { return nil }
+
+type mongoBucket struct {
+	Value string `bson:"_id"`
+	Count int    `bson:"n"`
+}
+
+func (m *MongoIndex) Search(ctx context.Context, q Query) (Result, error) {
+	ctx, done := instrument(ctx, "MongoIndex", "Search")
+	defer done()
+
+	ctx, cancel := context.WithTimeout(ctx, m.timeouts.Read)
+	defer cancel()
+
+	match := bson.M{}
+	text := strings.TrimSpace(q.Text)
+	if text != "" {
+		match["$text"] = bson.M{"$search": text}
+	}
+	if q.Genre != "" {
+		match["genre"] = q.Genre
+	}
+	if q.Author != "" {
+		match["$or"] = bson.A{bson.M{"authors": q.Author}, bson.M{"author": q.Author}}
+	}
+	if q.Price != "" {
+		band, err := priceBand(q.Price)
+		if err != nil {
+			return Result{}, err
+		}
+		price := bson.M{"$gte": band.Min}
+		if band.Max != 0 {
+			price["$lt"] = band.Max
+		}
+		match["price"] = price
+	}
+
+	pipeline := mongo.Pipeline{{{Key: "$match", Value: match}}}
+	order := bson.D{{Key: "id", Value: 1}}
+	if text != "" {
+		pipeline = append(pipeline, bson.D{{Key: "$addFields", Value: bson.M{"score": bson.M{"$meta": "textScore"}}}})
+		order = bson.D{{Key: "score", Value: -1}, {Key: "id", Value: 1}}
+	}
+	boundaries := bson.A{}
+	for _, band := range PriceBands {
+		boundaries = append(boundaries, band.Min)
+	}
+	boundaries = append(boundaries, math.MaxFloat64)
+	count := bson.M{"n": bson.M{"$sum": 1}}
+	pipeline = append(pipeline, bson.D{{Key: "$facet", Value: bson.M{
+		"hits": bson.A{
+			bson.M{"$sort": order},
+			bson.M{"$skip": q.Offset},
+			bson.M{"$limit": max(q.Limit, 1)}, // Mongo rejects 0
+			bson.M{"$project": bson.M{"_id": 0, "id": 1, "score": 1}},
+		},
+		"total": bson.A{bson.M{"$count": "n"}},
+		"genre": bson.A{
+			bson.M{"$match": bson.M{"genre": bson.M{"$gt": ""}}},
+			bson.M{"$group": bson.M{"_id": "$genre", "n": bson.M{"$sum": 1}}},
+			bson.M{"$sort": bson.D{{Key: "n", Value: -1}, {Key: "_id", Value: 1}}},
+			bson.M{"$limit": facetSize},
+		},
+		"author": bson.A{
+			bson.M{"$project": bson.M{"a": bson.M{"$ifNull": bson.A{"$authors", bson.A{"$author"}}}}},
+			bson.M{"$unwind": "$a"},
+			bson.M{"$group": bson.M{"_id": "$a", "n": bson.M{"$sum": 1}}},
+			bson.M{"$sort": bson.D{{Key: "n", Value: -1}, {Key: "_id", Value: 1}}},
+			bson.M{"$limit": facetSize},
+		},
+		"price": bson.A{
+			bson.M{"$bucket": bson.M{"groupBy": "$price", "boundaries": boundaries, "default": "other", "output": count}},
+		},
+	}}})
+
+	cur, err := m.col.Aggregate(ctx, pipeline)
+	if err != nil {
+		return Result{}, err
+	}
+	defer cur.Close(ctx)
+
+	var res []struct {
+		Hits   []Hit             `bson:"hits"`
+		Total  []struct{ N int } `bson:"total"`
+		Genre  []mongoBucket     `bson:"genre"`
+		Author []mongoBucket     `bson:"author"`
+		Price  []struct {
+			Min   any `bson:"_id"`
+			Count int `bson:"n"`
+		} `bson:"price"`
+	}
+	if err := cur.All(ctx, &res); err != nil {
+		return Result{}, err
+	}
+
+	out := Result{Hits: []Hit{}, Facets: Facets{Genre: []FacetCount{}, Author: []FacetCount{}, Price: []FacetCount{}}}
+	if len(res) == 0 {
+		return out, nil
+	}
+	r := res[0]
+	if len(r.Total) > 0 {
+		out.Total = r.Total[0].N
+	}
+	if q.Limit > 0 {
+		out.Hits = r.Hits[:min(len(r.Hits), q.Limit)]
+	}
+	for _, b := range r.Genre {
+		out.Facets.Genre = append(out.Facets.Genre, FacetCount{Value: b.Value, Count: b.Count})
+	}
+	for _, b := range r.Author {
+		out.Facets.Author = append(out.Facets.Author, FacetCount{Value: b.Value, Count: b.Count})
+	}
+	counts := map[string]int{}
+	for _, b := range r.Price {
+		if lo, ok := b.Min.(float64); ok {
+			for _, band := range PriceBands {
+				if band.Min == lo {
+					counts[band.Key] = b.Count
+				}
+			}
+		}
+	}
+	out.Facets.Price = priceCounts(counts)
+	return out, nil
+}
+
+// Rebuild has nothing to do, as Mongo maintains the text index on every
+// write; it only counts the books covered.
+func (m *MongoIndex) Rebuild(ctx context.Context, books repository.BookRepository) (int, error) {
+	ctx, done := instrument(ctx, "MongoIndex", "Rebuild")
+	defer done()
+
+	ctx, cancel := context.WithTimeout(ctx, m.timeouts.Read)
+	defer cancel()
+
+	n, err := m.col.CountDocuments(ctx, bson.M{})
+	return int(n), err
+}
+
+func (m *MongoIndex) Close() error { return nil }
+
+// instrument mirrors the repository package's, as MongoIndex reads the
+// books collection directly.
+func instrument(ctx context.Context, repo, method string) (context.Context, func()) {
+	stop := metrics.ObserveMongo(repo, method)
+	ctx, span := tracing.Start(ctx, repo+"."+method, attribute.String("db.system", "mongodb"))
+	return ctx, func() {
+		span.End()
+		stop()
+	}
+}
@@ -0,0 +1,111 @@
+// Package search finds books by free text. A SearchIndex ranks books by
+// relevance and counts the facets of the matches, so a search can be
+// narrowed by genre, author and price band.
+//
+// Two backends implement it: BleveIndex, an index on local disk with typo
+// tolerance and prefix matching, and MongoIndex, which needs no state of
+// its own but only matches whole (stemmed) words. A Suggester, kept in
+// memory whatever the backend, completes searches as they are typed.
+package search
+
+import (
+	"context"
+	"errors"
+
+	"bookstore/internal/models"
+	"bookstore/internal/repository"
+)
+
+var ErrUnknownPriceBand = errors.New("unknown price band")
+
+// Indexer is kept in step with the books by Synced.
+type Indexer interface {
+	// Index adds or replaces a book; Delete removes one and succeeds for a
+	// book not in the index.
+	Index(ctx context.Context, book models.Book) error
+	Delete(ctx context.Context, id int) error
+}
+
+type SearchIndex interface {
+	Indexer
+	Search(ctx context.Context, q Query) (Result, error)
+	// Rebuild indexes every book of books from scratch and returns their
+	// number. Searches keep working, on the old index, meanwhile.
+	Rebuild(ctx context.Context, books repository.BookRepository) (int, error)
+	Close() error
+}
+
+// Query is a search. An empty Text matches every book, so the filters
+// alone browse the catalog.
+type Query struct {
+	Text   string
+	Genre  string // filters, matched exactly
+	Author string
+	Price  string // the Key of one of PriceBands
+	Offset int
+	Limit  int
+}
+
+type Result struct {
+	Total  int    `json:"total"`
+	Hits   []Hit  `json:"hits"`
+	Facets Facets `json:"facets"`
+}
+
+type Hit struct {
+	ID    int     `json:"id"`
+	Score float64 `json:"score"`
+}
+
+// Facets count the matches by value, most frequent first; Price follows
+// the order of PriceBands and leaves out empty bands.
+type Facets struct {
+	Genre  []FacetCount `json:"genre"`
+	Author []FacetCount `json:"author"`
+	Price  []FacetCount `json:"price"`
+}
+
+type FacetCount struct {
+	Value string `json:"value"`
+	Label string `json:"label,omitempty"` // for price bands
+	Count int    `json:"count"`
+}
+
+// facetSize is how many genres and authors a search counts.
+const facetSize = 10
+
+// PriceBand is the price range [Min, Max); a zero Max leaves it open.
+type PriceBand struct {
+	Key   string
+	Label string
+	Min   float64
+	Max   float64
+}
+
+var PriceBands = []PriceBand{
+	{Key: "0-10", Label: "Under $10", Min: 0, Max: 10},
+	{Key: "10-20", Label: "$10 to $20", Min: 10, Max: 20},
+	{Key: "20-50", Label: "$20 to $50", Min: 20, Max: 50},
+	{Key: "50-", Label: "$50 and over", Min: 50},
+}
+
+func priceBand(key string) (PriceBand, error) {
+	for _, b := range PriceBands {
+		if b.Key == key {
+			return b, nil
+		}
+	}
+	return PriceBand{}, ErrUnknownPriceBand
+}
+
+// authorsOf are the names a book is found and faceted by; books from
+// before the author list have only the display form.
+func authorsOf(b models.Book) []string {
+	if len(b.Authors) > 0 {
+		return b.Authors
+	}
+	if b.Author != "" {
+		return []string{b.Author}
+	}
+	return nil
+}
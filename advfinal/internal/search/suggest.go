@@ -0,0 +1,361 @@
+package search
+
+import (
+	"cmp"
+	"context"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"bookstore/internal/models"
+	"bookstore/internal/repository"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	// MaxSuggestions bounds each kind of suggestion in a Suggest result.
+	MaxSuggestions = 10
+	// shortPrefix is the longest query answered from precomputed lists;
+	// shorter ones would scan much of the index.
+	shortPrefix = 3
+	// maxScan bounds the keys a longer query looks at.
+	maxScan = 5000
+	// refreshDelay batches writes, such as an import's, into one refresh.
+	refreshDelay = 500 * time.Millisecond
+)
+
+type Suggestion struct {
+	Text string `json:"text"`
+	URL  string `json:"url"` // the page of the book, author or genre
+}
+
+type Suggestions struct {
+	Titles  []Suggestion `json:"titles"`
+	Authors []Suggestion `json:"authors"`
+	Genres  []Suggestion `json:"genres"`
+}
+
+// Suggester completes what a shopper types into titles, authors and
+// genres, from the start of any of their words. It keeps the whole catalog
+// in memory: writes go to a map, and a sorted snapshot built from it
+// shortly after answers the lookups without locking.
+type Suggester struct {
+	mu        sync.Mutex
+	books     map[int]suggestBook
+	loading   map[int]bool // books written during Load, which it skips
+	scheduled bool
+
+	snap atomic.Pointer[suggestSnapshot]
+}
+
+type suggestBook struct {
+	title   string
+	path    string
+	authors []Suggestion
+	genre   Suggestion
+	rating  int
+}
+
+func NewSuggester() *Suggester {
+	s := &Suggester{books: map[int]suggestBook{}}
+	s.snap.Store(&suggestSnapshot{})
+	return s
+}
+
+// Load reads every book of books, keeping any written meanwhile as they
+// were written.
+func (s *Suggester) Load(ctx context.Context, books repository.BookRepository) error {
+	s.mu.Lock()
+	s.loading = map[int]bool{}
+	s.mu.Unlock()
+
+	loaded := map[int]suggestBook{}
+	err := books.Each(ctx, func(b models.Book) error {
+		loaded[b.ID] = suggestBookOf(b)
+		return nil
+	})
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err == nil {
+		for id, b := range s.books {
+			if s.loading[id] {
+				loaded[id] = b
+			}
+		}
+		for id := range s.loading {
+			if _, ok := s.books[id]; !ok {
+				delete(loaded, id)
+			}
+		}
+		s.books = loaded
+		s.schedule()
+	}
+	s.loading = nil
+	return err
+}
+
+// suggestBookOf links authors and genres to their pages, or to the catalog
+// filtered by them for books not yet pointing at them.
+func suggestBookOf(b models.Book) suggestBook {
+	sb := suggestBook{title: b.Title, path: b.Path(), rating: b.RatingCount}
+	for _, a := range authorsOf(b) {
+		u := "/catalog?author=" + url.QueryEscape(a)
+		if len(b.AuthorIDs) > 0 {
+			u = models.EntityPath(models.EntityAuthor, a)
+		}
+		sb.authors = append(sb.authors, Suggestion{Text: a, URL: u})
+	}
+	if b.Genre != "" {
+		sb.genre = Suggestion{Text: b.Genre, URL: "/catalog?genre=" + url.QueryEscape(b.Genre)}
+		if b.GenreID != 0 {
+			sb.genre.URL = models.EntityPath(models.EntityGenre, b.Genre)
+		}
+	}
+	return sb
+}
+
+func (s *Suggester) Index(ctx context.Context, book models.Book) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.books[book.ID] = suggestBookOf(book)
+	s.touch(book.ID)
+	return nil
+}
+
+func (s *Suggester) Delete(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.books, id)
+	s.touch(id)
+	return nil
+}
+
+// touch marks id written and schedules a refresh; s.mu is held.
+func (s *Suggester) touch(id int) {
+	if s.loading != nil {
+		s.loading[id] = true
+	}
+	s.schedule()
+}
+
+func (s *Suggester) schedule() {
+	if !s.scheduled {
+		s.scheduled = true
+		time.AfterFunc(refreshDelay, s.refresh)
+	}
+}
+
+func (s *Suggester) refresh() {
+	s.mu.Lock()
+	books := make([]suggestBook, 0, len(s.books))
+	for _, b := range s.books {
+		books = append(books, b)
+	}
+	s.scheduled = false
+	s.mu.Unlock()
+
+	s.snap.Store(buildSnapshot(books))
+}
+
+// Suggest returns up to limit suggestions of each kind for the text typed
+// so far, best first. Each word typed matches the start of a word of the
+// suggestion, in order, so "pot cham" finds "Harry Potter and the Chamber of
+// Secrets". Ranked first are those starting with the first word, then the
+// most frequent authors and genres and the most reviewed titles.
+func (s *Suggester) Suggest(text string, limit int) Suggestions {
+	limit = min(limit, MaxSuggestions)
+	qs := words(text)
+	if len(qs) == 0 || limit <= 0 {
+		return Suggestions{Titles: []Suggestion{}, Authors: []Suggestion{}, Genres: []Suggestion{}}
+	}
+	return s.snap.Load().lookup(qs, limit)
+}
+
+// words splits s into lowercase words of letters and digits, stripped of
+// accents.
+func words(s string) []string {
+	fold := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	s, _, err := transform.String(fold, strings.ToLower(s))
+	if err != nil {
+		s = strings.ToLower(s)
+	}
+	return strings.FieldsFunc(s, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) })
+}
+
+// Suggestion kinds, in the order of the lists of Suggestions.
+const (
+	kindTitle = iota
+	kindAuthor
+	kindGenre
+	numKinds
+)
+
+type suggestEntry struct {
+	kind   int
+	text   string
+	url    string
+	weight int
+}
+
+// A suggestKey is an entry's normalized text from the start of one of its
+// words; leading when that is the first word.
+type suggestKey struct {
+	text    string
+	entry   int32
+	leading bool
+}
+
+type suggestSnapshot struct {
+	entries []suggestEntry
+	keys    []suggestKey // by text
+	// short holds the best MaxSuggestions entries of each kind for every
+	// query of up to shortPrefix runes.
+	short map[string]*ranking
+}
+
+func buildSnapshot(books []suggestBook) *suggestSnapshot {
+	snap := &suggestSnapshot{short: map[string]*ranking{}}
+	authors := map[string]int{} // normalized name to entry
+	genres := map[string]int{}
+	// add adds weight to an entry; authors and genres are merged by their
+	// normalized text.
+	add := func(kind int, text, url string, weight int, seen map[string]int) {
+		key := strings.Join(words(text), " ")
+		if key == "" {
+			return
+		}
+		if seen != nil {
+			if i, ok := seen[key]; ok {
+				snap.entries[i].weight += weight
+				return
+			}
+			seen[key] = len(snap.entries)
+		}
+		snap.entries = append(snap.entries, suggestEntry{kind: kind, text: text, url: url, weight: weight})
+	}
+	for _, b := range books {
+		add(kindTitle, b.title, b.path, 1+b.rating, nil)
+		for _, a := range b.authors {
+			add(kindAuthor, a.Text, a.URL, 1, authors)
+		}
+		if b.genre.Text != "" {
+			add(kindGenre, b.genre.Text, b.genre.URL, 1, genres)
+		}
+	}
+
+	for i, e := range snap.entries {
+		ws := words(e.text)
+		for j := range ws {
+			snap.keys = append(snap.keys, suggestKey{text: strings.Join(ws[j:], " "), entry: int32(i), leading: j == 0})
+		}
+	}
+	slices.SortFunc(snap.keys, func(a, b suggestKey) int { return strings.Compare(a.text, b.text) })
+
+	for _, k := range snap.keys {
+		for n, end := 0, 0; n < shortPrefix && end < len(k.text); n++ {
+			_, size := utf8.DecodeRuneInString(k.text[end:])
+			end += size
+			p := k.text[:end]
+			r := snap.short[p]
+			if r == nil {
+				r = &ranking{}
+				snap.short[p] = r
+			}
+			r.add(snap.entries, k.entry, k.leading, MaxSuggestions)
+		}
+	}
+	return snap
+}
+
+func (snap *suggestSnapshot) lookup(qs []string, limit int) Suggestions {
+	r := snap.short[qs[0]]
+	if len(qs) > 1 || utf8.RuneCountInString(qs[0]) > shortPrefix {
+		r = &ranking{}
+		i := sort.Search(len(snap.keys), func(i int) bool { return snap.keys[i].text >= qs[0] })
+		for end := min(i+maxScan, len(snap.keys)); i < end && strings.HasPrefix(snap.keys[i].text, qs[0]); i++ {
+			if k := snap.keys[i]; len(qs) == 1 || followedBy(k.text, qs[1:]) {
+				r.add(snap.entries, k.entry, k.leading, limit)
+			}
+		}
+	}
+
+	var lists [numKinds][]Suggestion
+	for kind := range lists {
+		lists[kind] = []Suggestion{}
+		if r == nil {
+			continue
+		}
+		for _, c := range r.best[kind][:min(limit, len(r.best[kind]))] {
+			e := snap.entries[c.entry]
+			lists[kind] = append(lists[kind], Suggestion{Text: e.text, URL: e.url})
+		}
+	}
+	return Suggestions{Titles: lists[kindTitle], Authors: lists[kindAuthor], Genres: lists[kindGenre]}
+}
+
+// followedBy reports whether each of qs starts a word of text after its
+// first, in order.
+func followedBy(text string, qs []string) bool {
+	ws := strings.Fields(text)[1:]
+	for _, q := range qs {
+		i := slices.IndexFunc(ws, func(w string) bool { return strings.HasPrefix(w, q) })
+		if i < 0 {
+			return false
+		}
+		ws = ws[i+1:]
+	}
+	return true
+}
+
+// ranking keeps the best few entries of each kind matching a query.
+type ranking struct {
+	best [numKinds][]candidate
+}
+
+type candidate struct {
+	entry   int32
+	leading bool
+}
+
+func (r *ranking) add(entries []suggestEntry, entry int32, leading bool, n int) {
+	kind := entries[entry].kind
+	list := r.best[kind]
+	if i := slices.IndexFunc(list, func(c candidate) bool { return c.entry == entry }); i >= 0 {
+		if !leading || list[i].leading {
+			return
+		}
+		list = slices.Delete(list, i, i+1) // reinserted as leading below
+	}
+	better := func(a, b candidate) int {
+		if a.leading != b.leading {
+			if a.leading {
+				return -1
+			}
+			return 1
+		}
+		ea, eb := entries[a.entry], entries[b.entry]
+		if c := cmp.Compare(eb.weight, ea.weight); c != 0 {
+			return c
+		}
+		return cmp.Compare(ea.text, eb.text)
+	}
+	c := candidate{entry: entry, leading: leading}
+	i, _ := slices.BinarySearchFunc(list, c, better)
+	if i < n {
+		list = slices.Insert(list, i, c)
+		list = list[:min(len(list), n)]
+	}
+	r.best[kind] = list
+}
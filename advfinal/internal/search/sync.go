@@ -0,0 +1,60 @@
+package search
+
+import (
+	"context"
+
+	"bookstore/internal/logging"
+	"bookstore/internal/models"
+	"bookstore/internal/repository"
+)
+
+// Synced wraps repo so that every book created, updated or deleted through
+// it is also written to each of indexes. The database stays the source of
+// truth: an index failure is logged rather than failing the write, and the
+// next Rebuild repairs it. Covers and ratings are not indexed.
+func Synced(repo repository.BookRepository, indexes ...Indexer) repository.BookRepository {
+	return &syncedBooks{BookRepository: repo, indexes: indexes}
+}
+
+type syncedBooks struct {
+	repository.BookRepository
+	indexes []Indexer
+}
+
+func (r *syncedBooks) Create(ctx context.Context, book models.Book) (models.Book, error) {
+	book, err := r.BookRepository.Create(ctx, book)
+	if err == nil {
+		r.index(ctx, book)
+	}
+	return book, err
+}
+
+func (r *syncedBooks) Update(ctx context.Context, book models.Book) error {
+	err := r.BookRepository.Update(ctx, book)
+	if err == nil {
+		r.index(ctx, book)
+	}
+	return err
+}
+
+func (r *syncedBooks) Delete(ctx context.Context, id int) error {
+	err := r.BookRepository.Delete(ctx, id)
+	if err == nil {
+		for _, idx := range r.indexes {
+			r.logged(ctx, id, idx.Delete(ctx, id))
+		}
+	}
+	return err
+}
+
+func (r *syncedBooks) index(ctx context.Context, book models.Book) {
+	for _, idx := range r.indexes {
+		r.logged(ctx, book.ID, idx.Index(ctx, book))
+	}
+}
+
+func (r *syncedBooks) logged(ctx context.Context, id int, err error) {
+	if err != nil {
+		logging.From(ctx).Error("search index out of date", "book", id, "err", err)
+	}
+}
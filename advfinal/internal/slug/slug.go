@@ -0,0 +1,102 @@
+// Package slug turns titles and names into URL path segments such as
+// "the-master-and-margarita-mikhail-bulgakov".
+package slug
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// maxLen bounds a slug in bytes, before any numeric suffix.
+const maxLen = 80
+
+// Make joins parts into a slug of lowercase letters, digits and single
+// hyphens. Latin letters lose their accents and Cyrillic is transliterated;
+// letters of other scripts are kept. A slug is never empty or all digits, so
+// it cannot be mistaken for an id.
+func Make(parts ...string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(strings.Join(parts, " ")) {
+		s, ok := fold[r]
+		switch {
+		case ok && s == "":
+			continue // hard and soft signs
+		case ok:
+		case r < unicode.MaxASCII && (r >= 'a' && r <= 'z' || r >= '0' && r <= '9'):
+			s = string(r)
+		case r >= unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			s = string(r)
+		case unicode.Is(unicode.Mn, r) || r == '\'' || r == '’':
+			continue // combining marks and apostrophes join their word
+		}
+		if s == "" {
+			dash = b.Len() > 0
+			continue
+		}
+		if dash {
+			b.WriteByte('-')
+			dash = false
+		}
+		b.WriteString(s)
+	}
+
+	out := b.String()
+	if len(out) > maxLen {
+		out = out[:maxLen]
+		if i := strings.LastIndexByte(out, '-'); i > maxLen/2 {
+			out = out[:i]
+		}
+		out = strings.ToValidUTF8(out, "")
+	}
+	if strings.Trim(out, "0123456789") == "" {
+		out = strings.TrimSuffix("book-"+out, "-")
+	}
+	return out
+}
+
+// Unique returns base, or base-2, base-3 and so on, whichever taken first
+// reports free.
+func Unique(base string, taken func(string) (bool, error)) (string, error) {
+	s := base
+	for n := 2; ; n++ {
+		used, err := taken(s)
+		if err != nil || !used {
+			return s, err
+		}
+		s = base + "-" + strconv.Itoa(n)
+	}
+}
+
+// Base strips the numeric suffix Unique may have added, or one that was
+// part of the text.
+func Base(s string) string {
+	i := strings.LastIndexByte(s, '-')
+	if i < 0 {
+		return s
+	}
+	if n, err := strconv.Atoi(s[i+1:]); err == nil && n >= 2 {
+		return s[:i]
+	}
+	return s
+}
+
+var fold = map[rune]string{
+	'à': "a", 'á': "a", 'â': "a", 'ã': "a", 'ä': "a", 'å': "a", 'ā': "a", 'ą': "a", 'ă': "a",
+	'æ': "ae", 'ç': "c", 'ć': "c", 'č': "c", 'ď': "d", 'đ': "d", 'ð': "d",
+	'è': "e", 'é': "e", 'ê': "e", 'ë': "e", 'ē': "e", 'ę': "e", 'ě': "e",
+	'ğ': "g", 'ì': "i", 'í': "i", 'î': "i", 'ï': "i", 'ī': "i", 'ı': "i",
+	'ł': "l", 'ľ': "l", 'ñ': "n", 'ń': "n", 'ň': "n",
+	'ò': "o", 'ó': "o", 'ô': "o", 'õ': "o", 'ö': "o", 'ø': "o", 'ō': "o", 'ő': "o", 'œ': "oe",
+	'ř': "r", 'ś': "s", 'š': "s", 'ş': "s", 'ß': "ss", 'ť': "t", 'ţ': "t", 'þ': "th",
+	'ù': "u", 'ú': "u", 'û': "u", 'ü': "u", 'ū': "u", 'ů': "u", 'ű': "u",
+	'ý': "y", 'ÿ': "y", 'ź': "z", 'ż': "z", 'ž': "z",
+
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e", 'ж': "zh",
+	'з': "z", 'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o",
+	'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts",
+	'ч': "ch", 'ш': "sh", 'щ': "shch", 'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu",
+	'я': "ya", 'і': "i", 'ї': "yi", 'є': "ye", 'ґ': "g", 'ә': "a", 'ғ': "g", 'қ': "k",
+	'ң': "n", 'ө': "o", 'ұ': "u", 'ү': "u", 'һ': "h",
+}
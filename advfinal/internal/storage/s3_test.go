@@ -0,0 +1,147 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// s3Stub is a minimal S3-compatible server in the spirit of a local MinIO:
+// it keeps objects in memory and rejects requests whose signature does not
+// check out.
+type s3Stub struct {
+	t       *testing.T
+	mu      sync.Mutex
+	objects map[string]stubObject
+}
+
+type stubObject struct {
+	data        []byte
+	contentType string
+}
+
+func (s *s3Stub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !s.signatureValid(r) {
+		http.Error(w, "<Error><Code>SignatureDoesNotMatch</Code></Error>", http.StatusForbidden)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch r.Method {
+	case http.MethodPut:
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.objects[r.URL.Path] = stubObject{data: data, contentType: r.Header.Get("Content-Type")}
+	case http.MethodGet:
+		obj, ok := s.objects[r.URL.Path]
+		if !ok {
+			http.Error(w, "<Error><Code>NoSuchKey</Code></Error>", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", obj.contentType)
+		w.Header().Set("Last-Modified", time.Now().UTC().Format(http.TimeFormat))
+		_, _ = w.Write(obj.data)
+	case http.MethodDelete:
+		delete(s.objects, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// signatureValid signs a copy of r with the stub's credentials and the
+// request's own timestamp and compares the result.
+func (s *s3Stub) signatureValid(r *http.Request) bool {
+	at, err := time.Parse("20060102T150405Z", r.Header.Get("X-Amz-Date"))
+	if err != nil {
+		return false
+	}
+	check, err := http.NewRequest(r.Method, "http://"+r.Host+r.URL.RequestURI(), nil)
+	if err != nil {
+		s.t.Error(err)
+		return false
+	}
+	for name, vals := range r.Header {
+		if strings.HasPrefix(strings.ToLower(name), "x-amz-") {
+			check.Header[name] = vals
+		}
+	}
+	sign(check, "us-east-1", "minio", "minio-secret", r.Header.Get("X-Amz-Content-Sha256"), at)
+	return check.Header.Get("Authorization") == r.Header.Get("Authorization")
+}
+
+func newS3Stub(t *testing.T) (*s3Stub, *S3Store) {
+	stub := &s3Stub{t: t, objects: map[string]stubObject{}}
+	srv := httptest.NewServer(stub)
+	t.Cleanup(srv.Close)
+
+	store := NewS3Store(S3Config{
+		Endpoint:  srv.URL + "/",
+		Region:    "us-east-1",
+		Bucket:    "covers",
+		AccessKey: "minio",
+		SecretKey: "minio-secret",
+	})
+	return stub, store
+}
+
+func TestS3Store(t *testing.T) {
+	stub, store := newS3Stub(t)
+	ctx := context.Background()
+	const key = "books/1/v1.jpg"
+
+	body := "jpeg bytes"
+	if err := store.Put(ctx, key, strings.NewReader(body), int64(len(body)), "image/jpeg"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if _, ok := stub.objects["/covers/"+key]; !ok {
+		t.Fatalf("Put did not store under the bucket path; have %v", stub.objects)
+	}
+
+	rc, info, err := store.Get(ctx, key)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	got, _ := io.ReadAll(rc)
+	rc.Close()
+	if string(got) != body {
+		t.Errorf("Get body = %q, want %q", got, body)
+	}
+	if info.ContentType != "image/jpeg" || info.Size != int64(len(body)) || info.ModTime.IsZero() {
+		t.Errorf("Get info = %+v", info)
+	}
+
+	if err := store.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, _, err := store.Get(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get after Delete: err = %v, want ErrNotFound", err)
+	}
+	if err := store.Delete(ctx, key); err != nil {
+		t.Errorf("Delete of a missing key: %v", err)
+	}
+}
+
+func TestS3StoreRejectedSignature(t *testing.T) {
+	_, store := newS3Stub(t)
+	store.cfg.SecretKey = "wrong"
+
+	err := store.Put(context.Background(), "a.jpg", strings.NewReader("x"), 1, "image/jpeg")
+	if err == nil || errors.Is(err, ErrNotFound) {
+		t.Fatalf("Put with a bad secret: err = %v, want a failure", err)
+	}
+	if !strings.Contains(err.Error(), "403") {
+		t.Errorf("error %q does not carry the status", err)
+	}
+}
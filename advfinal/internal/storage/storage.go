@@ -0,0 +1,42 @@
+// Package storage keeps binary objects, such as cover images, outside the
+// database. Keys are slash-separated paths like "covers/12/3f9a/thumb.jpg".
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+var ErrNotFound = errors.New("blob not found")
+
+type BlobStore interface {
+	// Put stores size bytes from r under key, replacing any object there.
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get returns ErrNotFound for a missing key. The caller closes the body.
+	Get(ctx context.Context, key string) (io.ReadCloser, Info, error)
+	// Delete succeeds for a missing key.
+	Delete(ctx context.Context, key string) error
+}
+
+type Info struct {
+	Size        int64
+	ContentType string
+	ModTime     time.Time
+}
+
+// checkKey rejects keys that could escape a store's root.
+func checkKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.HasSuffix(key, "/") {
+		return fmt.Errorf("invalid blob key %q", key)
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." || strings.ContainsAny(part, `\`+"\x00") {
+			return fmt.Errorf("invalid blob key %q", key)
+		}
+	}
+	return nil
+}
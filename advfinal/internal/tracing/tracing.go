@@ -0,0 +1,122 @@
+// Package tracing wires OpenTelemetry into the service. Handlers get spans
+// from the otelhttp middleware, services and repositories open child spans
+// with Start, and background jobs carry the trace context across the queue
+// with Inject and Extract.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	instrumentationName = "bookstore"
+	defaultServiceName  = "bookstore"
+)
+
+// Setup installs the global tracer provider and W3C propagators. The exporter
+// follows the standard OpenTelemetry variables:
+//
+//	OTEL_TRACES_EXPORTER        otlp or none; defaults to otlp when an
+//	                            OTLP endpoint is configured, none otherwise
+//	OTEL_EXPORTER_OTLP_ENDPOINT collector base URL (OTLP over HTTP)
+//	OTEL_SERVICE_NAME           defaults to "bookstore"
+//
+// With no exporter spans are still created, so trace ids reach the logs.
+// The returned function flushes pending spans and must be called on exit.
+func Setup(ctx context.Context) (func(context.Context) error, error) {
+	var exp sdktrace.SpanExporter
+
+	switch exporterName() {
+	case "otlp":
+		e, err := otlptracehttp.New(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("otlp exporter: %w", err)
+		}
+		exp = e
+	case "none":
+	default:
+		return nil, fmt.Errorf("unsupported OTEL_TRACES_EXPORTER %q", os.Getenv("OTEL_TRACES_EXPORTER"))
+	}
+
+	tp := NewProvider(exp)
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{}, propagation.Baggage{},
+	))
+	return tp.Shutdown, nil
+}
+
+// NewProvider builds a tracer provider around exp, which may be nil. Tests
+// pass an in-memory exporter (go.opentelemetry.io/otel/sdk/trace/tracetest)
+// and install the result with otel.SetTracerProvider.
+func NewProvider(exp sdktrace.SpanExporter) *sdktrace.TracerProvider {
+	name := os.Getenv("OTEL_SERVICE_NAME")
+	if name == "" {
+		name = defaultServiceName
+	}
+
+	opts := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(name))),
+	}
+	if exp != nil {
+		opts = append(opts, sdktrace.WithBatcher(exp))
+	}
+	return sdktrace.NewTracerProvider(opts...)
+}
+
+func exporterName() string {
+	if v := os.Getenv("OTEL_TRACES_EXPORTER"); v != "" {
+		return v
+	}
+	if os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" || os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") != "" {
+		return "otlp"
+	}
+	return "none"
+}
+
+// Start opens a child span of whatever span ctx carries.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End records err on the span, if any, and ends it.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Inject serialises the trace context of ctx so it can travel with a job.
+func Inject(ctx context.Context) map[string]string {
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	return carrier
+}
+
+// Extract restores a trace context produced by Inject.
+func Extract(ctx context.Context, carrier map[string]string) context.Context {
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(carrier))
+}
+
+// TraceID returns the id of the span in ctx, or "" when there is none.
+func TraceID(ctx context.Context) string {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.HasTraceID() {
+		return ""
+	}
+	return sc.TraceID().String()
+}
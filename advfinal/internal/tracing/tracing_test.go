@@ -0,0 +1,121 @@
+package tracing_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"bookstore/internal/handlers"
+	"bookstore/internal/logic"
+	"bookstore/internal/middleware"
+	"bookstore/internal/models"
+	"bookstore/internal/repository"
+	"bookstore/internal/tracing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+const secret = "test-secret"
+
+type fakeBooks struct {
+	repository.BookRepository
+}
+
+func (fakeBooks) GetByID(_ context.Context, id int) (models.Book, error) {
+	return models.Book{ID: id, Title: "Book", Price: 10}, nil
+}
+
+type fakeOrders struct {
+	repository.OrderRepository
+}
+
+func (fakeOrders) Create(_ context.Context, o models.Order, items []models.OrderItem) (models.Order, []models.OrderItem, error) {
+	o.ID = 1
+	return o, items, nil
+}
+
+// TestOrderTrace follows an order from the HTTP request through the
+// service and repositories into the worker that clears the cart, and checks
+// that every span lands in the same trace.
+func TestOrderTrace(t *testing.T) {
+	exp := tracetest.NewInMemoryExporter()
+	tp := tracing.NewProvider(exp)
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { _ = tp.Shutdown(context.Background()) })
+
+	carts := repository.NewCartRepo()
+	cart := carts.Create(context.Background(), 7)
+	if _, err := carts.AddItem(context.Background(), cart.ID, 3, 2); err != nil {
+		t.Fatal(err)
+	}
+	logic.StartOrderWorkerPool(1, 10, carts, nil, nil)
+
+	orders := handlers.NewOrderHandler(logic.NewOrderService(fakeOrders{}, fakeBooks{}, carts))
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /orders", middleware.AuthOnly(secret, nil, orders.Orders))
+	server := middleware.Tracing(middleware.TraceRoute(mux))
+
+	tok, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"userId": 7, "role": "user"}).
+		SignedString([]byte(secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(`{"cartId":`+strconv.Itoa(cart.ID)+`}`))
+	req.Header.Set("Authorization", "Bearer "+tok)
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, req)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d; body: %s", rec.Code, rec.Body)
+	}
+
+	want := []string{
+		"http.request",
+		"OrderService.CreateOrderFromCart",
+		"CartRepo.GetByID",
+		"OrderJob CLEAR_CART",
+		"CartRepo.ClearCart",
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		_ = tp.ForceFlush(context.Background())
+		spans := map[string]tracetest.SpanStub{}
+		for _, s := range exp.GetSpans() {
+			spans[s.Name] = s
+		}
+
+		missing := ""
+		for _, name := range want {
+			if _, ok := spans[name]; !ok {
+				missing = name
+				break
+			}
+		}
+		if missing != "" {
+			if time.Now().After(deadline) {
+				t.Fatalf("span %q was not recorded; got %v", missing, exp.GetSpans().Snapshots())
+			}
+			time.Sleep(10 * time.Millisecond)
+			continue
+		}
+
+		traceID := spans[want[0]].SpanContext.TraceID()
+		for _, name := range want[1:] {
+			if got := spans[name].SpanContext.TraceID(); got != traceID {
+				t.Errorf("%s is in trace %s, want %s", name, got, traceID)
+			}
+		}
+		if p := spans["CartRepo.ClearCart"].Parent.SpanID(); p != spans["OrderJob CLEAR_CART"].SpanContext.SpanID() {
+			t.Errorf("CartRepo.ClearCart is not a child of the job span")
+		}
+		return
+	}
+}
@@ -0,0 +1,198 @@
+// Package validate checks structs against rules declared in `validate` tags:
+//
+//	Email string `json:"email" validate:"required,email,max=254"`
+//
+// Supported rules are required, email, password, phone, isbn, min=N and max=N.
+// min and max compare the value of numbers and the rune count of strings.
+// Fields are reported under their json name.
+package validate
+
+import (
+	"fmt"
+	"net/mail"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"bookstore/internal/isbn"
+)
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Password policy shared by registration and password changes. The upper
+// bound is bcrypt's input limit.
+const (
+	PasswordMin = 8
+	PasswordMax = 72
+)
+
+// Struct validates every tagged field of v (a struct or pointer to one) and
+// returns one error per failing field, in declaration order.
+func Struct(v any) []FieldError {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var out []FieldError
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		f := rt.Field(i)
+		tag := f.Tag.Get("validate")
+		if tag == "" || !f.IsExported() {
+			continue
+		}
+
+		name := fieldName(f)
+		if msg := check(rv.Field(i), strings.Split(tag, ",")); msg != "" {
+			out = append(out, FieldError{Field: name, Message: name + " " + msg})
+		}
+	}
+	return out
+}
+
+// check returns the message of the first failing rule, or "".
+func check(v reflect.Value, rules []string) string {
+	for _, rule := range rules {
+		name, arg, _ := strings.Cut(rule, "=")
+
+		switch name {
+		case "required":
+			if v.IsZero() || (v.Kind() == reflect.String && strings.TrimSpace(v.String()) == "") {
+				return "is required"
+			}
+		case "email":
+			if s := v.String(); s != "" && !isEmail(s) {
+				return "must be a valid email address"
+			}
+		case "password":
+			if msg := checkPassword(v.String()); msg != "" {
+				return msg
+			}
+		case "isbn":
+			if s := v.String(); s != "" && !isbn.Valid(s) {
+				return "must be a valid ISBN-10 or ISBN-13"
+			}
+		case "phone":
+			if s := v.String(); s != "" && !isPhone(s) {
+				return "must be a valid phone number"
+			}
+		case "min", "max":
+			n, err := strconv.ParseFloat(arg, 64)
+			if err != nil {
+				panic(fmt.Sprintf("validate: bad %s rule %q", name, rule))
+			}
+			if msg := checkBound(v, name, n); msg != "" {
+				return msg
+			}
+		default:
+			panic(fmt.Sprintf("validate: unknown rule %q", rule))
+		}
+	}
+	return ""
+}
+
+func checkBound(v reflect.Value, rule string, n float64) string {
+	var got float64
+	unit := ""
+	switch v.Kind() {
+	case reflect.String:
+		got = float64(utf8.RuneCountInString(v.String()))
+		unit = " characters"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		got = float64(v.Int())
+	case reflect.Float32, reflect.Float64:
+		got = v.Float()
+	default:
+		return ""
+	}
+
+	bound := strconv.FormatFloat(n, 'f', -1, 64)
+	if rule == "min" && got < n {
+		if unit != "" {
+			return "must be at least " + bound + unit
+		}
+		return "must be at least " + bound
+	}
+	if rule == "max" && got > n {
+		if unit != "" {
+			return "must be at most " + bound + unit
+		}
+		return "must be at most " + bound
+	}
+	return ""
+}
+
+func isEmail(s string) bool {
+	addr, err := mail.ParseAddress(s)
+	if err != nil || addr.Address != s {
+		return false
+	}
+	_, domain, _ := strings.Cut(s, "@")
+	return strings.Contains(domain, ".")
+}
+
+func isPhone(s string) bool {
+	digits := 0
+	for i, r := range s {
+		switch {
+		case unicode.IsDigit(r):
+			digits++
+		case r == '+' && i == 0, r == ' ', r == '-', r == '(', r == ')':
+		default:
+			return false
+		}
+	}
+	return digits >= 7 && digits <= 15
+}
+
+func checkPassword(s string) string {
+	n := utf8.RuneCountInString(s)
+	if n < PasswordMin {
+		return fmt.Sprintf("must be at least %d characters", PasswordMin)
+	}
+	if len(s) > PasswordMax {
+		return fmt.Sprintf("must be at most %d bytes", PasswordMax)
+	}
+
+	var letter, digit bool
+	for _, r := range s {
+		letter = letter || unicode.IsLetter(r)
+		digit = digit || unicode.IsDigit(r)
+	}
+	if !letter || !digit {
+		return "must contain a letter and a digit"
+	}
+	return ""
+}
+
+func fieldName(f reflect.StructField) string {
+	if tag := f.Tag.Get("json"); tag != "" {
+		if name, _, _ := strings.Cut(tag, ","); name != "" && name != "-" {
+			return name
+		}
+	}
+	return lowerCamel(f.Name)
+}
+
+// lowerCamel lowercases the leading capital, or the whole leading initialism:
+// Title -> title, ISBN -> isbn, URLPath -> urlPath.
+func lowerCamel(name string) string {
+	runes := []rune(name)
+	n := 0
+	for n < len(runes) && unicode.IsUpper(runes[n]) {
+		n++
+	}
+	if n > 1 && n < len(runes) {
+		n-- // the last capital starts the next word
+	}
+	for i := 0; i < n; i++ {
+		runes[i] = unicode.ToLower(runes[i])
+	}
+	return string(runes)
+}
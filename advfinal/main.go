@@ -1,23 +1,63 @@
 package main
 
 import (
-	"log"
+	"context"
+	"errors"
+	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
 
+	"bookstore/internal/config"
 	"bookstore/internal/db"
-
-	"github.com/joho/godotenv"
+	"bookstore/internal/health"
+	"bookstore/internal/logging"
+	"bookstore/internal/middleware"
+	"bookstore/internal/tracing"
 )
 
 func main() {
-	_ = godotenv.Load()
+	cfg, err := config.Load()
+	if err != nil {
+		fatal("config error", err)
+	}
+	logging.Setup(cfg.Log.Format, cfg.Log.Level)
 
-	client, mongoDB, err := db.Connect()
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		// Migrations are run by the bookstore CLI; refuse rather than start
+		// the server on a deploy script that still calls the old subcommand.
+		fatal("migrate is a bookstore CLI command", errors.New("run: go run ./cmd/bookstore migrate up | down [steps] | status"))
+	}
+
+	shutdownTracing, err := tracing.Setup(context.Background())
 	if err != nil {
-		log.Fatal(err)
+		fatal("tracing setup failed", err)
 	}
-	defer client.Disconnect(db.Bg())
+
+	client, mongoDB, err := db.Connect(cfg.Mongo)
+	if err != nil {
+		fatal("mongo connect failed", err)
+	}
+
+	// An unreachable Mongo is not fatal: the server starts unready and
+	// /readyz reports it until the connection comes up.
+	pingCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	if err := db.Ping(pingCtx, client); err != nil {
+		slog.Warn("mongo not reachable", "err", err)
+	}
+	cancel()
+
+	if cfg.Mongo.MigrateOnStart {
+		if err := migrateOnStart(context.Background(), mongoDB); err != nil {
+			_ = shutdownTracing(context.Background())
+			fatal("migrations failed", err)
+		}
+	}
+
+	checker := health.NewChecker(cfg.Server.HealthCheckTimeout)
 
 	mux := http.NewServeMux()
 
@@ -27,13 +67,62 @@ func main() {
 		),
 	)
 
-	RegisterRoutes(mux, mongoDB)
+	closeRoutes := RegisterRoutes(mux, cfg, mongoDB, checker)
 
-	addr := ":8080"
-	if p := os.Getenv("PORT"); p != "" {
-		addr = ":" + p
+	addr := ":" + strconv.Itoa(cfg.Server.Port)
+
+	// Outermost first. AccessLog, Metrics and TraceRoute read r.Pattern after
+	// the mux has served, so nothing between them and the mux may copy r.
+	handler := middleware.Tracing(
+		middleware.RealIP(cfg.Server.Proxies(),
+			middleware.RequestID(
+				middleware.AccessLog(
+					middleware.Metrics(
+						middleware.TraceRoute(mux),
+					),
+				),
+			),
+		),
+	)
+
+	srv := &http.Server{Addr: addr, Handler: handler}
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- srv.ListenAndServe() }()
+	slog.Info("server started", "addr", "http://localhost"+addr)
+
+	sigCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	select {
+	case err := <-serveErr:
+		_ = shutdownTracing(context.Background()) // flush spans; fatal skips defers
+		fatal("server stopped", err)
+	case <-sigCtx.Done():
+	}
+	stop() // a second signal kills the process
+
+	// Fail readiness first and give load balancers time to notice before the
+	// listener closes.
+	checker.Drain()
+	slog.Info("shutting down", "drain_delay", cfg.Server.DrainDelay.String())
+	time.Sleep(cfg.Server.DrainDelay)
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		slog.Error("http shutdown failed", "err", err)
+	}
+	if err := closeRoutes(); err != nil {
+		slog.Error("search index close failed", "err", err)
 	}
+	if err := client.Disconnect(ctx); err != nil {
+		slog.Error("mongo disconnect failed", "err", err)
+	}
+	if err := shutdownTracing(ctx); err != nil {
+		slog.Error("tracing shutdown failed", "err", err)
+	}
+	slog.Info("server stopped")
+}
 
-	log.Println("Server started at http://localhost" + addr)
-	log.Fatal(http.ListenAndServe(addr, mux))
+func fatal(msg string, err error) {
+	slog.Error(msg, "err", err)
+	os.Exit(1)
 }
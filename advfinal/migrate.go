@@ -0,0 +1,21 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+
+	"bookstore/internal/migrate"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// migrateOnStart applies pending migrations before the server listens. The
+// migrate up, down and status commands live in the bookstore CLI
+// (cmd/bookstore).
+func migrateOnStart(ctx context.Context, mongoDB *mongo.Database) error {
+	ran, err := migrate.NewMigrator(mongoDB, migrate.All).Up(ctx)
+	for _, mg := range ran {
+		slog.Info("migration applied", "version", mg.Version, "description", mg.Description)
+	}
+	return err
+}
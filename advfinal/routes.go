@@ -1,50 +1,96 @@
 package main
 
 import (
-	"log"
+	"context"
 	"net/http"
-	"os"
-	"strings"
+	"strconv"
 
+	"bookstore/internal/api"
+	"bookstore/internal/config"
+	"bookstore/internal/db"
 	"bookstore/internal/handlers"
+	"bookstore/internal/health"
 	"bookstore/internal/logic"
-	"bookstore/internal/middleware"
+	"bookstore/internal/metrics"
+	"bookstore/internal/models"
 	"bookstore/internal/repository"
+	"bookstore/internal/search"
+	"bookstore/internal/storage"
 
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
-func RegisterRoutes(mux *http.ServeMux, mongoDB *mongo.Database) {
-	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
-		log.Fatal("JWT_SECRET is not set")
-	}
+// RegisterRoutes wires the services and mounts every route. The returned
+// function closes what it opened, once the server has stopped.
+func RegisterRoutes(mux *http.ServeMux, cfg config.Config, mongoDB *mongo.Database, checker *health.Checker) func() error {
+	secret := cfg.Auth.JWTSecret
 
 	// ---------------- Repositories ----------------
-	bookRepo := repository.NewBookRepo(mongoDB)
-	userRepo := repository.NewUserRepo(mongoDB)
+	timeouts := repository.Timeouts{Read: cfg.Mongo.ReadTimeout, Write: cfg.Mongo.WriteTimeout}
+	searchIndex, emptyIndex := openSearchIndex(cfg.Search, mongoDB, timeouts)
+	suggester := search.NewSuggester()
+	bookRepo := search.Synced(repository.NewBookRepo(mongoDB, timeouts), searchIndex, suggester)
+	userRepo := repository.NewUserRepo(mongoDB, timeouts)
 	cartRepo := repository.NewCartRepo() // in-memory
-	wishlistRepo := repository.NewWishlistRepo(mongoDB)
-	orderRepo := repository.NewOrderRepo(mongoDB)
+	wishlistRepo := repository.NewWishlistRepo(mongoDB, timeouts)
+	orderRepo := repository.NewOrderRepo(mongoDB, timeouts)
+	sessionRepo := repository.NewSessionRepo(mongoDB, timeouts)
+	failedJobRepo := repository.NewFailedJobRepo(mongoDB, timeouts)
+	reviewRepo := repository.NewReviewRepo(mongoDB, timeouts)
+	recRepo := repository.NewRecommendationRepo(mongoDB, timeouts)
+	entities := repository.NewEntities(mongoDB, timeouts)
+	categoryRepo := repository.NewCategoryRepo(mongoDB, timeouts)
+	blobs := blobStore(cfg.Storage)
 
 	// ---------------- Workers ----------------
-	logic.StartOrderWorkerPool(2, cartRepo, wishlistRepo)
+	logic.StartOrderWorkerPool(cfg.Workers.Order, cfg.Workers.QueueSize, cartRepo, wishlistRepo, failedJobRepo)
+	logic.StartCartWorkerPool(cfg.Workers.Cart, cfg.Workers.CartQueueSize)
+	metrics.RegisterQueue("order", func() int { return len(logic.OrderJobQueue) })
+	metrics.RegisterQueue("cart", func() int { return len(logic.CartJobQueue) })
 
 	// ---------------- Services ----------------
-	bookService := logic.NewBookService(bookRepo)
-	authService := logic.NewAuthService(userRepo, secret)
+	entityService := logic.NewEntityService(entities, bookRepo)
+	categoryService := logic.NewCategoryService(categoryRepo, bookRepo)
+	bookService := logic.NewBookService(bookRepo, entityService, categoryService, cfg.Catalog.Currency)
+	importJobs := logic.NewImportJobs(bookService)
+	coverService := logic.NewCoverService(bookRepo, blobs)
+	if cfg.Catalog.ImportDir != "" {
+		go importJobs.Watch(context.Background(), cfg.Catalog.ImportDir, cfg.Catalog.ImportInterval)
+	}
+	authService := logic.NewAuthService(userRepo, sessionRepo, secret, cfg.Auth.TokenTTL)
+	accountService := logic.NewAccountService(userRepo, sessionRepo, orderRepo)
+	privacyService := logic.NewPrivacyService(userRepo, sessionRepo, cartRepo, orderRepo, wishlistRepo, reviewRepo)
 	cartCRUDService := logic.NewCartCRUDService(cartRepo, bookRepo)
 	orderSvc := logic.NewOrderService(orderRepo, bookRepo, cartRepo)
 	orderCRUD := logic.NewOrderCRUDService(orderRepo)
 	wishlistService := logic.NewWishlistService(wishlistRepo, bookRepo, orderRepo)
+	reviewService := logic.NewReviewService(reviewRepo, bookRepo, orderRepo)
+	recService := logic.NewRecommendationService(recRepo, bookRepo, orderRepo, wishlistRepo)
+	if cfg.Catalog.RecommendInterval > 0 {
+		go recService.Run(context.Background(), cfg.Catalog.RecommendInterval)
+	}
+	searchService := logic.NewSearchService(searchIndex, suggester, bookRepo)
+	go searchService.Warm(context.Background(), emptyIndex)
 
 	// ---------------- API Handlers ----------------
 	bookHandler := handlers.NewBookHandler(bookService)
+	bookTransferHandler := handlers.NewBookTransferHandler(bookService, importJobs)
+	coverHandler := handlers.NewCoverHandler(coverService)
 	cartHandler := handlers.NewCartHandler(cartCRUDService)
 	orderHandler := handlers.NewOrderHandler(orderSvc)
 	orderCRUDHandler := handlers.NewOrderCRUDHandler(orderCRUD)
 	wishlistHandler := handlers.NewWishlistHandler(wishlistService)
 	authHandler := handlers.NewAuthHandler(authService)
+	accountHandler := handlers.NewAccountHandler(accountService)
+	privacyHandler := handlers.NewPrivacyHandler(privacyService)
+	reviewHandler := handlers.NewReviewHandler(reviewService)
+	recHandler := handlers.NewRecommendationHandler(recService)
+	searchHandler := handlers.NewSearchHandler(searchService)
+	authorHandler := handlers.NewEntityHandler(entityService, models.EntityAuthor)
+	publisherHandler := handlers.NewEntityHandler(entityService, models.EntityPublisher)
+	seriesHandler := handlers.NewEntityHandler(entityService, models.EntitySeries)
+	genreHandler := handlers.NewEntityHandler(entityService, models.EntityGenre)
+	categoryHandler := handlers.NewCategoryHandler(categoryService, bookService)
 
 	// ---------------- Frontend ----------------
 	frontend, err := handlers.NewFrontendHandler(
@@ -54,105 +100,163 @@ func RegisterRoutes(mux *http.ServeMux, mongoDB *mongo.Database) {
 		orderSvc,
 		orderCRUD,
 		wishlistService,
+		accountService,
+		privacyService,
+		reviewService,
+		recService,
+		searchService,
+		entityService,
+		categoryService,
 		secret,
+		cfg.Auth.TokenTTL,
+		cfg.Server.PublicURL,
 	)
 	if err != nil {
-		log.Fatal(err)
+		fatal("frontend templates", err)
 	}
 
 	// ================= FRONTEND PAGES =================
-	mux.HandleFunc("GET /", frontend.Home)
-	mux.HandleFunc("GET /catalog", frontend.Catalog)
-	mux.HandleFunc("GET /about", frontend.About)
+	page := func(pattern string, h http.HandlerFunc) {
+		mux.HandleFunc(pattern, frontend.Session(h))
+	}
 
-	mux.HandleFunc("GET /login", frontend.Login)
-	mux.HandleFunc("POST /login", frontend.LoginPost)
+	page("GET /", frontend.Home)
+	page("GET /catalog", frontend.Catalog)
+	page("GET /catalog/{id}", frontend.BookByID)
+	mux.HandleFunc("GET /covers/{id}/{version}/{file}", coverHandler.Serve)
 
-	mux.HandleFunc("GET /register", frontend.Register)
-	mux.HandleFunc("POST /register", frontend.RegisterPost)
+	// Book pages took over the pre-v1 JSON path; numeric ids still get the
+	// deprecated JSON, as slugs are never all digits.
+	legacyBook := api.Deprecated(api.Prefix+"/books/{id}", bookHandler.BookByID)
+	page("GET /books/{slug}", func(w http.ResponseWriter, r *http.Request) {
+		if _, err := strconv.Atoi(r.PathValue("slug")); err == nil {
+			r.SetPathValue("id", r.PathValue("slug"))
+			legacyBook(w, r)
+			return
+		}
+		frontend.Book(w, r)
+	})
+	page("POST /catalog/{id}/review", frontend.ReviewPost)
+	page("POST /catalog/{id}/review/delete", frontend.ReviewDelete)
+	page("POST /catalog/{id}/reviews/{reviewId}/helpful", frontend.ReviewHelpful)
+	page("GET /about", frontend.About)
 
-	mux.HandleFunc("POST /logout", frontend.Logout)
+	// Authors, publishers, series and genres
+	for _, kind := range models.EntityKinds {
+		page("GET /"+kind, frontend.Entities(kind))
+		page("GET /"+kind+"/{slug}", frontend.Entity(kind))
+	}
 
-	// Cart
-	mux.HandleFunc("GET /cart", frontend.CartPage)
-	mux.HandleFunc("POST /cart/add/{bookId}", frontend.CartAdd)
-	mux.HandleFunc("POST /cart/item/{itemId}/update", frontend.CartUpdateQty)
-	mux.HandleFunc("POST /cart/item/{itemId}/delete", frontend.CartDeleteItem)
+	// Category tree and tags
+	page("GET /categories", frontend.Categories)
+	page("GET /categories/{slug}", frontend.Category)
+	page("GET /tags", frontend.Tags)
+	page("GET /tags/{tag}", frontend.Tag)
 
-	// Orders
-	mux.HandleFunc("GET /orders", frontend.OrdersPage)
-	mux.HandleFunc("GET /orders/{id}", frontend.OrderDetailsPage)
-	mux.HandleFunc("POST /orders/create", frontend.CreateOrderFromCart)
+	page("GET /login", frontend.Login)
+	page("POST /login", frontend.LoginPost)
 
-	// Wishlists
-	mux.HandleFunc("GET /wishlists", frontend.WishlistsPage)
-	mux.HandleFunc("POST /wishlists/add/{bookId}", frontend.WishlistAdd)
-	mux.HandleFunc("POST /wishlists/gift/{wishlistId}", frontend.WishlistGift)
+	page("GET /register", frontend.Register)
+	page("POST /register", frontend.RegisterPost)
 
-	// ================= HEALTH =================
-	mux.HandleFunc("GET /health", handlers.Health)
+	page("POST /logout", frontend.Logout)
 
-	// ================= AUTH API =================
-	mux.HandleFunc("POST /auth/register", authHandler.Register)
-	mux.HandleFunc("POST /auth/login", authHandler.Login)
+	// Cart
+	page("GET /cart", frontend.CartPage)
+	page("POST /cart/add/{bookId}", frontend.CartAdd)
+	page("POST /cart/item/{itemId}/update", frontend.CartUpdateQty)
+	page("POST /cart/item/{itemId}/delete", frontend.CartDeleteItem)
 
-	// ================= BOOKS API =================
-	mux.HandleFunc("GET /books", bookHandler.Books)
-	mux.HandleFunc("POST /books", middleware.AdminOnly(secret, bookHandler.Books))
+	// Orders
+	page("GET /orders", frontend.OrdersPage)
+	page("GET /orders/{id}", frontend.OrderDetailsPage)
+	page("POST /orders/create", frontend.CreateOrderFromCart)
 
-	mux.HandleFunc("GET /books/{id}", bookHandler.BookByID)
-	mux.HandleFunc("PUT /books/{id}", middleware.AdminOnly(secret, bookHandler.BookByID))
-	mux.HandleFunc("DELETE /books/{id}", middleware.AdminOnly(secret, bookHandler.BookByID))
+	// Wishlists
+	page("GET /wishlists", frontend.WishlistsPage)
+	page("POST /wishlists/add/{bookId}", frontend.WishlistAdd)
+	page("POST /wishlists/gift/{wishlistId}", frontend.WishlistGift)
 
-	// ================= CARTS API =================
-	mux.HandleFunc("GET /carts", middleware.AuthOnly(secret, cartHandler.Carts))
-	mux.HandleFunc("POST /carts", middleware.AuthOnly(secret, cartHandler.Carts))
+	// Account
+	page("GET /account", frontend.AccountPage)
+	page("POST /account/profile", frontend.AccountProfilePost)
+	page("GET /account/security", frontend.AccountSecurityPage)
+	page("POST /account/password", frontend.AccountPasswordPost)
+	page("POST /account/email", frontend.AccountEmailPost)
+	page("POST /account/sessions/{id}/revoke", frontend.AccountSessionRevoke)
+	page("GET /account/addresses", frontend.AccountAddressesPage)
+	page("POST /account/addresses", frontend.AccountAddressAdd)
+	page("POST /account/addresses/{id}/delete", frontend.AccountAddressDelete)
+	page("POST /account/addresses/{id}/default", frontend.AccountAddressDefault)
+	page("GET /account/privacy", frontend.AccountPrivacyPage)
+	page("GET /account/export", frontend.AccountExport)
+	page("POST /account/delete", frontend.AccountDeletePost)
 
-	cartsPrefixHandler := middleware.AuthOnly(secret, func(w http.ResponseWriter, r *http.Request) {
-		if strings.Contains(r.URL.Path, "/items/") {
-			cartHandler.CartItemByID(w, r)
-			return
-		}
-		if strings.HasSuffix(r.URL.Path, "/items") {
-			cartHandler.CartItems(w, r)
-			return
-		}
-		cartHandler.CartByID(w, r)
+	// ================= HEALTH & METRICS =================
+	checker.Add("mongo", func(ctx context.Context) (any, error) {
+		return nil, db.Ping(ctx, mongoDB.Client())
 	})
+	checker.Add("orderWorkers", logic.CheckOrderWorkers)
 
-	mux.HandleFunc("GET /carts/", cartsPrefixHandler)
-	mux.HandleFunc("POST /carts/", cartsPrefixHandler)
-	mux.HandleFunc("PUT /carts/", cartsPrefixHandler)
-	mux.HandleFunc("DELETE /carts/", cartsPrefixHandler)
+	healthHandler := handlers.NewHealthHandler(checker)
+	mux.HandleFunc("GET /healthz", healthHandler.Live)
+	mux.HandleFunc("GET /readyz", healthHandler.Ready)
+	mux.HandleFunc("GET /health", healthHandler.Live) // pre-probe alias
+	// Set METRICS_TOKEN to require "Authorization: Bearer <token>" on scrapes.
+	mux.Handle("GET /metrics", metrics.Handler(cfg.Metrics.Token))
 
-	// ================= ORDERS API =================
-	mux.HandleFunc("POST /orders_api", middleware.AuthOnly(secret, orderHandler.Orders))
-	mux.HandleFunc("GET /orders_api", middleware.AuthOnly(secret, orderCRUDHandler.Orders))
+	// ================= JSON API =================
+	// Every endpoint lives under /api/v1; the pre-v1 paths stay mounted as
+	// deprecated aliases so existing clients keep working.
+	routes := apiRoutes(apiHandlers{
+		auth:      authHandler,
+		account:   accountHandler,
+		privacy:   privacyHandler,
+		books:     bookHandler,
+		transfer:  bookTransferHandler,
+		covers:    coverHandler,
+		carts:     cartHandler,
+		orders:    orderHandler,
+		orderCRUD: orderCRUDHandler,
+		wishlists: wishlistHandler,
+		reviews:   reviewHandler,
+		recs:      recHandler,
+		search:    searchHandler,
 
-	ordersByID := middleware.AuthOnly(secret, orderCRUDHandler.OrderByID)
-	mux.HandleFunc("GET /orders_api/", ordersByID)
-	mux.HandleFunc("POST /orders_api/", ordersByID)
-	mux.HandleFunc("PUT /orders_api/", ordersByID)
-	mux.HandleFunc("DELETE /orders_api/", ordersByID)
+		authors:    authorHandler,
+		publishers: publisherHandler,
+		series:     seriesHandler,
+		genres:     genreHandler,
+		categories: categoryHandler,
+	})
+	api.Mount(mux, secret, authService.SessionActive, routes)
+	mux.HandleFunc("GET "+api.Prefix+"/openapi.json", api.SpecHandler("Online Bookstore API", "1.0.0", routes))
 
-	// ================= WISHLISTS API =================
-	mux.HandleFunc("GET /wishlists_api", middleware.AuthOnly(secret, wishlistHandler.Wishlists))
-	mux.HandleFunc("POST /wishlists_api", middleware.AuthOnly(secret, wishlistHandler.Wishlists))
+	return searchIndex.Close
+}
 
-	wishlistsPrefixHandler := middleware.AuthOnly(secret, func(w http.ResponseWriter, r *http.Request) {
-		if strings.HasSuffix(r.URL.Path, "/items") {
-			wishlistHandler.WishlistItems(w, r)
-			return
-		}
-		if strings.HasSuffix(r.URL.Path, "/gift") {
-			wishlistHandler.Gift(w, r)
-			return
-		}
-		wishlistHandler.WishlistByID(w, r)
-	})
+// openSearchIndex also reports whether the index is empty and needs
+// building.
+func openSearchIndex(cfg config.Search, mongoDB *mongo.Database, t repository.Timeouts) (search.SearchIndex, bool) {
+	if cfg.Backend == "mongo" {
+		return search.NewMongoIndex(mongoDB, t), false
+	}
+	idx, empty, err := search.OpenBleve(cfg.Dir)
+	if err != nil {
+		fatal("search index", err)
+	}
+	return idx, empty
+}
 
-	mux.HandleFunc("GET /wishlists_api/", wishlistsPrefixHandler)
-	mux.HandleFunc("POST /wishlists_api/", wishlistsPrefixHandler)
-	mux.HandleFunc("PUT /wishlists_api/", wishlistsPrefixHandler)
-	mux.HandleFunc("DELETE /wishlists_api/", wishlistsPrefixHandler)
+func blobStore(cfg config.Storage) storage.BlobStore {
+	if cfg.Driver == "s3" {
+		return storage.NewS3Store(storage.S3Config{
+			Endpoint:  cfg.S3.Endpoint,
+			Region:    cfg.S3.Region,
+			Bucket:    cfg.S3.Bucket,
+			AccessKey: cfg.S3.AccessKey,
+			SecretKey: cfg.S3.SecretKey,
+		})
+	}
+	return storage.NewLocalStore(cfg.Dir)
 }
@@ -0,0 +1,100 @@
+// Suggestions for the navbar search box. Without this script the box is a
+// plain form that searches the catalog.
+(function () {
+  "use strict";
+
+  var DELAY = 120; // ms after the last keystroke
+  var GROUPS = [["titles", "Titles"], ["authors", "Authors"], ["genres", "Genres"]];
+
+  function init(form) {
+    var input = form.querySelector("input[name=q]");
+    var list = form.querySelector(".suggestions");
+    var endpoint = form.getAttribute("data-suggest");
+    if (!input || !list || !endpoint || !window.fetch) return;
+
+    var timer = null;
+    var seq = 0; // answers to older requests are dropped
+    var active = -1;
+
+    function links() {
+      return list.querySelectorAll("a");
+    }
+
+    function close() {
+      list.hidden = true;
+      list.textContent = "";
+      input.setAttribute("aria-expanded", "false");
+      active = -1;
+    }
+
+    function show(res) {
+      list.textContent = "";
+      active = -1;
+      GROUPS.forEach(function (g) {
+        var items = res[g[0]] || [];
+        if (!items.length) return;
+        var h = document.createElement("h4");
+        h.textContent = g[1];
+        list.appendChild(h);
+        items.forEach(function (s) {
+          var a = document.createElement("a");
+          a.href = s.url;
+          a.textContent = s.text;
+          a.setAttribute("role", "option");
+          list.appendChild(a);
+        });
+      });
+      if (!links().length) return close();
+      list.hidden = false;
+      input.setAttribute("aria-expanded", "true");
+    }
+
+    function fetchSuggestions() {
+      var q = input.value.trim();
+      if (!q) return close();
+      var mine = ++seq;
+      fetch(endpoint + "?q=" + encodeURIComponent(q), { headers: { Accept: "application/json" } })
+        .then(function (r) { return r.ok ? r.json() : null; })
+        .then(function (res) {
+          if (mine !== seq) return;
+          if (res) show(res); else close();
+        })
+        .catch(function () { if (mine === seq) close(); });
+    }
+
+    function move(by) {
+      var all = links();
+      if (!all.length) return;
+      if (active >= 0) all[active].classList.remove("active");
+      active = (active + 1 + by + all.length + 1) % (all.length + 1) - 1; // -1 is the input
+      if (active >= 0) all[active].classList.add("active");
+    }
+
+    input.addEventListener("input", function () {
+      clearTimeout(timer);
+      timer = setTimeout(fetchSuggestions, DELAY);
+    });
+
+    input.addEventListener("keydown", function (e) {
+      if (list.hidden) return;
+      if (e.key === "ArrowDown" || e.key === "ArrowUp") {
+        e.preventDefault();
+        move(e.key === "ArrowDown" ? 1 : -1);
+      } else if (e.key === "Enter" && active >= 0) {
+        e.preventDefault();
+        window.location.href = links()[active].href;
+      } else if (e.key === "Escape") {
+        close();
+      }
+    });
+
+    // Let a click on a suggestion land before the list goes away.
+    input.addEventListener("blur", function () {
+      setTimeout(close, 150);
+    });
+  }
+
+  document.addEventListener("DOMContentLoaded", function () {
+    document.querySelectorAll("form.nav-search").forEach(init);
+  });
+})();